@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
 	"qvarkk/kvault/config"
-	"qvarkk/kvault/internal/aws"
+	"qvarkk/kvault/internal/blob"
+	"qvarkk/kvault/internal/challenge"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/envelope"
+	"qvarkk/kvault/internal/health"
 	"qvarkk/kvault/internal/postgres"
 	"qvarkk/kvault/internal/redis"
 	"qvarkk/kvault/internal/repositories"
 	"qvarkk/kvault/internal/routes"
 	"qvarkk/kvault/internal/services"
+	"qvarkk/kvault/internal/tracing"
 	"qvarkk/kvault/logger"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 )
 
@@ -25,23 +37,31 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
+// @description Type "Bearer" followed by a space and the API key
 func main() {
 	config, err := config.LoadConfig()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	err = logger.Init("api", config.Debug)
+	err = logger.Init("api", config.Log, config.Debug)
 	if err != nil {
 		log.Fatalf("Failed to initialize zap logger: %v", err)
 	}
 
+	shutdownTracing, err := tracing.Init(config.Tracing, "kvault-api")
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	tracingEnabled := config.Tracing.Exporter != tracing.ExporterNone
+
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", config.DB.Username, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Database)
 	pgConfig := postgres.Config{
 		DSN:             dsn,
 		MaxOpenConns:    10,
 		MaxIdleConns:    5,
 		ConnMaxLifetime: time.Minute * 5,
+		Traced:          tracingEnabled,
 	}
 
 	pg, err := postgres.NewPostgres(pgConfig)
@@ -55,50 +75,181 @@ func main() {
 		Username: config.Redis.User,
 		Password: config.Redis.Password,
 		DB:       0,
+		Traced:   tracingEnabled,
 	}
 
-	redis, err := redis.NewRedis(redisConfig)
+	redisClient, err := redis.NewRedis(redisConfig)
 	if err != nil {
 		logger.Logger.Fatal("Connection to Redis failed", zap.Error(err))
 	}
+	defer redisClient.Close()
+
+	blobStore, err := blob.NewStore(config.Blob, config.Aws)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up blob store", zap.Error(err))
+	}
+	// signed links are served by the API only with the local driver
+	localStore, _ := blobStore.(*blob.LocalStore)
+	if config.Metrics.Enabled {
+		blobStore = blob.NewInstrumentedStore(blobStore, config.Blob.Driver)
+		prometheus.MustRegister(collectors.NewDBStatsCollector(pg.DB.DB, "kvault"))
+	}
 
-	aws, err := aws.NewAws(config.Aws)
+	fileCipher, err := envelope.NewFileCipher(config.Encryption)
 	if err != nil {
-		logger.Logger.Fatal("Connection to AWS failed", zap.Error(err))
+		logger.Logger.Fatal("Failed to set up file encryption", zap.Error(err))
 	}
 
 	var (
-		userRepo     = repositories.NewUserRepo(pg.DB)
-		itemRepo     = repositories.NewItemRepo(pg.DB)
-		fileRepo     = repositories.NewFileRepo(pg.DB)
-		stopwordRepo = repositories.NewStopwordRepo(pg.DB)
-		tagRepo      = repositories.NewTagRepo(pg.DB)
-		transactor   = repositories.NewTransactor(pg.DB)
+		userRepo          = repositories.NewUserRepo(pg.DB)
+		apiKeyRepo        = repositories.NewApiKeyRepo(pg.DB)
+		passwordResetRepo = repositories.NewPasswordResetRepo(pg.DB)
+		itemRepo          = repositories.NewItemRepo(pg.DB)
+		fileRepo          = repositories.NewFileRepo(pg.DB)
+		filePreviewRepo   = repositories.NewFilePreviewRepo(pg.DB)
+		stopwordRepo      = repositories.NewStopwordRepo(pg.DB)
+		tagRepo           = repositories.NewTagRepo(pg.DB)
+		tagRuleRepo       = repositories.NewTagRuleRepo(pg.DB)
+		auditRepo         = repositories.NewAuditRepo(pg.DB)
+		statsRepo         = repositories.NewStatsRepo(pg.DB)
+		transactor        = repositories.NewTransactor(pg.DB)
 	)
 
+	challengeVerifier, err := challenge.NewVerifier(config.AuthGuard)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up registration challenge", zap.Error(err))
+	}
+
+	guardConfig := services.BruteForceConfig{
+		FailureWindow:    time.Duration(config.AuthGuard.FailureWindowMinutes) * time.Minute,
+		FreeAttempts:     config.AuthGuard.FreeAttempts,
+		BaseDelay:        time.Duration(config.AuthGuard.BaseDelaySeconds) * time.Second,
+		MaxDelay:         time.Duration(config.AuthGuard.MaxDelaySeconds) * time.Second,
+		MaxEmailFailures: config.AuthGuard.MaxEmailFailures,
+		MaxIpFailures:    config.AuthGuard.MaxIpFailures,
+		LockoutDuration:  time.Duration(config.AuthGuard.LockoutMinutes) * time.Minute,
+	}
+
+	resetLimit := services.PasswordResetLimit{
+		Limit:  config.Api.PasswordResetMailsPerHour,
+		Window: time.Hour,
+	}
+
+	quotaDefaults := domain.UserQuota{
+		MaxStorageBytes: config.Quota.MaxStorageBytes,
+		MaxFileBytes:    config.Quota.MaxFileBytes,
+		MaxItems:        config.Quota.MaxItems,
+	}
+
+	// worker component is logged under only by the worker process
+	logComponents := []string{logger.ComponentRoot, logger.ComponentAccess, logger.ComponentHttp}
+
+	// shared by route limits and the per-email budget of reset mails
+	rateLimiter := redis.NewRateLimiter(redisClient.Client)
+
 	var (
-		authService     = services.NewAuthService(userRepo)
+		auditService    = services.NewAuditService(auditRepo)
+		bruteForceGuard = services.NewBruteForceGuard(redis.NewAttemptTracker(redisClient.Client), auditService, guardConfig)
+		authService     = services.NewAuthService(userRepo, apiKeyRepo, transactor, bruteForceGuard, auditService, challengeVerifier)
+		apiKeyService   = services.NewApiKeyService(apiKeyRepo, userRepo, auditService)
+		accountService  = services.NewAccountService(userRepo, passwordResetRepo, apiKeyRepo, transactor, redisClient, bruteForceGuard, rateLimiter, auditService, resetLimit)
+		adminService    = services.NewAdminService(userRepo, apiKeyRepo, stopwordRepo, transactor, auditService)
 		userService     = services.NewUserService(userRepo)
-		itemService     = services.NewItemService(itemRepo, tagRepo, transactor)
-		fileService     = services.NewFileService(fileRepo, transactor, redis, aws)
-		stopwordService = services.NewStopwordService(stopwordRepo, transactor)
-		tagService      = services.NewTagService(tagRepo, stopwordRepo, transactor)
+		itemService     = services.NewItemService(itemRepo, tagRepo, tagRuleRepo, transactor, auditService)
+		fileService     = services.NewFileService(fileRepo, tagRepo, filePreviewRepo, transactor, redisClient, blobStore, fileCipher, auditService)
+		stopwordService = services.NewStopwordService(stopwordRepo, transactor, auditService)
+		tagService      = services.NewTagService(tagRepo, stopwordRepo, transactor, auditService)
+		tagRuleService  = services.NewTagRuleService(tagRuleRepo, tagRepo, transactor)
+		quotaService    = services.NewQuotaService(userRepo, auditService, quotaDefaults)
+		queueService    = services.NewQueueService(redisClient.Inspector, auditService)
+		logService      = services.NewLogService(auditService, logComponents)
 	)
 
+	err = adminService.PromoteAdmins(context.Background(), config.Api.AdminEmails)
+	if err != nil {
+		logger.Logger.Fatal("Failed to promote admins", zap.Error(err))
+	}
+
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("postgres", pg.DB.PingContext)
+	checker.Add("redis", func(ctx context.Context) error {
+		return redisClient.Client.Ping(ctx).Err()
+	})
+	checker.Add("blob", blobStore.Ping)
+
 	hs := &routes.HandlerServices{
 		Auth:     authService,
 		AuthUser: userService,
+		Account:  accountService,
+		ApiKey:   apiKeyService,
 		User:     userService,
 		Item:     itemService,
 		File:     fileService,
 		Stopword: stopwordService,
 		Tag:      tagService,
+		TagRule:  tagRuleService,
+		Admin:    adminService,
+		Audit:    auditService,
+		Quota:    quotaService,
+		Queue:    queueService,
+		Log:      logService,
+		Health:   checker,
+	}
+
+	if localStore != nil {
+		hs.Blob = services.NewBlobService(localStore)
 	}
 
 	ms := &routes.MiddlewareServices{
-		User: userService,
+		ApiKey:      apiKeyService,
+		RateLimiter: rateLimiter,
+	}
+
+	router, err := routes.SetupRouter(hs, ms, config)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up router", zap.Error(err))
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.Api.Port),
+		Handler: router,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if config.Metrics.Enabled {
+		statsService := services.NewStatsService(statsRepo)
+		go statsService.Run(ctx, time.Duration(config.Metrics.RefreshSeconds)*time.Second)
+	}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Logger.Fatal("API server failed", zap.Error(err))
+		}
+	}()
+	logger.Logger.Info("API server started", zap.String("addr", server.Addr))
+
+	<-ctx.Done()
+	stop()
+
+	// probes fail from now on, new requests are still served until load
+	// balancers take the instance out
+	logger.Logger.Info("Shutting down API server")
+	checker.Drain()
+	time.Sleep(time.Duration(config.Api.ShutdownDelaySeconds) * time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Api.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Logger.Error("Failed to drain in-flight requests", zap.Error(err))
 	}
 
-	r := routes.SetupRouter(hs, ms)
-	r.Run(fmt.Sprintf(":%d", config.Api.Port))
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Logger.Error("Failed to flush traces", zap.Error(err))
+	}
 }
@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
 	"qvarkk/kvault/config"
-	"qvarkk/kvault/internal/aws"
+	"qvarkk/kvault/internal/blob"
+	"qvarkk/kvault/internal/envelope"
 	"qvarkk/kvault/internal/handlers/worker"
+	"qvarkk/kvault/internal/health"
+	"qvarkk/kvault/internal/mail"
+	"qvarkk/kvault/internal/metrics"
+	"qvarkk/kvault/internal/ocr"
 	"qvarkk/kvault/internal/postgres"
+	"qvarkk/kvault/internal/preview"
+	"qvarkk/kvault/internal/redis"
 	"qvarkk/kvault/internal/repositories"
+	"qvarkk/kvault/internal/routes"
 	"qvarkk/kvault/internal/services"
 	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/internal/tracing"
 	"qvarkk/kvault/logger"
+	"syscall"
 	"time"
 
 	"github.com/hibiken/asynq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"go.uber.org/zap"
 )
 
@@ -23,17 +39,24 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
-	err = logger.Init("worker", config.Debug)
+	err = logger.Init("worker", config.Log, config.Debug)
 	if err != nil {
 		log.Fatalf("Failed to initialize zap logger: %v", err)
 	}
 
+	shutdownTracing, err := tracing.Init(config.Tracing, "kvault-worker")
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+	tracingEnabled := config.Tracing.Exporter != tracing.ExporterNone
+
 	dsn := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=disable", config.DB.Username, config.DB.Password, config.DB.Host, config.DB.Port, config.DB.Database)
 	pgConfig := postgres.Config{
 		DSN:             dsn,
 		MaxOpenConns:    10,
 		MaxIdleConns:    5,
 		ConnMaxLifetime: time.Minute * 5,
+		Traced:          tracingEnabled,
 	}
 
 	pg, err := postgres.NewPostgres(pgConfig)
@@ -42,30 +65,165 @@ func main() {
 	}
 	defer pg.Close()
 
-	aws, err := aws.NewAws(config.Aws)
+	blobStore, err := blob.NewStore(config.Blob, config.Aws)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up blob store", zap.Error(err))
+	}
+	if config.Metrics.Enabled {
+		blobStore = blob.NewInstrumentedStore(blobStore, config.Blob.Driver)
+		prometheus.MustRegister(collectors.NewDBStatsCollector(pg.DB.DB, "kvault"))
+	}
+
+	fileCipher, err := envelope.NewFileCipher(config.Encryption)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up file encryption", zap.Error(err))
+	}
+
+	mailSender, err := mail.NewSender(config.Mail)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up mail sender", zap.Error(err))
+	}
+
+	resetConfig := services.PasswordResetConfig{
+		TokenTTL: time.Duration(config.Api.PasswordResetTtlMinutes) * time.Minute,
+		Url:      config.Api.PasswordResetUrl,
+	}
+
+	renderer, err := preview.NewRenderer(config.Preview)
+	if err != nil {
+		logger.Logger.Fatal("Failed to set up preview renderer", zap.Error(err))
+	}
+
+	previewConfig := services.PreviewConfig{
+		ThumbnailWidth: config.Preview.ThumbnailWidth,
+		PageWidth:      config.Preview.PageWidth,
+		MaxPages:       config.Preview.MaxPages,
+	}
+
+	ocrEngine, err := ocr.NewEngine(config.Ocr)
 	if err != nil {
-		logger.Logger.Fatal("Connection to AWS failed", zap.Error(err))
+		logger.Logger.Fatal("Failed to set up OCR engine", zap.Error(err))
+	}
+	if ocrEngine != nil && renderer == nil {
+		logger.Logger.Warn("No preview renderer configured, only images will be OCR'd")
 	}
 
-	srv := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr:     fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
-			Username: config.Redis.User,
-			Password: config.Redis.Password,
-			DB:       0,
+	ocrConfig := services.OcrConfig{
+		RenderWidth: config.Ocr.RenderWidth,
+		MaxPages:    config.Ocr.MaxPages,
+	}
+
+	redisConfig := redis.Config{
+		Addr:     fmt.Sprintf("%s:%d", config.Redis.Host, config.Redis.Port),
+		Username: config.Redis.User,
+		Password: config.Redis.Password,
+		DB:       0,
+		Traced:   tracingEnabled,
+	}
+
+	redisClient, err := redis.NewRedis(redisConfig)
+	if err != nil {
+		logger.Logger.Fatal("Connection to Redis failed", zap.Error(err))
+	}
+	defer redisClient.Close()
+
+	srv := asynq.NewServerFromRedisClient(
+		redisClient.Client,
+		asynq.Config{
+			Concurrency: config.Worker.ConcurrentTasks,
+			RetryDelayFunc: worker.RetryDelay(
+				time.Duration(config.Worker.RetryBaseSeconds)*time.Second,
+				time.Duration(config.Worker.RetryMaxSeconds)*time.Second,
+			),
+			ShutdownTimeout: time.Duration(config.Worker.ShutdownTimeoutSeconds) * time.Second,
 		},
-		asynq.Config{Concurrency: config.Worker.ConcurrentTasks},
 	)
 
 	fileRepo := repositories.NewFileRepo(pg.DB)
+	userRepo := repositories.NewUserRepo(pg.DB)
+	tagRuleRepo := repositories.NewTagRuleRepo(pg.DB)
+	previewRepo := repositories.NewFilePreviewRepo(pg.DB)
+	auditRepo := repositories.NewAuditRepo(pg.DB)
 	transactor := repositories.NewTransactor(pg.DB)
-	fileService := services.NewFileTaskService(fileRepo, transactor, aws)
+	fileService := services.NewFileTaskService(
+		fileRepo, tagRuleRepo, previewRepo, transactor, blobStore, fileCipher,
+		renderer, previewConfig, ocrEngine, ocrConfig,
+	)
 	fileTaskHandler := worker.NewFileTaskHandler(fileService)
+	passwordResetRepo := repositories.NewPasswordResetRepo(pg.DB)
+	accountService := services.NewAccountTaskService(
+		fileRepo, userRepo, passwordResetRepo, transactor, blobStore,
+		mailSender, resetConfig,
+	)
+	accountTaskHandler := worker.NewAccountTaskHandler(accountService)
 
 	mux := asynq.NewServeMux()
+	mux.Use(worker.LoggingMiddleware)
+	if tracingEnabled {
+		mux.Use(worker.TracingMiddleware)
+	}
+	if config.Metrics.Enabled {
+		mux.Use(worker.MetricsMiddleware)
+	}
 	mux.HandleFunc(tasks.TypePdfProcess, fileTaskHandler.HandlePdfProcessTask)
+	mux.HandleFunc(tasks.TypeUserDelete, accountTaskHandler.HandleUserDeleteTask)
+	mux.HandleFunc(tasks.TypePasswordResetMail, accountTaskHandler.HandlePasswordResetMailTask)
+
+	checker := health.NewChecker(3 * time.Second)
+	checker.Add("postgres", pg.DB.PingContext)
+	checker.Add("redis", func(ctx context.Context) error {
+		return srv.Ping()
+	})
+	checker.Add("blob", blobStore.Ping)
+
+	queueService := services.NewQueueService(redisClient.Inspector, services.NewAuditService(auditRepo))
+	if config.Metrics.Enabled {
+		prometheus.MustRegister(metrics.NewQueueCollector(queueService))
+	}
+
+	var probeServer *http.Server
+	if config.Worker.ProbePort > 0 {
+		probeServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", config.Worker.ProbePort),
+			Handler: routes.SetupWorkerRouter(checker, queueService, config),
+		}
+
+		go func() {
+			if err := probeServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Logger.Fatal("Probe server failed", zap.Error(err))
+			}
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := srv.Start(mux); err != nil {
+		logger.Logger.Fatal("Failed to start worker", zap.Error(err))
+	}
+
+	<-ctx.Done()
+	stop()
+
+	// asynq waits for running tasks up to its shutdown timeout, probes keep
+	// answering meanwhile
+	logger.Logger.Info("Shutting down worker")
+	checker.Drain()
+	srv.Shutdown()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Logger.Error("Failed to flush traces", zap.Error(err))
+	}
+
+	if probeServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
 
-	if err := srv.Run(mux); err != nil {
-		log.Fatal(err)
+		if err := probeServer.Shutdown(shutdownCtx); err != nil {
+			logger.Logger.Error("Failed to stop probe server", zap.Error(err))
+		}
 	}
 }
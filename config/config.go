@@ -12,11 +12,38 @@ type Config struct {
 	DB     DBConfig
 	Redis  RedisConfig
 	Aws    AwsConfig
+	Blob   BlobConfig
 	Worker WorkerConfig
+	Mail   MailConfig
+
+	RateLimit  RateLimitConfig  `envconfig:"RATE_LIMIT"`
+	AuthGuard  AuthGuardConfig  `envconfig:"AUTH_GUARD"`
+	Quota      QuotaConfig      `envconfig:"QUOTA"`
+	Encryption EncryptionConfig `envconfig:"ENCRYPTION"`
+	Preview    PreviewConfig    `envconfig:"PREVIEW"`
+	Ocr        OcrConfig        `envconfig:"OCR"`
+	Metrics    MetricsConfig    `envconfig:"METRICS"`
+	Tracing    TracingConfig    `envconfig:"TRACING"`
+	Log        LogConfig        `envconfig:"LOG"`
 }
 
 type ApiConfig struct {
 	Port int `default:"8080"`
+	// Existing users with these emails are made admins on startup
+	AdminEmails []string `envconfig:"ADMIN_EMAILS"`
+	// IPs or CIDRs of reverse proxies whose X-Forwarded-For is believed,
+	// empty trusts none and uses the peer address
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+	// Token is appended as a query parameter, only the token is mailed when empty
+	PasswordResetUrl        string `envconfig:"PASSWORD_RESET_URL"`
+	PasswordResetTtlMinutes int    `envconfig:"PASSWORD_RESET_TTL_MINUTES" default:"30"`
+	// Reset mails per email per hour, further requests are dropped silently
+	PasswordResetMailsPerHour int `envconfig:"PASSWORD_RESET_MAILS_PER_HOUR" default:"3"`
+	// How long readiness fails before the server stops accepting requests,
+	// gives load balancers time to notice
+	ShutdownDelaySeconds int `envconfig:"SHUTDOWN_DELAY_SECONDS" default:"5"`
+	// How long in-flight requests may take to finish after SIGTERM
+	ShutdownTimeoutSeconds int `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
 }
 
 type DBConfig struct {
@@ -43,8 +70,148 @@ type AwsConfig struct {
 	UrlExpirationTimeSeconds int    `envconfig:"URL_EXPIRATION_TIME_SECONDS" default:"60"`
 }
 
+// Local driver keeps objects on disk and serves signed links through the API,
+// its directory has to be shared by the API and the worker
+type BlobConfig struct {
+	Driver                    string `default:"s3"` // s3 or local
+	LocalDir                  string `envconfig:"LOCAL_DIR" default:"blobs"`
+	LocalUrl                  string `envconfig:"LOCAL_URL" default:"http://localhost:8080/api/v1/blobs"`
+	LocalSigningKey           string `envconfig:"LOCAL_SIGNING_KEY"`
+	LocalUrlExpirationSeconds int    `envconfig:"LOCAL_URL_EXPIRATION_SECONDS" default:"60"`
+}
+
+// New uploads are encrypted only when enabled, files encrypted earlier can be
+// read as long as their key provider stays configured
+type EncryptionConfig struct {
+	Enabled     bool   `default:"false"`
+	KeyProvider string `envconfig:"KEY_PROVIDER"` // config, local-kms or empty
+	// Base64 of 32 bytes, used by the config provider
+	MasterKey   string `envconfig:"MASTER_KEY"`
+	MasterKeyID string `envconfig:"MASTER_KEY_ID" default:"master"`
+	// Used by the local-kms provider and shared by the API and the worker,
+	// created with a generated key if missing
+	KmsKeyringPath string `envconfig:"KMS_KEYRING_PATH" default:"keyring.json"`
+	// Wrap data keys with keys derived per file owner
+	PerUserKeys bool `envconfig:"PER_USER_KEYS" default:"true"`
+}
+
+// Page images are rendered by the worker with an external tool,
+// no previews are made when the renderer is empty
+type PreviewConfig struct {
+	Renderer string `default:""` // pdftoppm, mutool or empty
+	// Binary of the renderer, looked up in PATH when empty
+	RendererPath   string `envconfig:"RENDERER_PATH"`
+	ThumbnailWidth int    `envconfig:"THUMBNAIL_WIDTH" default:"320"`
+	PageWidth      int    `envconfig:"PAGE_WIDTH" default:"1024"`
+	// Pages after the first MaxPages get no preview, zero disables page previews
+	MaxPages       int `envconfig:"MAX_PAGES" default:"0"`
+	TimeoutSeconds int `envconfig:"TIMEOUT_SECONDS" default:"30"`
+}
+
+// Scanned pages are rendered with the preview renderer before recognition,
+// images are recognized as uploaded
+type OcrConfig struct {
+	Engine string `default:""` // tesseract, nop or empty
+	// Binary of the engine, looked up in PATH when empty
+	EnginePath string `envconfig:"ENGINE_PATH"`
+	Languages  string `default:"eng"` // tesseract language codes joined by +
+	// Width scanned pages are rendered at, about 300 DPI for A4
+	RenderWidth    int `envconfig:"RENDER_WIDTH" default:"2480"`
+	MaxPages       int `envconfig:"MAX_PAGES" default:"50"`
+	TimeoutSeconds int `envconfig:"TIMEOUT_SECONDS" default:"120"`
+}
+
+// /metrics of both binaries needs no API key, keep it off public listeners
+type MetricsConfig struct {
+	Enabled bool `default:"true"`
+	// How often the API recounts items, files and tags
+	RefreshSeconds int `envconfig:"REFRESH_SECONDS" default:"60"`
+}
+
+// Access logs are JSON whatever the format, the file is always JSON
+type LogConfig struct {
+	Output string `default:"both"`    // stdout, file or both
+	Format string `default:"console"` // console or json, for stdout
+	// <binary>.log in the working directory when empty
+	File string `default:""`
+	// The file is rotated once it reaches MaxSizeMB. Rotated files are
+	// removed after MaxAgeDays or beyond MaxBackups, zero lifts either limit
+	MaxSizeMB  int  `envconfig:"MAX_SIZE_MB" default:"100"`
+	MaxAgeDays int  `envconfig:"MAX_AGE_DAYS" default:"14"`
+	MaxBackups int  `envconfig:"MAX_BACKUPS" default:"5"`
+	Compress   bool `default:"true"`
+	// Level of everything without an override, Debug lowers it to debug
+	Level string `default:"info"`
+	// Overrides per component as component:level pairs, e.g. http:debug,access:warn
+	Levels map[string]string
+}
+
+// Spans of one request or task share a trace, tasks carry it in their payload
+type TracingConfig struct {
+	Exporter string `default:""` // otlp, stdout or empty
+	// host:port of an OTLP/HTTP collector
+	OtlpEndpoint string `envconfig:"OTLP_ENDPOINT" default:"localhost:4318"`
+	OtlpInsecure bool   `envconfig:"OTLP_INSECURE" default:"true"`
+	// Share of new traces kept, tasks follow the choice made for their request
+	SampleRatio float64 `envconfig:"SAMPLE_RATIO" default:"1"`
+}
+
+type MailConfig struct {
+	Driver       string `default:"log"` // smtp, log or file
+	From         string `default:"kvault@localhost"`
+	SmtpHost     string `envconfig:"SMTP_HOST" default:"localhost"`
+	SmtpPort     int    `envconfig:"SMTP_PORT" default:"587"`
+	SmtpUser     string `envconfig:"SMTP_USER"`
+	SmtpPassword string `envconfig:"SMTP_PASSWORD"`
+	Dir          string `default:"mail"` // used by file driver
+}
+
+// Limits are requests per window per user, or per API key with KeyBy=key
+type RateLimitConfig struct {
+	Enabled       bool   `default:"true"`
+	KeyBy         string `envconfig:"KEY_BY" default:"user"` // user or key
+	WindowSeconds int    `envconfig:"WINDOW_SECONDS" default:"60"`
+	DefaultLimit  int    `envconfig:"DEFAULT_LIMIT" default:"300"`
+	UploadLimit   int    `envconfig:"UPLOAD_LIMIT" default:"20"`
+	SearchLimit   int    `envconfig:"SEARCH_LIMIT" default:"60"`
+	// Per client IP, for password reset routes that have no user to key by
+	PasswordResetLimit int `envconfig:"PASSWORD_RESET_LIMIT" default:"5"`
+}
+
+// Failed login and registration attempts are counted per email and per client IP
+type AuthGuardConfig struct {
+	FailureWindowMinutes int `envconfig:"FAILURE_WINDOW_MINUTES" default:"15"`
+	FreeAttempts         int `envconfig:"FREE_ATTEMPTS" default:"3"`
+	BaseDelaySeconds     int `envconfig:"BASE_DELAY_SECONDS" default:"1"`
+	MaxDelaySeconds      int `envconfig:"MAX_DELAY_SECONDS" default:"30"`
+	MaxEmailFailures     int `envconfig:"MAX_EMAIL_FAILURES" default:"10"`
+	MaxIpFailures        int `envconfig:"MAX_IP_FAILURES" default:"50"`
+	LockoutMinutes       int `envconfig:"LOCKOUT_MINUTES" default:"15"`
+
+	// Registration challenge: empty, pow or captcha
+	ChallengeProvider string `envconfig:"CHALLENGE_PROVIDER"`
+	PowDifficulty     int    `envconfig:"POW_DIFFICULTY" default:"20"`
+	PowMaxAgeMinutes  int    `envconfig:"POW_MAX_AGE_MINUTES" default:"10"`
+	CaptchaVerifyUrl  string `envconfig:"CAPTCHA_VERIFY_URL"`
+	CaptchaSecret     string `envconfig:"CAPTCHA_SECRET"`
+}
+
+// Defaults for users without admin overrides, zero disables a limit
+type QuotaConfig struct {
+	MaxStorageBytes int64 `envconfig:"MAX_STORAGE_BYTES" default:"1073741824"`
+	MaxFileBytes    int64 `envconfig:"MAX_FILE_BYTES" default:"52428800"`
+	MaxItems        int64 `envconfig:"MAX_ITEMS" default:"10000"`
+}
+
+// Failed tasks are retried after RetryBaseSeconds doubled with each attempt
 type WorkerConfig struct {
-	ConcurrentTasks int `default:"10"`
+	ConcurrentTasks  int `envconfig:"CONCURRENT_TASKS" default:"10"`
+	RetryBaseSeconds int `envconfig:"RETRY_BASE_SECONDS" default:"10"`
+	RetryMaxSeconds  int `envconfig:"RETRY_MAX_SECONDS" default:"3600"`
+	// Running tasks left after the timeout are put back in the queue
+	ShutdownTimeoutSeconds int `envconfig:"SHUTDOWN_TIMEOUT_SECONDS" default:"30"`
+	// Serves health probes, queue stats and metrics, zero disables it
+	ProbePort int `envconfig:"PROBE_PORT" default:"6768"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"context"
+	"qvarkk/kvault/internal/tracing"
+
+	smithytracing "github.com/aws/smithy-go/tracing"
+	"github.com/aws/smithy-go/tracing/smithyoteltracing"
+	"go.opentelemetry.io/otel"
+)
+
+// Hands SDK spans to the global tracer provider, calls outside a trace like
+// readiness checks of the bucket are left out
+func newTracerProvider() smithytracing.TracerProvider {
+	return parentOnlyProvider{smithyoteltracing.Adapt(otel.GetTracerProvider())}
+}
+
+type parentOnlyProvider struct {
+	smithytracing.TracerProvider
+}
+
+func (p parentOnlyProvider) Tracer(scope string, opts ...smithytracing.TracerOption) smithytracing.Tracer {
+	return parentOnlyTracer{p.TracerProvider.Tracer(scope, opts...)}
+}
+
+type parentOnlyTracer struct {
+	smithytracing.Tracer
+}
+
+func (t parentOnlyTracer) StartSpan(
+	ctx context.Context,
+	name string,
+	opts ...smithytracing.SpanOption,
+) (context.Context, smithytracing.Span) {
+	if !tracing.HasParent(ctx) {
+		return smithytracing.NopTracerProvider{}.Tracer("").StartSpan(ctx, name, opts...)
+	}
+	return t.Tracer.StartSpan(ctx, name, opts...)
+}
@@ -0,0 +1,64 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"qvarkk/kvault/config"
+	"qvarkk/kvault/internal/aws"
+	"time"
+)
+
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+var (
+	ErrNotFound         = errors.New("blob: object not found")
+	ErrInvalidKey       = errors.New("blob: invalid object key")
+	ErrInvalidSignature = errors.New("blob: invalid or expired signature")
+)
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+type PresignedURL struct {
+	URL       string
+	ExpiresAt time.Time
+}
+
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Reads length bytes from offset, or up to the end when length is negative
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, keys ...string) error
+	// Download link that makes clients save the object under filename
+	PresignGet(ctx context.Context, key, filename string) (*PresignedURL, error)
+	// Checks that the bucket or directory is reachable
+	Ping(ctx context.Context) error
+}
+
+// Picks a store implementation by configured driver
+func NewStore(blobConfig config.BlobConfig, awsConfig config.AwsConfig) (Store, error) {
+	switch blobConfig.Driver {
+	case DriverS3:
+		client, err := aws.NewAws(awsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("blob: failed to set up s3 client: %w", err)
+		}
+		return NewS3Store(client), nil
+	case DriverLocal:
+		return NewLocalStore(blobConfig)
+	default:
+		return nil, fmt.Errorf("blob: unknown driver %q", blobConfig.Driver)
+	}
+}
@@ -0,0 +1,84 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"qvarkk/kvault/internal/metrics"
+	"time"
+)
+
+// Wraps a store to record latency and failures of every operation. Reads
+// are timed until the object is opened, not until the body is consumed
+type InstrumentedStore struct {
+	store  Store
+	driver string
+}
+
+func NewInstrumentedStore(store Store, driver string) *InstrumentedStore {
+	return &InstrumentedStore{
+		store:  store,
+		driver: driver,
+	}
+}
+
+func (s *InstrumentedStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	start := time.Now()
+	err := s.store.Put(ctx, key, body, size, contentType)
+	s.record("put", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	start := time.Now()
+	body, info, err := s.store.Get(ctx, key)
+	s.record("get", start, err)
+	return body, info, err
+}
+
+func (s *InstrumentedStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	start := time.Now()
+	body, err := s.store.GetRange(ctx, key, offset, length)
+	s.record("get_range", start, err)
+	return body, err
+}
+
+func (s *InstrumentedStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	start := time.Now()
+	info, err := s.store.Head(ctx, key)
+	s.record("head", start, err)
+	return info, err
+}
+
+func (s *InstrumentedStore) Delete(ctx context.Context, keys ...string) error {
+	start := time.Now()
+	err := s.store.Delete(ctx, keys...)
+	s.record("delete", start, err)
+	return err
+}
+
+func (s *InstrumentedStore) PresignGet(ctx context.Context, key, filename string) (*PresignedURL, error) {
+	start := time.Now()
+	url, err := s.store.PresignGet(ctx, key, filename)
+	s.record("presign_get", start, err)
+	return url, err
+}
+
+func (s *InstrumentedStore) Ping(ctx context.Context) error {
+	start := time.Now()
+	err := s.store.Ping(ctx)
+	s.record("ping", start, err)
+	return err
+}
+
+// The store being wrapped, for callers that need driver specific methods
+func (s *InstrumentedStore) Unwrap() Store {
+	return s.store
+}
+
+func (s *InstrumentedStore) record(operation string, start time.Time, err error) {
+	metrics.BlobOperationDuration.WithLabelValues(s.driver, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		metrics.BlobOperationErrors.WithLabelValues(s.driver, operation).Inc()
+	}
+}
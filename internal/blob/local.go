@@ -0,0 +1,237 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"qvarkk/kvault/config"
+	"strconv"
+	"time"
+)
+
+// Keeps objects in a directory, for local development. Downloads go through
+// the API by links signed with the configured key
+type LocalStore struct {
+	dir           string
+	url           string
+	signingKey    []byte
+	urlExpiration time.Duration
+}
+
+func NewLocalStore(config config.BlobConfig) (*LocalStore, error) {
+	if config.LocalSigningKey == "" {
+		return nil, errors.New("blob: local driver requires a signing key")
+	}
+
+	if err := os.MkdirAll(config.LocalDir, 0o755); err != nil {
+		return nil, fmt.Errorf("blob: failed to create %s: %w", config.LocalDir, err)
+	}
+
+	return &LocalStore{
+		dir:           config.LocalDir,
+		url:           config.LocalUrl,
+		signingKey:    []byte(config.LocalSigningKey),
+		urlExpiration: time.Second * time.Duration(config.LocalUrlExpirationSeconds),
+	}, nil
+}
+
+// Writes to a temporary file first so readers never see a partial object
+func (s *LocalStore) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("blob: failed to create directory for %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("blob: failed to put %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("blob: failed to put %s: %w", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("blob: failed to put %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// Returned body is an *os.File, so it can be seeked
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, toLocalError(key, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, toLocalError(key, err)
+	}
+
+	return file, localObjectInfo(key, stat), nil
+}
+
+func (s *LocalStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	file, _, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	seeker := file.(*os.File)
+	if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+		seeker.Close()
+		return nil, fmt.Errorf("blob: failed to get %s: %w", key, err)
+	}
+
+	if length < 0 {
+		return seeker, nil
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(seeker, length), seeker}, nil
+}
+
+func (s *LocalStore) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, toLocalError(key, err)
+	}
+
+	return localObjectInfo(key, stat), nil
+}
+
+func (s *LocalStore) Ping(ctx context.Context) error {
+	stat, err := os.Stat(s.dir)
+	if err != nil {
+		return fmt.Errorf("blob: failed to stat %s: %w", s.dir, err)
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("blob: %s is not a directory", s.dir)
+	}
+	return nil
+}
+
+// Missing objects are skipped like in S3
+func (s *LocalStore) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		path, err := s.path(key)
+		if err != nil {
+			return err
+		}
+
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("blob: failed to delete %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *LocalStore) PresignGet(ctx context.Context, key, filename string) (*PresignedURL, error) {
+	if _, err := s.path(key); err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().UTC().Add(s.urlExpiration)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("filename", filename)
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(key, filename, expires))
+
+	keyPath := (&url.URL{Path: key}).EscapedPath()
+
+	return &PresignedURL{
+		URL:       s.url + "/" + keyPath + "?" + query.Encode(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Checks a link made by PresignGet and returns the download filename
+func (s *LocalStore) VerifyPresigned(key string, query url.Values) (string, error) {
+	filename := query.Get("filename")
+	expires := query.Get("expires")
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return "", ErrInvalidSignature
+	}
+
+	signature, err := hex.DecodeString(query.Get("signature"))
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+
+	expected, _ := hex.DecodeString(s.sign(key, filename, expires))
+	if !hmac.Equal(signature, expected) {
+		return "", ErrInvalidSignature
+	}
+
+	return filename, nil
+}
+
+func (s *LocalStore) sign(key, filename, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%s", key, filename, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Keys are slash separated and can't leave the store directory
+func (s *LocalStore) path(key string) (string, error) {
+	localPath := filepath.FromSlash(key)
+	if !filepath.IsLocal(localPath) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidKey, key)
+	}
+	return filepath.Join(s.dir, localPath), nil
+}
+
+func localObjectInfo(key string, stat fs.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(key)),
+		ETag:         fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func toLocalError(key string, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return fmt.Errorf("blob: failed to get %s: %w", key, err)
+}
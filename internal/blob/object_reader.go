@@ -0,0 +1,94 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+type rangeGetter interface {
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+}
+
+// Seekable view of a stored object of known size. Seeking does no I/O,
+// reads stream a ranged get from the current offset that is reused while
+// reads stay sequential
+type ObjectReader struct {
+	ctx    context.Context
+	store  rangeGetter
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+	// Offset the open body is at
+	bodyOffset int64
+}
+
+func NewObjectReader(ctx context.Context, store rangeGetter, key string, size int64) *ObjectReader {
+	return &ObjectReader{
+		ctx:   ctx,
+		store: store,
+		key:   key,
+		size:  size,
+	}
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body != nil && r.bodyOffset != r.offset {
+		r.body.Close()
+		r.body = nil
+	}
+
+	if r.body == nil {
+		body, err := r.store.GetRange(r.ctx, r.key, r.offset, -1)
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+		r.bodyOffset = r.offset
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	r.bodyOffset += int64(n)
+
+	if errors.Is(err, io.EOF) && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("blob: invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("blob: negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *ObjectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
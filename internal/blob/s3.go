@@ -0,0 +1,161 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"qvarkk/kvault/internal/aws"
+	"strconv"
+	"time"
+
+	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3 won't take more keys in one DeleteObjects call
+const s3DeleteBatchSize = 1000
+
+type S3Store struct {
+	aws *aws.Aws
+}
+
+func NewS3Store(aws *aws.Aws) *S3Store {
+	return &S3Store{aws: aws}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	_, err := s.aws.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        awsSdk.String(s.aws.BucketName),
+		Key:           awsSdk.String(key),
+		Body:          body,
+		ContentLength: awsSdk.Int64(size),
+		ContentType:   awsSdk.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("blob: failed to put %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	resp, err := s.aws.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: awsSdk.String(s.aws.BucketName),
+		Key:    awsSdk.String(key),
+	})
+	if err != nil {
+		return nil, nil, toStoreError(key, err)
+	}
+
+	info := &ObjectInfo{
+		Key:          key,
+		Size:         awsSdk.ToInt64(resp.ContentLength),
+		ContentType:  awsSdk.ToString(resp.ContentType),
+		ETag:         awsSdk.ToString(resp.ETag),
+		LastModified: awsSdk.ToTime(resp.LastModified),
+	}
+
+	return resp.Body, info, nil
+}
+
+func (s *S3Store) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+
+	resp, err := s.aws.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: awsSdk.String(s.aws.BucketName),
+		Key:    awsSdk.String(key),
+		Range:  awsSdk.String(byteRange),
+	})
+	if err != nil {
+		return nil, toStoreError(key, err)
+	}
+
+	return resp.Body, nil
+}
+
+func (s *S3Store) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	resp, err := s.aws.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: awsSdk.String(s.aws.BucketName),
+		Key:    awsSdk.String(key),
+	})
+	if err != nil {
+		return nil, toStoreError(key, err)
+	}
+
+	return &ObjectInfo{
+		Key:          key,
+		Size:         awsSdk.ToInt64(resp.ContentLength),
+		ContentType:  awsSdk.ToString(resp.ContentType),
+		ETag:         awsSdk.ToString(resp.ETag),
+		LastModified: awsSdk.ToTime(resp.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, keys ...string) error {
+	for start := 0; start < len(keys); start += s3DeleteBatchSize {
+		end := min(start+s3DeleteBatchSize, len(keys))
+
+		objects := make([]types.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			objects = append(objects, types.ObjectIdentifier{Key: awsSdk.String(key)})
+		}
+
+		_, err := s.aws.S3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: awsSdk.String(s.aws.BucketName),
+			Delete: &types.Delete{
+				Objects: objects,
+				Quiet:   awsSdk.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("blob: failed to delete objects: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *S3Store) PresignGet(ctx context.Context, key, filename string) (*PresignedURL, error) {
+	expires := time.Second * time.Duration(s.aws.UrlExpirationTimeSeconds)
+	contentDisposition := fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(filename))
+
+	presignClient := s3.NewPresignClient(s.aws.S3Client)
+	result, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket:                     awsSdk.String(s.aws.BucketName),
+		Key:                        awsSdk.String(key),
+		ResponseContentDisposition: awsSdk.String(contentDisposition),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("blob: failed to presign %s: %w", key, err)
+	}
+
+	return &PresignedURL{
+		URL:       result.URL,
+		ExpiresAt: time.Now().UTC().Add(expires),
+	}, nil
+}
+
+func (s *S3Store) Ping(ctx context.Context) error {
+	_, err := s.aws.S3Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: awsSdk.String(s.aws.BucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("blob: failed to reach bucket %s: %w", s.aws.BucketName, err)
+	}
+	return nil
+}
+
+// GetObject reports missing keys as NoSuchKey, HeadObject as NotFound
+func toStoreError(key string, err error) error {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return fmt.Errorf("blob: failed to get %s: %w", key, err)
+}
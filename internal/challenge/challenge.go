@@ -0,0 +1,39 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/config"
+	"time"
+)
+
+const (
+	ProviderNone    = ""
+	ProviderPow     = "pow"
+	ProviderCaptcha = "captcha"
+)
+
+var ErrChallengeFailed = errors.New("challenge: solution was rejected")
+
+// Checks a solution sent by the client, subject is the value it was solved for
+type Verifier interface {
+	Verify(ctx context.Context, subject, solution, clientIP string) error
+}
+
+// Picks a verifier by configured provider, nil means that no challenge is required
+func NewVerifier(config config.AuthGuardConfig) (Verifier, error) {
+	switch config.ChallengeProvider {
+	case ProviderNone:
+		return nil, nil
+	case ProviderPow:
+		return NewPowVerifier(config.PowDifficulty, time.Duration(config.PowMaxAgeMinutes)*time.Minute), nil
+	case ProviderCaptcha:
+		if config.CaptchaVerifyUrl == "" || config.CaptchaSecret == "" {
+			return nil, errors.New("challenge: captcha verify url and secret are required")
+		}
+		return NewCaptchaVerifier(config.CaptchaVerifyUrl, config.CaptchaSecret), nil
+	default:
+		return nil, fmt.Errorf("challenge: unknown provider %q", config.ChallengeProvider)
+	}
+}
@@ -0,0 +1,59 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hashcash-like proof of work. The solution is "<unix seconds>:<nonce>" such that
+// sha256("<subject>:<solution>") starts with at least difficulty zero bits
+type PowVerifier struct {
+	difficulty int
+	maxAge     time.Duration
+}
+
+func NewPowVerifier(difficulty int, maxAge time.Duration) *PowVerifier {
+	return &PowVerifier{
+		difficulty: difficulty,
+		maxAge:     maxAge,
+	}
+}
+
+func (v *PowVerifier) Verify(ctx context.Context, subject, solution, clientIP string) error {
+	timestamp, nonce, found := strings.Cut(solution, ":")
+	if !found || nonce == "" {
+		return ErrChallengeFailed
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrChallengeFailed
+	}
+
+	age := time.Since(time.Unix(seconds, 0))
+	if age > v.maxAge || age < -time.Minute {
+		return ErrChallengeFailed
+	}
+
+	sum := sha256.Sum256([]byte(subject + ":" + solution))
+	if leadingZeroBits(sum[:]) < v.difficulty {
+		return ErrChallengeFailed
+	}
+
+	return nil
+}
+
+func leadingZeroBits(b []byte) int {
+	zeros := 0
+	for _, x := range b {
+		if x != 0 {
+			return zeros + bits.LeadingZeros8(x)
+		}
+		zeros += 8
+	}
+	return zeros
+}
@@ -0,0 +1,101 @@
+package docmeta
+
+import (
+	"qvarkk/kvault/internal/domain"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Reads the info dictionary of the PDF, documents without one get only
+// the page count
+func FromPdf(r *pdf.Reader) domain.FileMetadata {
+	info := r.Trailer().Key("Info")
+
+	return domain.FileMetadata{
+		Title:      infoText(info, "Title"),
+		Author:     infoText(info, "Author"),
+		Subject:    infoText(info, "Subject"),
+		Keywords:   SplitKeywords(infoText(info, "Keywords")),
+		Producer:   infoText(info, "Producer"),
+		PageCount:  r.NumPage(),
+		CreatedAt:  parsePdfDate(info.Key("CreationDate").RawString()),
+		ModifiedAt: parsePdfDate(info.Key("ModDate").RawString()),
+	}
+}
+
+func infoText(info pdf.Value, key string) string {
+	text := strings.TrimSpace(info.Key(key).Text())
+	if !utf8.ValidString(text) {
+		return ""
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+var keywordSeparators = regexp.MustCompile(`[,;]`)
+
+// Keywords are usually separated by commas or semicolons, lists without
+// them are taken as one keyword per word
+func SplitKeywords(keywords string) []string {
+	parts := keywordSeparators.Split(keywords, -1)
+	if len(parts) == 1 {
+		parts = strings.Fields(keywords)
+	}
+
+	seen := make(map[string]bool, len(parts))
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		keyword := strings.Join(strings.Fields(part), " ")
+		key := strings.ToLower(keyword)
+		if keyword == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, keyword)
+	}
+
+	return result
+}
+
+// PDF dates look like D:YYYYMMDDHHmmSSOHH'mm', every part after the year
+// is optional and the offset defaults to UTC
+func parsePdfDate(raw string) *time.Time {
+	raw = strings.TrimPrefix(strings.TrimSpace(raw), "D:")
+	raw = strings.ReplaceAll(raw, "'", "")
+
+	digits := 0
+	for digits < len(raw) && digits < 14 && raw[digits] >= '0' && raw[digits] <= '9' {
+		digits++
+	}
+	if digits < 4 || digits%2 != 0 {
+		return nil
+	}
+
+	// missing parts are filled with the earliest value of each
+	stamp := raw[:digits] + "0101000000"[digits-4:]
+	zone := raw[digits:]
+
+	layout := "20060102150405"
+	switch {
+	case zone == "" || zone == "Z" || strings.HasPrefix(zone, "Z"):
+		zone = "Z"
+		layout += "Z07"
+	case len(zone) == 3:
+		layout += "-07"
+	case len(zone) == 5:
+		layout += "-0700"
+	default:
+		return nil
+	}
+
+	parsed, err := time.Parse(layout, stamp+zone)
+	if err != nil {
+		return nil
+	}
+
+	parsed = parsed.UTC()
+	return &parsed
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx/types"
+)
+
+type (
+	AuditAction string
+	AuditTarget string
+)
+
+const (
+	AuditActionLoginSucceeded    AuditAction = "auth.login.succeeded"
+	AuditActionLoginFailed       AuditAction = "auth.login.failed"
+	AuditActionLockout           AuditAction = "auth.lockout"
+	AuditActionPasswordChanged   AuditAction = "auth.password.changed"
+	AuditActionPasswordReset     AuditAction = "auth.password.reset"
+	AuditActionDeletionRequested AuditAction = "account.deletion_requested"
+	AuditActionApiKeyCreated     AuditAction = "api_key.created"
+	AuditActionApiKeyRotated     AuditAction = "api_key.rotated"
+	AuditActionApiKeyRevoked     AuditAction = "api_key.revoked"
+	AuditActionUserDisabled      AuditAction = "admin.user.disabled"
+	AuditActionUserEnabled       AuditAction = "admin.user.enabled"
+	AuditActionUserKeysRotated   AuditAction = "admin.user.keys_rotated"
+	AuditActionUserQuotaUpdated  AuditAction = "admin.user.quota_updated"
+	AuditActionTaskRetried       AuditAction = "admin.task.retried"
+	AuditActionTaskDeleted       AuditAction = "admin.task.deleted"
+	AuditActionLogLevelUpdated   AuditAction = "admin.log_level.updated"
+	AuditActionLogLevelReset     AuditAction = "admin.log_level.reset"
+	AuditActionItemDeleted       AuditAction = "item.deleted"
+	AuditActionItemRestored      AuditAction = "item.restored"
+	AuditActionFileDeleted       AuditAction = "file.deleted"
+	AuditActionFileRestored      AuditAction = "file.restored"
+	AuditActionFileReprocessed   AuditAction = "file.reprocessed"
+	AuditActionFilesReprocessed  AuditAction = "files.reprocessed"
+	AuditActionTagMerged         AuditAction = "tag.merged"
+	AuditActionStopwordsImported AuditAction = "stopwords.imported"
+	AuditActionPackEnabled       AuditAction = "stopword_pack.enabled"
+	AuditActionPackDisabled      AuditAction = "stopword_pack.disabled"
+)
+
+const (
+	AuditTargetUser         AuditTarget = "user"
+	AuditTargetApiKey       AuditTarget = "api_key"
+	AuditTargetItem         AuditTarget = "item"
+	AuditTargetFile         AuditTarget = "file"
+	AuditTargetTag          AuditTarget = "tag"
+	AuditTargetStopword     AuditTarget = "stopword"
+	AuditTargetStopwordPack AuditTarget = "stopword_pack"
+	AuditTargetTask         AuditTarget = "task"
+	AuditTargetLogComponent AuditTarget = "log_component"
+)
+
+type AuditEvent struct {
+	ID string `db:"id"`
+	// Who did it, empty for anonymous requests like failed logins
+	ActorID sql.NullString `db:"actor_id"`
+	// Whose account the event belongs to
+	UserID     sql.NullString `db:"user_id"`
+	Action     AuditAction    `db:"action"`
+	TargetType AuditTarget    `db:"target_type"`
+	TargetID   sql.NullString `db:"target_id"`
+	Details    types.JSONText `db:"details"`
+	RequestID  string         `db:"request_id"`
+	IP         string         `db:"ip"`
+	UserAgent  string         `db:"user_agent"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+type ListAuditEventFilter struct {
+	UserID     string
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+	PaginationFilter
+	SortFilter
+}
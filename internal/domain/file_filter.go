@@ -1,9 +1,24 @@
 package domain
 
+import "time"
+
 type ListFileFilter struct {
 	UserID   string
 	MimeType string
+	// Matched against declared metadata, files without it never match
+	Author  string
+	PagesGt *int
+	PagesLt *int
 	QueryFilter
 	PaginationFilter
 	SortFilter
 }
+
+// Selects files to process again, empty UserID matches files of all users
+type ReprocessFileFilter struct {
+	UserID      string
+	Status      FileStatus
+	MimeType    string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Properties a document declares about itself, stored as JSONB. Fields the
+// document doesn't set stay empty
+type FileMetadata struct {
+	Title      string     `json:"title,omitempty"`
+	Author     string     `json:"author,omitempty"`
+	Subject    string     `json:"subject,omitempty"`
+	Keywords   []string   `json:"keywords,omitempty"`
+	Producer   string     `json:"producer,omitempty"`
+	PageCount  int        `json:"page_count"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	ModifiedAt *time.Time `json:"modified_at,omitempty"`
+}
+
+func (m FileMetadata) Value() (driver.Value, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *FileMetadata) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	default:
+		return fmt.Errorf("domain: can't scan %T into FileMetadata", src)
+	}
+}
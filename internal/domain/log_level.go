@@ -0,0 +1,8 @@
+package domain
+
+// Level a logger component writes at, taken from root unless Overridden
+type LogLevel struct {
+	Component  string
+	Level      string
+	Overridden bool
+}
@@ -3,13 +3,20 @@ package domain
 import (
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type (
 	FileStatus     string
+	TextSource     string
 	ItemType       string
 	TagSource      string
+	TagRuleKind    string
+	TagRuleField   string
 	StopwordSource string
+	ApiKeyScope    string
+	UserRole       string
 )
 
 const (
@@ -19,6 +26,14 @@ const (
 	FileStatusError      FileStatus = "error"
 )
 
+// Where the text of a file came from, mixed files have OCR'd pages next
+// to pages with a text layer
+const (
+	TextSourceLayer TextSource = "text_layer"
+	TextSourceOcr   TextSource = "ocr"
+	TextSourceMixed TextSource = "mixed"
+)
+
 const (
 	ItemTypeText ItemType = "text"
 	ItemTypeUrl  ItemType = "url"
@@ -26,21 +41,122 @@ const (
 
 const (
 	TagSourceAuto   TagSource = "auto"
+	TagSourceRule   TagSource = "rule"
 	TagSourceManual TagSource = "manual"
 )
 
+const (
+	TagRuleKindRegex    TagRuleKind = "regex"
+	TagRuleKindKeywords TagRuleKind = "keywords"
+)
+
+const (
+	TagRuleFieldTitle   TagRuleField = "title"
+	TagRuleFieldContent TagRuleField = "content"
+	TagRuleFieldAny     TagRuleField = "any"
+)
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+const (
+	ApiKeyScopeItemsRead      ApiKeyScope = "items:read"
+	ApiKeyScopeItemsWrite     ApiKeyScope = "items:write"
+	ApiKeyScopeFilesRead      ApiKeyScope = "files:read"
+	ApiKeyScopeFilesWrite     ApiKeyScope = "files:write"
+	ApiKeyScopeTagsRead       ApiKeyScope = "tags:read"
+	ApiKeyScopeTagsWrite      ApiKeyScope = "tags:write"
+	ApiKeyScopeStopwordsRead  ApiKeyScope = "stopwords:read"
+	ApiKeyScopeStopwordsWrite ApiKeyScope = "stopwords:write"
+	ApiKeyScopeAdmin          ApiKeyScope = "admin"
+)
+
+// Scopes granted to the key issued on registration
+var DefaultApiKeyScopes = []ApiKeyScope{
+	ApiKeyScopeItemsRead, ApiKeyScopeItemsWrite,
+	ApiKeyScopeFilesRead, ApiKeyScopeFilesWrite,
+	ApiKeyScopeTagsRead, ApiKeyScopeTagsWrite,
+	ApiKeyScopeStopwordsRead, ApiKeyScopeStopwordsWrite,
+}
+
+const DefaultApiKeyName = "default"
+
+// Keys issued on login are named this followed by their prefix
+const LoginApiKeyNamePrefix = "login-"
+
 const (
 	StopwordSourceDefault StopwordSource = "default"
 	StopwordSourceUser    StopwordSource = "user"
 )
 
 type User struct {
-	ID        string    `db:"id"`
-	Email     string    `db:"email"`
-	Password  string    `db:"password"`
-	APIKey    string    `db:"api_key"`
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
+	ID                  string        `db:"id"`
+	Email               string        `db:"email"`
+	Password            string        `db:"password"`
+	Role                UserRole      `db:"role"`
+	DisabledAt          sql.NullTime  `db:"disabled_at"`
+	DeletionRequestedAt sql.NullTime  `db:"deletion_requested_at"`
+	MaxStorageBytes     sql.NullInt64 `db:"max_storage_bytes"`
+	MaxFileBytes        sql.NullInt64 `db:"max_file_bytes"`
+	MaxItems            sql.NullInt64 `db:"max_items"`
+	CreatedAt           time.Time     `db:"created_at"`
+	UpdatedAt           time.Time     `db:"updated_at"`
+}
+
+type PasswordResetToken struct {
+	ID        string       `db:"id"`
+	UserID    string       `db:"user_id"`
+	TokenHash string       `db:"token_hash"`
+	ExpiresAt time.Time    `db:"expires_at"`
+	UsedAt    sql.NullTime `db:"used_at"`
+	CreatedAt time.Time    `db:"created_at"`
+}
+
+type UserUsage struct {
+	UserID       string `db:"user_id"`
+	ItemCount    int    `db:"item_count"`
+	FileCount    int    `db:"file_count"`
+	StorageBytes int64  `db:"storage_bytes"`
+}
+
+// Counts over the whole instance, exported as metrics
+type Totals struct {
+	ItemCount     int                `db:"item_count"`
+	TagCount      int                `db:"tag_count"`
+	FilesByStatus map[FileStatus]int `db:"-"`
+}
+
+// Limits in effect for a user, zero means no limit
+type UserQuota struct {
+	MaxStorageBytes int64
+	MaxFileBytes    int64
+	MaxItems        int64
+}
+
+// Per-user overrides of configured quotas, nil fields fall back to defaults
+type UserQuotaOverride struct {
+	MaxStorageBytes *int64
+	MaxFileBytes    *int64
+	MaxItems        *int64
+}
+
+type ApiKey struct {
+	ID         string         `db:"id"`
+	UserID     string         `db:"user_id"`
+	Name       string         `db:"name"`
+	Prefix     string         `db:"prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     pq.StringArray `db:"scopes"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullTime   `db:"last_used_at"`
+	RevokedAt  sql.NullTime   `db:"revoked_at"`
+	CreatedAt  time.Time      `db:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at"`
+
+	// Plaintext key, only set right after the key was issued
+	Key string `db:"-"`
 }
 
 type Item struct {
@@ -58,20 +174,44 @@ type Item struct {
 }
 
 type File struct {
-	ID           string         `db:"id"`
-	UserID       string         `db:"user_id"`
-	OriginalName string         `db:"original_name"`
-	TextContent  sql.NullString `db:"text_content"`
-	S3Key        string         `db:"s3_key"`
-	Size         int64          `db:"size"`
-	MimeType     string         `db:"mime_type"`
-	Status       FileStatus     `db:"status"`
-	CreatedAt    time.Time      `db:"created_at"`
-	UpdatedAt    time.Time      `db:"updated_at"`
-	SearchVector string         `db:"search_vector"`
-	DeletedAt    sql.NullTime   `db:"deleted_at"`
+	ID            string          `db:"id"`
+	UserID        string          `db:"user_id"`
+	OriginalName  string          `db:"original_name"`
+	TextContent   sql.NullString  `db:"text_content"`
+	S3Key         string          `db:"s3_key"`
+	Size          int64           `db:"size"`
+	MimeType      string          `db:"mime_type"`
+	Status        FileStatus      `db:"status"`
+	KeyID         sql.NullString  `db:"key_id"`
+	WrappedKey    []byte          `db:"wrapped_key"`
+	Metadata      *FileMetadata   `db:"metadata"`
+	TextSource    sql.NullString  `db:"text_source"`
+	OcrConfidence sql.NullFloat64 `db:"ocr_confidence"`
+	ErrorMessage  sql.NullString  `db:"error_message"`
+	CreatedAt     time.Time       `db:"created_at"`
+	UpdatedAt     time.Time       `db:"updated_at"`
+	SearchVector  string          `db:"search_vector"`
+	DeletedAt     sql.NullTime    `db:"deleted_at"`
+
+	Tags         []Tag
+	HasThumbnail bool `db:"-"`
 }
 
+// Page of a file rendered as PNG, the thumbnail is stored as page 0
+type FilePreview struct {
+	FileID     string         `db:"file_id"`
+	Page       int            `db:"page"`
+	S3Key      string         `db:"s3_key"`
+	Width      int            `db:"width"`
+	Height     int            `db:"height"`
+	Size       int64          `db:"size"`
+	KeyID      sql.NullString `db:"key_id"`
+	WrappedKey []byte         `db:"wrapped_key"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+const FileThumbnailPage = 0
+
 type Tag struct {
 	ID        string    `db:"id"`
 	Name      string    `db:"name"`
@@ -80,6 +220,27 @@ type Tag struct {
 	UpdatedAt time.Time `db:"updated_at"`
 }
 
+type TagAlias struct {
+	Alias     string    `db:"alias"`
+	TagID     string    `db:"tag_id"`
+	UserID    string    `db:"user_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type TagRule struct {
+	ID        string         `db:"id"`
+	UserID    string         `db:"user_id"`
+	TagID     string         `db:"tag_id"`
+	Name      string         `db:"name"`
+	Kind      TagRuleKind    `db:"kind"`
+	Field     TagRuleField   `db:"field"`
+	Pattern   sql.NullString `db:"pattern"`
+	Keywords  pq.StringArray `db:"keywords"`
+	IsEnabled bool           `db:"is_enabled"`
+	CreatedAt time.Time      `db:"created_at"`
+	UpdatedAt time.Time      `db:"updated_at"`
+}
+
 type Stopword struct {
 	Word      string         `db:"word"`
 	UserID    string         `db:"user_id"`
@@ -89,8 +250,45 @@ type Stopword struct {
 	UpdatedAt time.Time      `db:"updated_at"`
 }
 
+type DefaultStopword struct {
+	Lang string `db:"lang"`
+	Word string `db:"word"`
+}
+
+type StopwordPack struct {
+	Lang      string `db:"lang"`
+	Name      string `db:"name"`
+	IsEnabled bool   `db:"is_enabled"`
+	WordCount int    `db:"word_count"`
+}
+
 type ItemTag struct {
-	ItemID string    `db:"item_id"`
-	TagID  string    `db:"tag_id"`
-	Source TagSource `db:"source"`
+	ItemID    string    `db:"item_id"`
+	TagID     string    `db:"tag_id"`
+	Source    TagSource `db:"source"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type TagStats struct {
+	Tag
+	ItemCount   int          `db:"item_count"`
+	AutoCount   int          `db:"auto_count"`
+	RuleCount   int          `db:"rule_count"`
+	ManualCount int          `db:"manual_count"`
+	LastUsedAt  sql.NullTime `db:"last_used_at"`
+
+	CoOccurrences []TagCoOccurrence
+}
+
+// Keyword a file declares about itself, Tag is set when the user already
+// has a tag of that name or alias
+type TagCandidate struct {
+	Word string
+	Tag  *Tag
+}
+
+type TagCoOccurrence struct {
+	TagID string
+	Name  string
+	Count int
 }
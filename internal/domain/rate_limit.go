@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Time until the window frees up a slot
+	Reset time.Duration
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "context"
+
+// Client details of the request that started the work, services read them
+// from the context when recording audit events
+type RequestMeta struct {
+	RequestID string
+	ClientIP  string
+	UserAgent string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
@@ -0,0 +1,15 @@
+package domain
+
+type ListTagRuleFilter struct {
+	UserID string
+	TagID  string
+}
+
+type TagRuleDryRunFilter struct {
+	UserID   string
+	Kind     TagRuleKind
+	Field    TagRuleField
+	Pattern  string
+	Keywords []string
+	PaginationFilter
+}
@@ -0,0 +1,10 @@
+package domain
+
+type TagStatsFilter struct {
+	UserID          string
+	MinCount        int
+	CoOccurrenceMax int
+	QueryFilter
+	PaginationFilter
+	SortFilter
+}
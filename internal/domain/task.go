@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Task that failed permanently or ran out of retries
+type ArchivedTask struct {
+	ID           string
+	Queue        string
+	Type         string
+	Payload      []byte
+	LastError    string
+	LastFailedAt time.Time
+	Retried      int
+	MaxRetry     int
+}
+
+type ListArchivedTaskFilter struct {
+	Queue string
+	PaginationFilter
+}
+
+// Processed and Failed are counted for the current day
+type QueueStats struct {
+	Queue     string
+	Size      int
+	Pending   int
+	Active    int
+	Scheduled int
+	Retry     int
+	Archived  int
+	Completed int
+	Processed int
+	Failed    int
+	Paused    bool
+	Latency   time.Duration
+}
@@ -0,0 +1,9 @@
+package domain
+
+type ListUserFilter struct {
+	Role     string
+	Disabled *bool
+	QueryFilter
+	PaginationFilter
+	SortFilter
+}
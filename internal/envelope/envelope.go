@@ -0,0 +1,163 @@
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"qvarkk/kvault/config"
+)
+
+const (
+	ProviderNone     = ""
+	ProviderConfig   = "config"
+	ProviderLocalKms = "local-kms"
+)
+
+const dataKeySize = 32
+
+var (
+	ErrUnknownKey = errors.New("envelope: unknown key")
+	ErrCorrupted  = errors.New("envelope: data is corrupted or was tampered with")
+)
+
+// Data key of a file wrapped by the key with KeyID
+type Envelope struct {
+	WrappedKey []byte
+	KeyID      string
+}
+
+type KeyWrapper interface {
+	Wrap(ctx context.Context, userID string, dataKey []byte) (*Envelope, error)
+	Unwrap(ctx context.Context, userID string, env Envelope) ([]byte, error)
+}
+
+type FileCipher interface {
+	Seal(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *Envelope, error)
+	SealAlways(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *Envelope, error)
+	Open(ctx context.Context, userID string, env Envelope, sealed io.ReadCloser) (io.ReadCloser, error)
+	OpenSeekable(ctx context.Context, userID string, env Envelope, sealed io.ReadSeekCloser) (io.ReadSeekCloser, error)
+	SealedSize(size int64) int64
+}
+
+// Picks a key provider and builds the cipher, nil means that no keys are
+// configured and stored files are neither encrypted nor decrypted
+func NewFileCipher(config config.EncryptionConfig) (FileCipher, error) {
+	var keys *Keyring
+	var err error
+
+	switch config.KeyProvider {
+	case ProviderNone:
+		if config.Enabled {
+			return nil, errors.New("envelope: encryption requires a key provider")
+		}
+		return nil, nil
+	case ProviderConfig:
+		keys, err = NewConfigKeyring(config.MasterKeyID, config.MasterKey, config.PerUserKeys)
+	case ProviderLocalKms:
+		keys, err = LoadLocalKms(config.KmsKeyringPath, config.PerUserKeys)
+	default:
+		return nil, fmt.Errorf("envelope: unknown key provider %q", config.KeyProvider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCipher(keys, config.Enabled), nil
+}
+
+// Files are sealed with a random data key each, only the wrapped data key
+// has to be stored next to the file
+type Cipher struct {
+	keys        KeyWrapper
+	sealUploads bool
+}
+
+func NewCipher(keys KeyWrapper, sealUploads bool) *Cipher {
+	return &Cipher{
+		keys:        keys,
+		sealUploads: sealUploads,
+	}
+}
+
+// Plaintext is returned as is with a nil envelope when sealing of new
+// uploads is off, files sealed before can still be opened
+func (c *Cipher) Seal(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *Envelope, error) {
+	if !c.sealUploads {
+		return plaintext, nil, nil
+	}
+
+	return c.SealAlways(ctx, userID, plaintext)
+}
+
+// Seals even with sealing of uploads off, for data derived from files
+// that are encrypted already
+func (c *Cipher) SealAlways(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *Envelope, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("envelope: failed to generate data key: %w", err)
+	}
+
+	env, err := c.keys.Wrap(ctx, userID, dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newSealReader(aead, plaintext), env, nil
+}
+
+// Closing the returned reader closes the sealed one
+func (c *Cipher) Open(ctx context.Context, userID string, env Envelope, sealed io.ReadCloser) (io.ReadCloser, error) {
+	dataKey, err := c.keys.Unwrap(ctx, userID, env)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOpenReader(aead, sealed), nil
+}
+
+// Random access variant of Open, for range requests. Seeking to the end of
+// the returned reader gives the plaintext size
+func (c *Cipher) OpenSeekable(
+	ctx context.Context,
+	userID string,
+	env Envelope,
+	sealed io.ReadSeekCloser,
+) (io.ReadSeekCloser, error) {
+	dataKey, err := c.keys.Unwrap(ctx, userID, env)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newOpenSeeker(aead, sealed)
+}
+
+func (c *Cipher) SealedSize(size int64) int64 {
+	return sealedSize(size)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,203 @@
+package envelope
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	kekSize = 32
+	// Suffix of key IDs whose wrapping key was derived for the file owner
+	perUserSuffix = ":user"
+)
+
+// Wrapping keys by ID, only the active one wraps new data keys
+type Keyring struct {
+	keys     map[string][]byte
+	activeID string
+	perUser  bool
+}
+
+func NewKeyring(keys map[string][]byte, activeID string, perUser bool) (*Keyring, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, activeID)
+	}
+
+	for id, key := range keys {
+		if len(key) != kekSize {
+			return nil, fmt.Errorf("envelope: key %q has to be %d bytes", id, kekSize)
+		}
+		if strings.HasSuffix(id, perUserSuffix) {
+			return nil, fmt.Errorf("envelope: key id %q can't end with %s", id, perUserSuffix)
+		}
+	}
+
+	return &Keyring{
+		keys:     keys,
+		activeID: activeID,
+		perUser:  perUser,
+	}, nil
+}
+
+// Single master key given as base64 in config
+func NewConfigKeyring(keyID, masterKey string, perUser bool) (*Keyring, error) {
+	key, err := base64.StdEncoding.DecodeString(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: master key is not valid base64: %w", err)
+	}
+
+	return NewKeyring(map[string][]byte{keyID: key}, keyID, perUser)
+}
+
+type localKmsFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// Stand-in for a KMS that keeps keys in a JSON file, one is generated when
+// the file doesn't exist. Keys are rotated by adding one and making it active
+func LoadLocalKms(path string, perUser bool) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		data, err = createLocalKms(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("envelope: failed to load keyring %s: %w", path, err)
+	}
+
+	var file localKmsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("envelope: failed to parse keyring %s: %w", path, err)
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("envelope: key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(keys, file.Active, perUser)
+}
+
+func createLocalKms(path string) ([]byte, error) {
+	key := make([]byte, kekSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(localKmsFile{
+		Active: "local-1",
+		Keys:   map[string]string{"local-1": base64.StdEncoding.EncodeToString(key)},
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".keyring-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// API and worker may start at once, linking fails for the one that loses
+	// and it reads the complete file of the winner
+	err = os.Link(tmp.Name(), path)
+	if errors.Is(err, fs.ErrExist) {
+		return os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Wrapped key is bound to the user, so it can't be moved to another user's file
+func (k *Keyring) Wrap(ctx context.Context, userID string, dataKey []byte) (*Envelope, error) {
+	keyID := k.activeID
+	if k.perUser {
+		keyID += perUserSuffix
+	}
+
+	kek, err := k.kek(keyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("envelope: failed to generate nonce: %w", err)
+	}
+
+	return &Envelope{
+		WrappedKey: aead.Seal(nonce, nonce, dataKey, []byte(userID)),
+		KeyID:      keyID,
+	}, nil
+}
+
+func (k *Keyring) Unwrap(ctx context.Context, userID string, env Envelope) ([]byte, error) {
+	kek, err := k.kek(env.KeyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(env.WrappedKey) < aead.NonceSize() {
+		return nil, ErrCorrupted
+	}
+
+	nonce, wrapped := env.WrappedKey[:aead.NonceSize()], env.WrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, wrapped, []byte(userID))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+
+	return dataKey, nil
+}
+
+// Per-user keys are derived from the keyring key, the key ID tells which
+// kind was used so switching PerUser doesn't break existing files
+func (k *Keyring) kek(keyID, userID string) ([]byte, error) {
+	baseID, perUser := strings.CutSuffix(keyID, perUserSuffix)
+
+	key, ok := k.keys[baseID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, baseID)
+	}
+
+	if !perUser {
+		return key, nil
+	}
+
+	return hkdf.Key(sha256.New, key, nil, "kvault user file key "+userID, kekSize)
+}
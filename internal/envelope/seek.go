@@ -0,0 +1,155 @@
+package envelope
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const sealedChunkSize = chunkSize + tagSize
+
+// Inverse of sealedSize, -1 when no sealing produces the size
+func plaintextSize(sealed int64) int64 {
+	body := sealed - int64(len(magic))
+	if body < tagSize {
+		return -1
+	}
+
+	chunks := (body + sealedChunkSize - 1) / sealedChunkSize
+	if body-(chunks-1)*sealedChunkSize < tagSize {
+		return -1
+	}
+
+	return body - chunks*tagSize
+}
+
+// Decrypts any range of sealed data by opening only the chunks it covers.
+// The last opened chunk is kept, so sequential reads open every chunk once.
+// Seeks of the sealed reader are expected to be cheap
+type openSeeker struct {
+	aead   cipher.AEAD
+	sealed io.ReadSeekCloser
+	size   int64
+	chunks int64
+	offset int64
+
+	started bool
+	buf     []byte
+	plain   []byte
+	// Index of the chunk in plain, -1 when none
+	chunk int64
+}
+
+func newOpenSeeker(aead cipher.AEAD, sealed io.ReadSeekCloser) (*openSeeker, error) {
+	sealedSize, err := sealed.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	size := plaintextSize(sealedSize)
+	if size < 0 {
+		return nil, ErrCorrupted
+	}
+
+	return &openSeeker{
+		aead:   aead,
+		sealed: sealed,
+		size:   size,
+		chunks: max((size+chunkSize-1)/chunkSize, 1),
+		buf:    make([]byte, sealedChunkSize),
+		chunk:  -1,
+	}, nil
+}
+
+func (r *openSeeker) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	index := r.offset / chunkSize
+	if index != r.chunk {
+		if err := r.openChunk(index); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain[r.offset-index*chunkSize:])
+	r.offset += int64(n)
+	return n, nil
+}
+
+func (r *openSeeker) openChunk(index int64) error {
+	if !r.started {
+		if err := r.checkMagic(); err != nil {
+			return err
+		}
+		r.started = true
+	}
+
+	position := int64(len(magic)) + index*sealedChunkSize
+	if _, err := r.sealed.Seek(position, io.SeekStart); err != nil {
+		return err
+	}
+
+	sealedLen := sealedChunkSize
+	last := index == r.chunks-1
+	if last {
+		sealedLen = int(r.size-index*chunkSize) + tagSize
+	}
+
+	if _, err := io.ReadFull(r.sealed, r.buf[:sealedLen]); err != nil {
+		return fmt.Errorf("envelope: failed to read chunk %d: %w", index, err)
+	}
+
+	ad := chunkMiddle
+	if last {
+		ad = chunkLast
+	}
+
+	plain, err := r.aead.Open(r.plain[:0], chunkNonce(r.aead, uint64(index)), r.buf[:sealedLen], ad)
+	if err != nil {
+		r.chunk = -1
+		return ErrCorrupted
+	}
+
+	r.plain = plain
+	r.chunk = index
+	return nil
+}
+
+func (r *openSeeker) checkMagic() error {
+	if _, err := r.sealed.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(r.sealed, header); err != nil || string(header) != magic {
+		return ErrCorrupted
+	}
+
+	return nil
+}
+
+func (r *openSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, fmt.Errorf("envelope: invalid whence %d", whence)
+	}
+
+	if offset < 0 {
+		return 0, errors.New("envelope: negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *openSeeker) Close() error {
+	return r.sealed.Close()
+}
@@ -0,0 +1,176 @@
+package envelope
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Sealed data is the magic followed by chunks of up to chunkSize plaintext
+// bytes, each sealed on its own. Nonces count chunks and the last chunk is
+// marked in additional data, so reordering and truncation are detected
+const (
+	magic     = "KVE1"
+	chunkSize = 64 * 1024
+	tagSize   = 16
+)
+
+var (
+	chunkMiddle = []byte{0}
+	chunkLast   = []byte{1}
+)
+
+func sealedSize(size int64) int64 {
+	chunks := max((size+chunkSize-1)/chunkSize, 1)
+	return int64(len(magic)) + size + chunks*tagSize
+}
+
+func chunkNonce(aead cipher.AEAD, counter uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	return nonce
+}
+
+// Reads a full chunk from src, it's the last one when nothing follows it
+func readChunk(src *bufio.Reader, buf []byte) (int, bool, error) {
+	n, err := io.ReadFull(src, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, true, nil
+	}
+	if err != nil {
+		return n, false, err
+	}
+
+	if _, err := src.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return n, true, nil
+		}
+		return n, false, err
+	}
+
+	return n, false, nil
+}
+
+type sealReader struct {
+	aead    cipher.AEAD
+	src     *bufio.Reader
+	plain   []byte
+	out     []byte
+	pending []byte
+	counter uint64
+	done    bool
+}
+
+func newSealReader(aead cipher.AEAD, src io.Reader) *sealReader {
+	return &sealReader{
+		aead:    aead,
+		src:     bufio.NewReader(src),
+		plain:   make([]byte, chunkSize),
+		out:     make([]byte, 0, chunkSize+tagSize),
+		pending: []byte(magic),
+	}
+}
+
+func (r *sealReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *sealReader) sealNext() error {
+	n, last, err := readChunk(r.src, r.plain)
+	if err != nil {
+		return err
+	}
+
+	ad := chunkMiddle
+	if last {
+		ad = chunkLast
+	}
+
+	r.pending = r.aead.Seal(r.out[:0], chunkNonce(r.aead, r.counter), r.plain[:n], ad)
+	r.counter++
+	r.done = last
+	return nil
+}
+
+type openReader struct {
+	aead    cipher.AEAD
+	closer  io.Closer
+	src     *bufio.Reader
+	sealed  []byte
+	plain   []byte
+	pending []byte
+	counter uint64
+	started bool
+	done    bool
+}
+
+func newOpenReader(aead cipher.AEAD, src io.ReadCloser) *openReader {
+	return &openReader{
+		aead:   aead,
+		closer: src,
+		src:    bufio.NewReader(src),
+		sealed: make([]byte, chunkSize+tagSize),
+		plain:  make([]byte, 0, chunkSize),
+	}
+}
+
+func (r *openReader) Read(p []byte) (int, error) {
+	if !r.started {
+		header := make([]byte, len(magic))
+		if _, err := io.ReadFull(r.src, header); err != nil || string(header) != magic {
+			return 0, ErrCorrupted
+		}
+		r.started = true
+	}
+
+	for len(r.pending) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.openNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *openReader) openNext() error {
+	n, last, err := readChunk(r.src, r.sealed)
+	if err != nil {
+		return err
+	}
+
+	ad := chunkMiddle
+	if last {
+		ad = chunkLast
+	}
+
+	r.pending, err = r.aead.Open(r.plain[:0], chunkNonce(r.aead, r.counter), r.sealed[:n], ad)
+	if err != nil {
+		return ErrCorrupted
+	}
+
+	r.counter++
+	r.done = last
+	return nil
+}
+
+func (r *openReader) Close() error {
+	return r.closer.Close()
+}
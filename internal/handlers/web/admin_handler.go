@@ -0,0 +1,679 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"qvarkk/kvault/internal/domain"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminService interface {
+	ListUsers(context.Context, domain.ListUserFilter) ([]domain.User, int, error)
+	GetUser(ctx context.Context, userID string) (*domain.User, error)
+	DisableUser(ctx context.Context, userID, adminID string) (*domain.User, error)
+	EnableUser(ctx context.Context, userID, adminID string) (*domain.User, error)
+	RotateUserKeys(ctx context.Context, userID, adminID string) (int, error)
+	GetUserUsage(ctx context.Context, userID string) (*domain.UserUsage, error)
+	ListDefaultStopwords(ctx context.Context, lang, query string) ([]domain.DefaultStopword, error)
+	CreateDefaultStopword(ctx context.Context, lang, word string) (*domain.DefaultStopword, error)
+	DeleteDefaultStopword(ctx context.Context, lang, word string) error
+}
+
+type AdminQuotaService interface {
+	SetUserQuota(ctx context.Context, userID, adminID string, override domain.UserQuotaOverride) (*domain.UserQuota, error)
+}
+
+type AdminFileService interface {
+	ReprocessMany(ctx context.Context, params domain.ReprocessFileFilter, actorID string) (int, error)
+}
+
+type AdminQueueService interface {
+	ListArchived(context.Context, domain.ListArchivedTaskFilter) ([]domain.ArchivedTask, int, error)
+	RetryArchived(ctx context.Context, queue, taskID, adminID string) error
+	DeleteArchived(ctx context.Context, queue, taskID, adminID string) error
+}
+
+type AdminLogService interface {
+	ListLevels(context.Context) []domain.LogLevel
+	SetLevel(ctx context.Context, component, level, adminID string) (*domain.LogLevel, error)
+	ResetLevel(ctx context.Context, component, adminID string) (*domain.LogLevel, error)
+}
+
+type AdminHandler struct {
+	adminService AdminService
+	quotaService AdminQuotaService
+	fileService  AdminFileService
+	queueService AdminQueueService
+	logService   AdminLogService
+}
+
+func NewAdminHandler(
+	adminService AdminService,
+	quotaService AdminQuotaService,
+	fileService AdminFileService,
+	queueService AdminQueueService,
+	logService AdminLogService,
+) *AdminHandler {
+	return &AdminHandler{
+		adminService: adminService,
+		quotaService: quotaService,
+		fileService:  fileService,
+		queueService: queueService,
+		logService:   logService,
+	}
+}
+
+type listUserRequest struct {
+	Query    string `form:"q"`
+	Role     string `form:"role" binding:"omitempty,oneof=user admin"`
+	Disabled *bool  `form:"disabled"`
+	PaginationParams
+	UserSortingParams
+}
+
+type userIDUri struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// Omitted or null limits fall back to configured defaults, zero removes a limit
+type setUserQuotaRequest struct {
+	MaxStorageBytes *int64 `json:"max_storage_bytes" binding:"omitempty,min=0" example:"5368709120"`
+	MaxFileBytes    *int64 `json:"max_file_bytes" binding:"omitempty,min=0" example:"104857600"`
+	MaxItems        *int64 `json:"max_items" binding:"omitempty,min=0" example:"50000"`
+}
+
+// Without user_id files of all users are reprocessed
+type adminReprocessFilesRequest struct {
+	UserID string `json:"user_id" binding:"omitempty,uuid"`
+	reprocessFilesRequest
+}
+
+type listArchivedTaskRequest struct {
+	Queue string `form:"queue,default=default"`
+	PaginationParams
+}
+
+type archivedTaskUri struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type taskQueueQuery struct {
+	Queue string `form:"queue,default=default"`
+}
+
+type logComponentUri struct {
+	Component string `uri:"component" binding:"required"`
+}
+
+type setLogLevelRequest struct {
+	Level string `json:"level" binding:"required,oneof=debug info warn error" example:"debug"`
+}
+
+type listDefaultStopwordRequest struct {
+	Lang  string `form:"lang"`
+	Query string `form:"q"`
+}
+
+type createDefaultStopwordRequest struct {
+	Lang string `json:"lang" binding:"required" example:"en"`
+	Word string `json:"word" binding:"required,max=100" example:"the"`
+}
+
+type defaultStopwordUri struct {
+	Lang string `uri:"lang" binding:"required"`
+	Word string `uri:"word" binding:"required"`
+}
+
+// @Summary      Get all users
+// @Description  Returns a paginated list of users filtered by email, role and disabled state
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param				 params query listUserRequest false "Query parameters"
+// @Success      200   {object}  PaginatedResponse[UserResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users [get]
+func (h *AdminHandler) ListUsers(ctx *gin.Context) error {
+	var req listUserRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	params := domain.ListUserFilter{
+		Role:     req.Role,
+		Disabled: req.Disabled,
+		QueryFilter: domain.QueryFilter{
+			Query: req.Query,
+		},
+		PaginationFilter: domain.PaginationFilter{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+		SortFilter: domain.SortFilter{
+			Direction: req.Direction,
+			Column:    req.Column,
+		},
+	}
+
+	users, count, err := h.adminService.ListUsers(ctx.Request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	userResponses := make([]UserResponse, len(users))
+	for i, user := range users {
+		userResponses[i] = toUserResponse(&user)
+	}
+
+	ctx.JSON(http.StatusOK, toPaginatedResponse(userResponses, count, req.Page, req.PageSize))
+	return nil
+}
+
+// @Summary      Get a user
+// @Description  Gets any user by ID
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200   {object}  UserResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id} [get]
+func (h *AdminHandler) GetUser(ctx *gin.Context) error {
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	user, err := h.adminService.GetUser(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toUserResponse(user))
+	return nil
+}
+
+// @Summary      Disable a user
+// @Description  Disables the account, its API keys stop working until it's enabled again
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200   {object}  UserResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id}/disable [post]
+func (h *AdminHandler) DisableUser(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	user, err := h.adminService.DisableUser(ctx.Request.Context(), uri.ID, adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toUserResponse(user))
+	return nil
+}
+
+// @Summary      Enable a user
+// @Description  Enables a previously disabled account
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200   {object}  UserResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id}/enable [post]
+func (h *AdminHandler) EnableUser(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	user, err := h.adminService.EnableUser(ctx.Request.Context(), uri.ID, adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toUserResponse(user))
+	return nil
+}
+
+// @Summary      Force API key rotation
+// @Description  Replaces values of all user's API keys, the user has to log in
+// @Description  again to get a working default key
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200   {object}  KeyRotationResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id}/rotate-keys [post]
+func (h *AdminHandler) RotateUserKeys(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	rotated, err := h.adminService.RotateUserKeys(ctx.Request.Context(), uri.ID, adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, KeyRotationResponse{Rotated: rotated})
+	return nil
+}
+
+// @Summary      Get user usage
+// @Description  Returns item and file counts and total storage used by the user
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Success      200   {object}  UserUsageResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id}/usage [get]
+func (h *AdminHandler) GetUserUsage(ctx *gin.Context) error {
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	usage, err := h.adminService.GetUserUsage(ctx.Request.Context(), uri.ID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toUserUsageResponse(usage))
+	return nil
+}
+
+// @Summary      Set user quota
+// @Description  Replaces quota overrides of the user and returns limits in effect.
+// @Description  Omitted limits fall back to configured defaults, zero removes a limit
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "User ID"
+// @Param        body body setUserQuotaRequest true "Quota overrides"
+// @Success      200   {object}  QuotaResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/users/{id}/quota [put]
+func (h *AdminHandler) SetUserQuota(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri userIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var req setUserQuotaRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	override := domain.UserQuotaOverride{
+		MaxStorageBytes: req.MaxStorageBytes,
+		MaxFileBytes:    req.MaxFileBytes,
+		MaxItems:        req.MaxItems,
+	}
+
+	quota, err := h.quotaService.SetUserQuota(ctx.Request.Context(), uri.ID, adminID, override)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toQuotaResponse(quota))
+	return nil
+}
+
+// @Summary      Get default stopwords
+// @Description  Returns global default stopwords, optionally of one language pack
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param				 params query listDefaultStopwordRequest false "Query parameters"
+// @Success      200   {object}  ListResponse[DefaultStopwordResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/stopwords [get]
+func (h *AdminHandler) ListDefaultStopwords(ctx *gin.Context) error {
+	var req listDefaultStopwordRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	stopwords, err := h.adminService.ListDefaultStopwords(ctx.Request.Context(), req.Lang, req.Query)
+	if err != nil {
+		return err
+	}
+
+	stopwordResponses := make([]DefaultStopwordResponse, len(stopwords))
+	for i, stopword := range stopwords {
+		stopwordResponses[i] = toDefaultStopwordResponse(&stopword)
+	}
+
+	ctx.JSON(http.StatusOK, toListResponse(stopwordResponses))
+	return nil
+}
+
+// @Summary      Add a default stopword
+// @Description  Adds a global stopword to a language pack
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body body createDefaultStopwordRequest true "Stopword data"
+// @Success      201   {object}  DefaultStopwordResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/stopwords [post]
+func (h *AdminHandler) CreateDefaultStopword(ctx *gin.Context) error {
+	var req createDefaultStopwordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	stopword, err := h.adminService.CreateDefaultStopword(ctx.Request.Context(), req.Lang, req.Word)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusCreated, toDefaultStopwordResponse(stopword))
+	return nil
+}
+
+// @Summary      Delete a default stopword
+// @Description  Removes a global stopword from a language pack
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        lang path string true "Pack language"
+// @Param        word path string true "Stopword"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/stopwords/{lang}/{word} [delete]
+func (h *AdminHandler) DeleteDefaultStopword(ctx *gin.Context) error {
+	var uri defaultStopwordUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	err := h.adminService.DeleteDefaultStopword(ctx.Request.Context(), uri.Lang, uri.Word)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      Reprocess files of all users
+// @Description  Queues matching files of all users, or of one user, for
+// @Description  processing again, e.g. after the extractor was upgraded
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body adminReprocessFilesRequest true "Filters"
+// @Success      202   {object}  ReprocessResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/files/reprocess [post]
+func (h *AdminHandler) ReprocessFiles(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var req adminReprocessFilesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return err
+	}
+
+	queued, err := h.fileService.ReprocessMany(ctx.Request.Context(), req.toFilter(req.UserID), adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusAccepted, ReprocessResponse{Queued: queued})
+	return nil
+}
+
+// @Summary      Get archived tasks
+// @Description  Returns tasks of the queue that failed permanently or ran out
+// @Description  of retries, most recent failures first
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param				 params query listArchivedTaskRequest false "Query parameters"
+// @Success      200   {object}  PaginatedResponse[ArchivedTaskResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/tasks/archived [get]
+func (h *AdminHandler) ListArchivedTasks(ctx *gin.Context) error {
+	var req listArchivedTaskRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	params := domain.ListArchivedTaskFilter{
+		Queue: req.Queue,
+		PaginationFilter: domain.PaginationFilter{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+	}
+
+	archived, total, err := h.queueService.ListArchived(ctx.Request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	taskResponses := make([]ArchivedTaskResponse, len(archived))
+	for i, task := range archived {
+		taskResponses[i] = toArchivedTaskResponse(&task)
+	}
+
+	ctx.JSON(http.StatusOK, toPaginatedResponse(taskResponses, total, req.Page, req.PageSize))
+	return nil
+}
+
+// @Summary      Retry an archived task
+// @Description  Queues the archived task again with a fresh retry budget
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "Task ID"
+// @Param        params query taskQueueQuery false "Query parameters"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/tasks/archived/{id}/retry [post]
+func (h *AdminHandler) RetryArchivedTask(ctx *gin.Context) error {
+	return h.withArchivedTaskAction(ctx, h.queueService.RetryArchived)
+}
+
+// @Summary      Delete an archived task
+// @Description  Drops the archived task for good
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "Task ID"
+// @Param        params query taskQueueQuery false "Query parameters"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/tasks/archived/{id} [delete]
+func (h *AdminHandler) DeleteArchivedTask(ctx *gin.Context) error {
+	return h.withArchivedTaskAction(ctx, h.queueService.DeleteArchived)
+}
+
+func (h *AdminHandler) withArchivedTaskAction(
+	ctx *gin.Context,
+	fn func(ctx context.Context, queue, taskID, adminID string) error,
+) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri archivedTaskUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var query taskQueueQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		return err
+	}
+
+	if err := fn(ctx.Request.Context(), query.Queue, uri.ID, adminID); err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      List log levels
+// @Description  Returns the level every logger component of the API writes
+// @Description  at. Components without an override follow root
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200   {array}   LogLevelResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/log-levels [get]
+func (h *AdminHandler) ListLogLevels(ctx *gin.Context) error {
+	levels := h.logService.ListLevels(ctx.Request.Context())
+
+	levelResponses := make([]LogLevelResponse, len(levels))
+	for i, level := range levels {
+		levelResponses[i] = toLogLevelResponse(&level)
+	}
+
+	ctx.JSON(http.StatusOK, levelResponses)
+	return nil
+}
+
+// @Summary      Set log level
+// @Description  Changes the level of a logger component right away. Setting
+// @Description  root changes every component without an override. Only the
+// @Description  API process is affected and the change is lost on restart, so
+// @Description  components only the worker logs under are rejected
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        component path string true "Component" Enums(root, access, http)
+// @Param        body body setLogLevelRequest true "Level"
+// @Success      200   {object}  LogLevelResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/log-levels/{component} [put]
+func (h *AdminHandler) SetLogLevel(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri logComponentUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var req setLogLevelRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return err
+	}
+
+	level, err := h.logService.SetLevel(ctx.Request.Context(), uri.Component, req.Level, adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toLogLevelResponse(level))
+	return nil
+}
+
+// @Summary      Reset log level
+// @Description  Drops the override of a component so it follows root again,
+// @Description  root goes back to the configured level
+// @Tags         Admin
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        component path string true "Component" Enums(root, access, http)
+// @Success      200   {object}  LogLevelResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Component not used by the API"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /admin/log-levels/{component} [delete]
+func (h *AdminHandler) ResetLogLevel(ctx *gin.Context) error {
+	adminID := ctx.MustGet("userID").(string)
+
+	var uri logComponentUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	level, err := h.logService.ResetLevel(ctx.Request.Context(), uri.Component, adminID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toLogLevelResponse(level))
+	return nil
+}
@@ -0,0 +1,117 @@
+package web
+
+import (
+	"encoding/json"
+	"qvarkk/kvault/internal/domain"
+	"time"
+)
+
+type UserUsageResponse struct {
+	UserID       string `json:"user_id"`
+	ItemCount    int    `json:"item_count"`
+	FileCount    int    `json:"file_count"`
+	StorageBytes int64  `json:"storage_bytes"`
+}
+
+func toUserUsageResponse(usage *domain.UserUsage) UserUsageResponse {
+	return UserUsageResponse{
+		UserID:       usage.UserID,
+		ItemCount:    usage.ItemCount,
+		FileCount:    usage.FileCount,
+		StorageBytes: usage.StorageBytes,
+	}
+}
+
+type KeyRotationResponse struct {
+	Rotated int `json:"rotated"`
+}
+
+type DefaultStopwordResponse struct {
+	Lang string `json:"lang"`
+	Word string `json:"word"`
+}
+
+func toDefaultStopwordResponse(stopword *domain.DefaultStopword) DefaultStopwordResponse {
+	return DefaultStopwordResponse{
+		Lang: stopword.Lang,
+		Word: stopword.Word,
+	}
+}
+
+type ArchivedTaskResponse struct {
+	ID           string          `json:"id"`
+	Queue        string          `json:"queue"`
+	Type         string          `json:"type" example:"pdf:process"`
+	Payload      json.RawMessage `json:"payload" swaggertype:"object"`
+	LastError    string          `json:"last_error"`
+	LastFailedAt string          `json:"last_failed_at"`
+	Retried      int             `json:"retried"`
+	MaxRetry     int             `json:"max_retry"`
+}
+
+func toArchivedTaskResponse(task *domain.ArchivedTask) ArchivedTaskResponse {
+	// payloads are JSON encoded task structs, anything else is passed as a string
+	payload := json.RawMessage(task.Payload)
+	if !json.Valid(payload) {
+		payload, _ = json.Marshal(string(task.Payload))
+	}
+
+	return ArchivedTaskResponse{
+		ID:           task.ID,
+		Queue:        task.Queue,
+		Type:         task.Type,
+		Payload:      payload,
+		LastError:    task.LastError,
+		LastFailedAt: task.LastFailedAt.Format(time.RFC3339),
+		Retried:      task.Retried,
+		MaxRetry:     task.MaxRetry,
+	}
+}
+
+type QueueStatsResponse struct {
+	Queue     string `json:"queue" example:"default"`
+	Size      int    `json:"size"`
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Scheduled int    `json:"scheduled"`
+	Retry     int    `json:"retry"`
+	Archived  int    `json:"archived"`
+	Completed int    `json:"completed"`
+	// counted for the current day
+	ProcessedToday int     `json:"processed_today"`
+	FailedToday    int     `json:"failed_today"`
+	Paused         bool    `json:"paused"`
+	LatencySeconds float64 `json:"latency_seconds"`
+}
+
+func toQueueStatsResponse(stats *domain.QueueStats) QueueStatsResponse {
+	return QueueStatsResponse{
+		Queue:          stats.Queue,
+		Size:           stats.Size,
+		Pending:        stats.Pending,
+		Active:         stats.Active,
+		Scheduled:      stats.Scheduled,
+		Retry:          stats.Retry,
+		Archived:       stats.Archived,
+		Completed:      stats.Completed,
+		ProcessedToday: stats.Processed,
+		FailedToday:    stats.Failed,
+		Paused:         stats.Paused,
+		LatencySeconds: stats.Latency.Seconds(),
+	}
+}
+
+type LogLevelResponse struct {
+	Component string `json:"component" example:"http"`
+	Level     string `json:"level" example:"info"`
+	// false when the level is taken from root
+	Overridden bool `json:"overridden"`
+}
+
+func toLogLevelResponse(level *domain.LogLevel) LogLevelResponse {
+	return LogLevelResponse{
+		Component:  level.Component,
+		Level:      level.Level,
+		Overridden: level.Overridden,
+	}
+}
@@ -0,0 +1,168 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyService interface {
+	CreateNew(context.Context, services.CreateApiKeyInput) (*domain.ApiKey, error)
+	List(ctx context.Context, userID string) ([]domain.ApiKey, error)
+	Revoke(ctx context.Context, keyID, userID string, callerScopes []string) error
+	Rotate(ctx context.Context, keyID, userID string, callerScopes []string) (*domain.ApiKey, error)
+}
+
+type ApiKeyHandler struct {
+	apiKeyService ApiKeyService
+}
+
+func NewApiKeyHandler(apiKeyService ApiKeyService) *ApiKeyHandler {
+	return &ApiKeyHandler{apiKeyService: apiKeyService}
+}
+
+type createApiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100" example:"backup script"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=items:read items:write files:read files:write tags:read tags:write stopwords:read stopwords:write admin" example:"items:read,files:read"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2030-01-01T00:00:00Z"`
+}
+
+type apiKeyIDUri struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// @Summary      Create an API key
+// @Description  Creates a named API key limited to the given scopes. A key can
+// @Description  only grant scopes that the key used for the request has.
+// @Description  The key is shown only in this response
+// @Tags         API keys
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body body createApiKeyRequest true "API key data"
+// @Success      201   {object}  ApiKeyResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/keys [post]
+func (h *ApiKeyHandler) Create(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+	grantorScopes := ctx.MustGet("apiKeyScopes").([]string)
+
+	var req createApiKeyRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	apiKeyInput := services.CreateApiKeyInput{
+		UserID:        userID,
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresAt:     req.ExpiresAt,
+		GrantorScopes: grantorScopes,
+	}
+
+	apiKey, err := h.apiKeyService.CreateNew(ctx.Request.Context(), apiKeyInput)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusCreated, toApiKeyResponseWithKey(apiKey))
+	return nil
+}
+
+// @Summary      Get API keys
+// @Description  Returns all API keys of the User that weren't revoked
+// @Tags         API keys
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200   {object}  ListResponse[ApiKeyResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/keys [get]
+func (h *ApiKeyHandler) List(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	apiKeys, err := h.apiKeyService.List(ctx.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	apiKeyResponses := make([]ApiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		apiKeyResponses[i] = toApiKeyResponse(&apiKey)
+	}
+
+	ctx.JSON(http.StatusOK, toListResponse(apiKeyResponses))
+	return nil
+}
+
+// @Summary      Revoke an API key
+// @Description  Revokes an API key by ID, it can't be used afterwards.
+// @Description  The key used for the request must have every scope of the revoked key
+// @Tags         API keys
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "API key ID"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/keys/{id} [delete]
+func (h *ApiKeyHandler) Revoke(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+	callerScopes := ctx.MustGet("apiKeyScopes").([]string)
+
+	var uri apiKeyIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	err := h.apiKeyService.Revoke(ctx.Request.Context(), uri.ID, userID, callerScopes)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      Rotate an API key
+// @Description  Replaces the value of an API key by ID keeping its name, scopes and expiry.
+// @Description  The key used for the request must have every scope of the rotated key.
+// @Description  The new key is shown only in this response
+// @Tags         API keys
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "API key ID"
+// @Success      200   {object}  ApiKeyResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/keys/{id}/rotate [post]
+func (h *ApiKeyHandler) Rotate(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+	callerScopes := ctx.MustGet("apiKeyScopes").([]string)
+
+	var uri apiKeyIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	apiKey, err := h.apiKeyService.Rotate(ctx.Request.Context(), uri.ID, userID, callerScopes)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toApiKeyResponseWithKey(apiKey))
+	return nil
+}
@@ -0,0 +1,47 @@
+package web
+
+import (
+	"qvarkk/kvault/internal/domain"
+	"time"
+)
+
+type ApiKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Key        string   `json:"key,omitempty"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  *string  `json:"expires_at"`
+	LastUsedAt *string  `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+func toApiKeyResponse(apiKey *domain.ApiKey) ApiKeyResponse {
+	response := ApiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: apiKey.UpdatedAt.Format(time.RFC3339),
+	}
+
+	if apiKey.ExpiresAt.Valid {
+		expiresAt := apiKey.ExpiresAt.Time.Format(time.RFC3339)
+		response.ExpiresAt = &expiresAt
+	}
+
+	if apiKey.LastUsedAt.Valid {
+		lastUsedAt := apiKey.LastUsedAt.Time.Format(time.RFC3339)
+		response.LastUsedAt = &lastUsedAt
+	}
+
+	return response
+}
+
+func toApiKeyResponseWithKey(apiKey *domain.ApiKey) ApiKeyResponse {
+	response := toApiKeyResponse(apiKey)
+	response.Key = apiKey.Key
+	return response
+}
@@ -0,0 +1,97 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"qvarkk/kvault/internal/domain"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditService interface {
+	List(ctx context.Context, params domain.ListAuditEventFilter, viewerID string, isAdmin bool) ([]domain.AuditEvent, int, error)
+}
+
+type AuditHandler struct {
+	auditService AuditService
+}
+
+func NewAuditHandler(auditService AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+type listAuditEventRequest struct {
+	// Admins only, users always get their own events
+	UserID     string    `form:"user_id" binding:"omitempty,uuid"`
+	ActorID    string    `form:"actor_id" binding:"omitempty,uuid"`
+	Action     string    `form:"action" example:"item.deleted"`
+	TargetType string    `form:"target_type" example:"item"`
+	TargetID   string    `form:"target_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	PaginationParams
+	DescendingSortingParams
+}
+
+// @Summary      Get audit events
+// @Description  Returns security and data relevant events of the user's account.
+// @Description  Admins using a key with the admin scope get events of all users
+// @Tags         Audit
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param				 params query listAuditEventRequest false "Query parameters"
+// @Success      200   {object}  PaginatedResponse[AuditEventResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /audit [get]
+func (h *AuditHandler) List(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+	userRole := ctx.MustGet("userRole").(domain.UserRole)
+	scopes := ctx.MustGet("apiKeyScopes").([]string)
+
+	var req listAuditEventRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	params := domain.ListAuditEventFilter{
+		UserID:     req.UserID,
+		ActorID:    req.ActorID,
+		Action:     req.Action,
+		TargetType: req.TargetType,
+		TargetID:   req.TargetID,
+		PaginationFilter: domain.PaginationFilter{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+		SortFilter: domain.SortFilter{
+			Direction: req.Direction,
+			Column:    "created_at",
+		},
+	}
+	if !req.From.IsZero() {
+		params.From = &req.From
+	}
+	if !req.To.IsZero() {
+		params.To = &req.To
+	}
+
+	isAdmin := userRole == domain.UserRoleAdmin && slices.Contains(scopes, string(domain.ApiKeyScopeAdmin))
+
+	events, count, err := h.auditService.List(ctx.Request.Context(), params, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	eventResponses := make([]AuditEventResponse, len(events))
+	for i, event := range events {
+		eventResponses[i] = toAuditEventResponse(&event)
+	}
+
+	ctx.JSON(http.StatusOK, toPaginatedResponse(eventResponses, count, req.Page, req.PageSize))
+	return nil
+}
@@ -0,0 +1,42 @@
+package web
+
+import (
+	"encoding/json"
+	"qvarkk/kvault/internal/domain"
+	"time"
+)
+
+type AuditEventResponse struct {
+	ID         string          `json:"id"`
+	ActorID    string          `json:"actor_id,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id,omitempty"`
+	Details    json.RawMessage `json:"details" swaggertype:"object"`
+	RequestID  string          `json:"request_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  string          `json:"created_at"`
+}
+
+func toAuditEventResponse(event *domain.AuditEvent) AuditEventResponse {
+	details := json.RawMessage(event.Details)
+	if len(details) == 0 {
+		details = json.RawMessage("{}")
+	}
+
+	return AuditEventResponse{
+		ID:         event.ID,
+		ActorID:    event.ActorID.String,
+		UserID:     event.UserID.String,
+		Action:     string(event.Action),
+		TargetType: string(event.TargetType),
+		TargetID:   event.TargetID.String,
+		Details:    details,
+		RequestID:  event.RequestID,
+		IP:         event.IP,
+		UserAgent:  event.UserAgent,
+		CreatedAt:  event.CreatedAt.Format(time.RFC3339),
+	}
+}
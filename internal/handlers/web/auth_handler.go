@@ -4,29 +4,44 @@ import (
 	"context"
 	"net/http"
 	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AuthService interface {
-	GenerateApiKey(context.Context) (string, error)
-	RegisterNewUser(ctx context.Context, email string, password string) (*domain.User, error)
-	VerifyCredentials(ctx context.Context, email string, password string) (*domain.User, error)
-	RotateApiKey(ctx context.Context, userID string) (*domain.User, error)
+	RegisterNewUser(context.Context, services.RegisterUserInput) (*domain.User, *domain.ApiKey, error)
+	VerifyCredentials(context.Context, services.LoginInput) (*domain.User, *domain.ApiKey, error)
+	RotateApiKey(ctx context.Context, keyID string) (*domain.ApiKey, error)
 }
 
 type AuthUserService interface {
 	GetByID(context.Context, string) (*domain.User, error)
 }
 
+type AccountService interface {
+	ChangePassword(context.Context, services.ChangePasswordInput) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword, clientIP string) error
+	RequestDeletion(ctx context.Context, userID, password string) (string, error)
+}
+
+type UsageService interface {
+	GetUsage(ctx context.Context, userID string) (*domain.UserUsage, *domain.UserQuota, error)
+}
+
 type AuthHandler struct {
-	authService AuthService
-	userService AuthUserService
+	authService    AuthService
+	userService    AuthUserService
+	accountService AccountService
+	usageService   UsageService
 }
 
 type registerUserRequest struct {
 	Email    string `json:"email" binding:"required,email" example:"example@mail.com"`
 	Password string `json:"password" binding:"required,min=8" example:"#strongPwd?123."`
+	// Solution of the registration challenge when one is configured
+	Challenge string `json:"challenge" example:"1767225600:8f3a1c"`
 }
 
 type authenticateUserRequest struct {
@@ -34,22 +49,51 @@ type authenticateUserRequest struct {
 	Password string `json:"password" binding:"required" example:"#strongPwd?123."`
 }
 
-func NewAuthHandler(authService AuthService, userService AuthUserService) *AuthHandler {
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"#strongPwd?123."`
+	NewPassword     string `json:"new_password" binding:"required,min=8" example:"#strongerPwd?456."`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password" binding:"required" example:"#strongPwd?123."`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email" example:"example@mail.com"`
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8" example:"#strongerPwd?456."`
+}
+
+func NewAuthHandler(
+	authService AuthService,
+	userService AuthUserService,
+	accountService AccountService,
+	usageService UsageService,
+) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		userService: userService,
+		authService:    authService,
+		userService:    userService,
+		accountService: accountService,
+		usageService:   usageService,
 	}
 }
 
 // @Summary      User registration
 // @Description  Creates a user record in database with given credentials
-// @Description  and returns user's information
+// @Description  and returns user's information with the default API key.
+// @Description  Depending on configuration a captcha token or a proof of work
+// @Description  "<unix seconds>:<nonce>" for sha256("<email>:<solution>") is required
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
 // @Param        body body registerUserRequest true "User credentials"
 // @Success      201   {object}  UserResponse
+// @Failure      403   {object}  httpx.ErrorResponse
 // @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      429   {object}  httpx.ErrorResponse
 // @Failure      500   {object}  httpx.ErrorResponse
 // @Router       /auth/register [post]
 func (h *AuthHandler) RegisterUser(ctx *gin.Context) error {
@@ -58,17 +102,26 @@ func (h *AuthHandler) RegisterUser(ctx *gin.Context) error {
 		return err
 	}
 
-	user, err := h.authService.RegisterNewUser(ctx.Request.Context(), req.Email, req.Password)
+	input := services.RegisterUserInput{
+		Email:     req.Email,
+		Password:  req.Password,
+		ClientIP:  ctx.ClientIP(),
+		Challenge: req.Challenge,
+	}
+
+	user, apiKey, err := h.authService.RegisterNewUser(ctx.Request.Context(), input)
 	if err != nil {
 		return err
 	}
 
-	ctx.JSON(http.StatusCreated, toUserResponseWithApiKey(user))
+	ctx.JSON(http.StatusCreated, toUserResponseWithApiKey(user, apiKey))
 	return nil
 }
 
 // @Summary      User authentication
-// @Description  Verifies user credentials
+// @Description  Verifies user credentials and issues a new API key with the scopes of the
+// @Description  default key, valid for 30 days. Existing keys are left unchanged.
+// @Description  Repeated failures per email or IP are delayed and then locked out for a while
 // @Tags         Authentication
 // @Accept       json
 // @Produce      json
@@ -76,6 +129,7 @@ func (h *AuthHandler) RegisterUser(ctx *gin.Context) error {
 // @Success      200   {object}  UserResponse
 // @Failure      401   {object}  httpx.ErrorResponse
 // @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      429   {object}  httpx.ErrorResponse
 // @Failure      500   {object}  httpx.ErrorResponse
 // @Router       /auth/login [post]
 func (h *AuthHandler) AuthenticateUser(ctx *gin.Context) error {
@@ -84,12 +138,18 @@ func (h *AuthHandler) AuthenticateUser(ctx *gin.Context) error {
 		return err
 	}
 
-	user, err := h.authService.VerifyCredentials(ctx.Request.Context(), req.Email, req.Password)
+	input := services.LoginInput{
+		Email:    req.Email,
+		Password: req.Password,
+		ClientIP: ctx.ClientIP(),
+	}
+
+	user, apiKey, err := h.authService.VerifyCredentials(ctx.Request.Context(), input)
 	if err != nil {
 		return err
 	}
 
-	ctx.JSON(http.StatusOK, toUserResponseWithApiKey(user))
+	ctx.JSON(http.StatusOK, toUserResponseWithApiKey(user, apiKey))
 	return nil
 }
 
@@ -110,27 +170,176 @@ func (h *AuthHandler) GetAuthenticatedUser(ctx *gin.Context) error {
 		return err
 	}
 
-	ctx.JSON(http.StatusOK, toUserResponseWithApiKey(user))
+	ctx.JSON(http.StatusOK, toUserResponse(user))
+	return nil
+}
+
+// @Summary      Get usage and quota
+// @Description  Returns item and file counts and used storage of the authenticated
+// @Description  user along with the limits in effect, zero limits are not enforced
+// @Tags         Authentication
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200   {object}  AccountUsageResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/me/usage [get]
+func (h *AuthHandler) GetAuthenticatedUserUsage(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	usage, quota, err := h.usageService.GetUsage(ctx.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, AccountUsageResponse{
+		UserUsageResponse: toUserUsageResponse(usage),
+		Limits:            toQuotaResponse(quota),
+	})
 	return nil
 }
 
 // @Summary      Refresh API key
-// @Description  Refreshes authenticated user's API key
+// @Description  Refreshes the API key used for the request, other keys stay valid.
+// @Description  The new key is shown only in this response. Requires a key with
+// @Description  admin scope or every default scope
 // @Tags         Authentication
 // @Security     ApiKeyAuth
 // @Produce      json
-// @Success      200   {object}  UserResponse
+// @Success      200   {object}  ApiKeyResponse
 // @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
 // @Failure      500   {object}  httpx.ErrorResponse
 // @Router       /auth/refresh [post]
 func (h *AuthHandler) RotateApiKey(ctx *gin.Context) error {
+	keyID := ctx.MustGet("apiKeyID").(string)
+
+	apiKey, err := h.authService.RotateApiKey(ctx.Request.Context(), keyID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toApiKeyResponseWithKey(apiKey))
+	return nil
+}
+
+// @Summary      Change password
+// @Description  Changes password of the authenticated user. Login keys other than
+// @Description  the one used are revoked, the rest stay valid.
+// @Description  Requires a key with admin scope or every default scope
+// @Tags         Authentication
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Param        body body changePasswordRequest true "Current and new passwords"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/password [post]
+func (h *AuthHandler) ChangePassword(ctx *gin.Context) error {
 	userID := ctx.MustGet("userID").(string)
 
-	user, err := h.authService.RotateApiKey(ctx, userID)
+	var req changePasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	input := services.ChangePasswordInput{
+		UserID:          userID,
+		ApiKeyID:        ctx.MustGet("apiKeyID").(string),
+		CurrentPassword: req.CurrentPassword,
+		NewPassword:     req.NewPassword,
+	}
+
+	if err := h.accountService.ChangePassword(ctx.Request.Context(), input); err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      Request password reset
+// @Description  Mails a password reset token if the account exists.
+// @Description  The response is the same either way. Requests are limited per
+// @Description  client IP, mails past the limit of an email are dropped
+// @Tags         Authentication
+// @Accept       json
+// @Param        body body forgotPasswordRequest true "Account email"
+// @Success      202
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      429   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/password/forgot [post]
+func (h *AuthHandler) ForgotPassword(ctx *gin.Context) error {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	if err := h.accountService.RequestPasswordReset(ctx.Request.Context(), req.Email); err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusAccepted)
+	return nil
+}
+
+// @Summary      Reset password
+// @Description  Sets a new password using a mailed reset token, the token works once.
+// @Description  All login keys of the account are revoked.
+// @Description  Invalid tokens count as failed attempts of the client IP
+// @Tags         Authentication
+// @Accept       json
+// @Param        body body resetPasswordRequest true "Reset token and new password"
+// @Success      204
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      429   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(ctx *gin.Context) error {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	if err := h.accountService.ResetPassword(ctx.Request.Context(), req.Token, req.NewPassword, ctx.ClientIP()); err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      Delete account
+// @Description  Disables the account at once and enqueues removal of all its data.
+// @Description  Requires the current password and a key with admin scope or every
+// @Description  default scope
+// @Tags         Authentication
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body body deleteAccountRequest true "Current password"
+// @Success      202   {object}  AccountDeletionResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /auth/me [delete]
+func (h *AuthHandler) DeleteAuthenticatedUser(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var req deleteAccountRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	taskID, err := h.accountService.RequestDeletion(ctx.Request.Context(), userID, req.Password)
 	if err != nil {
 		return err
 	}
 
-	ctx.JSON(http.StatusOK, toUserResponseWithApiKey(user))
+	ctx.JSON(http.StatusAccepted, AccountDeletionResponse{TaskID: taskID})
 	return nil
 }
@@ -6,19 +6,25 @@ import (
 )
 
 type AwsUrlResponse struct {
-	Url       string    `json:"url"`
-	Filename  string    `json:"filename"`
-	MimeType  string    `json:"mime_type"`
-	Size      int       `json:"size"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Url      string `json:"url"`
+	Filename string `json:"filename"`
+	MimeType string `json:"mime_type"`
+	Size     int    `json:"size"`
+	// Missing for links that don't expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 func toAwsUrlResponse(p *domain.PresignedURL) AwsUrlResponse {
-	return AwsUrlResponse{
-		Url:       p.URL,
-		Filename:  p.Filename,
-		MimeType:  p.MimeType,
-		Size:      int(p.Size),
-		ExpiresAt: p.ExpiresAt,
+	response := AwsUrlResponse{
+		Url:      p.URL,
+		Filename: p.Filename,
+		MimeType: p.MimeType,
+		Size:     int(p.Size),
 	}
+
+	if !p.ExpiresAt.IsZero() {
+		response.ExpiresAt = &p.ExpiresAt
+	}
+
+	return response
 }
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"qvarkk/kvault/internal/services"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BlobService interface {
+	OpenSigned(ctx context.Context, key string, query url.Values) (*services.SignedObject, error)
+}
+
+type BlobHandler struct {
+	blobService BlobService
+}
+
+func NewBlobHandler(blobService BlobService) *BlobHandler {
+	return &BlobHandler{blobService: blobService}
+}
+
+// @Summary      Download a stored file
+// @Description  Serves a file of the local blob store by a signed link
+// @Description  returned from file download, only when the local driver is used
+// @Tags         Files
+// @Produce      octet-stream
+// @Param        key       path   string true "Object key"
+// @Param        filename  query  string true "Download filename"
+// @Param        expires   query  int    true "Link expiry, unix seconds"
+// @Param        signature query  string true "Link signature"
+// @Success      200
+// @Failure      403   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /blobs/{key} [get]
+func (h *BlobHandler) Download(ctx *gin.Context) error {
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+
+	object, err := h.blobService.OpenSigned(ctx.Request.Context(), key, ctx.Request.URL.Query())
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	contentDisposition := fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(object.Filename))
+	ctx.Header("Content-Disposition", contentDisposition)
+	ctx.Header("ETag", object.Info.ETag)
+	if object.Info.ContentType != "" {
+		ctx.Header("Content-Type", object.Info.ContentType)
+	}
+
+	if body, ok := object.Body.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.Writer, ctx.Request, object.Filename, object.Info.LastModified, body)
+		return nil
+	}
+
+	ctx.DataFromReader(http.StatusOK, object.Info.Size, object.Info.ContentType, object.Body, nil)
+	return nil
+}
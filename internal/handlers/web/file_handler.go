@@ -2,11 +2,16 @@ package web
 
 import (
 	"context"
+	"fmt"
+	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/services"
 	"qvarkk/kvault/internal/tasks"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hibiken/asynq"
@@ -18,18 +23,29 @@ type FileService interface {
 	GetFilePresignedUrl(ctx context.Context, fileID, userID string) (*domain.PresignedURL, error)
 	DeleteByID(ctx context.Context, fileID, userID string) error
 	RestoreByID(ctx context.Context, fileID, userID string) error
-	ValidatePdfFile(context.Context, *multipart.FileHeader) error
-	UploadPdfFileToS3(context.Context, *multipart.FileHeader) (string, error)
-	EnqueuePdfProcessTask(context.Context, tasks.PdfProcessPayload) (*asynq.TaskInfo, error)
+	ValidateUploadFile(context.Context, *multipart.FileHeader) (string, error)
+	UploadFile(ctx context.Context, userID string, fileHeader *multipart.FileHeader, mimeType string) (*services.StoredUpload, error)
+	OpenContent(ctx context.Context, fileID, userID string) (*services.FileContent, error)
+	OpenPreview(ctx context.Context, fileID, userID string, page int) (*services.FileContent, error)
+	ListTagCandidates(ctx context.Context, fileID, userID string) ([]domain.TagCandidate, error)
+	EnqueuePdfProcessTask(context.Context, tasks.PdfProcessPayload, ...asynq.Option) (*asynq.TaskInfo, error)
+	Reprocess(ctx context.Context, fileID, userID string) error
+	ReprocessMany(ctx context.Context, params domain.ReprocessFileFilter, actorID string) (int, error)
+}
+
+type UploadQuotaService interface {
+	CheckFileUpload(ctx context.Context, userID string, size int64) error
 }
 
 type FileHandler struct {
-	fileService FileService
+	fileService  FileService
+	quotaService UploadQuotaService
 }
 
-func NewFileHandler(fileService FileService) *FileHandler {
+func NewFileHandler(fileService FileService, quotaService UploadQuotaService) *FileHandler {
 	return &FileHandler{
-		fileService: fileService,
+		fileService:  fileService,
+		quotaService: quotaService,
 	}
 }
 
@@ -39,25 +55,58 @@ type uploadFileForm struct {
 
 type listFileRequest struct {
 	Query    string `form:"q"`
-	MimeType string `form:"mime_type" binding:"omitempty,oneof=application/pdf"`
+	MimeType string `form:"mime_type" binding:"omitempty,oneof=application/pdf image/png image/jpeg image/tiff"`
+	Author   string `form:"author"`
+	PagesGt  *int   `form:"pages_gt" binding:"omitempty,min=0"`
+	PagesLt  *int   `form:"pages_lt" binding:"omitempty,min=1"`
 	PaginationParams
 	FileSortingParams
 }
 
+// Files waiting for or under processing are never matched
+type reprocessFilesRequest struct {
+	Status      string     `json:"status" binding:"omitempty,oneof=ready error" example:"error"`
+	MimeType    string     `json:"mime_type" binding:"omitempty,oneof=application/pdf image/png image/jpeg image/tiff"`
+	CreatedFrom *time.Time `json:"created_from" example:"2024-01-01T00:00:00Z"`
+	CreatedTo   *time.Time `json:"created_to" example:"2024-02-01T00:00:00Z"`
+}
+
+func (r reprocessFilesRequest) toFilter(userID string) domain.ReprocessFileFilter {
+	return domain.ReprocessFileFilter{
+		UserID:      userID,
+		Status:      domain.FileStatus(r.Status),
+		MimeType:    r.MimeType,
+		CreatedFrom: r.CreatedFrom,
+		CreatedTo:   r.CreatedTo,
+	}
+}
+
+type fileContentQuery struct {
+	Disposition string `form:"disposition" binding:"omitempty,oneof=inline attachment"`
+}
+
 type fileIDUri struct {
 	ID string `uri:"id" binding:"required,uuid"`
 }
 
-// @Summary      Upload a PDF file to your vault
-// @Description  Validates and uploads given file to S3 container,
-// @Description  enqueues redis task to process the file
+type filePageUri struct {
+	ID   string `uri:"id" binding:"required,uuid"`
+	Page int    `uri:"page" binding:"required,min=1"`
+}
+
+// @Summary      Upload a PDF or an image to your vault
+// @Description  Validates and uploads given file to blob storage,
+// @Description  enqueues redis task to process the file. PNG, JPEG and
+// @Description  TIFF images get their text by OCR.
+// @Description  Files over the max file size or storage quota are rejected
 // @Tags         Files
 // @Security     ApiKeyAuth
 // @Accept       mpfd
 // @Produce      json
-// @Param        file formData file true "PDF file"
+// @Param        file formData file true "PDF or image file"
 // @Success      201   {object}  FileResponse
 // @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      413   {object}  httpx.ErrorResponse "Quota Exceeded"
 // @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
 // @Failure      500   {object}  httpx.ErrorResponse
 // @Router       /files/upload [post]
@@ -69,12 +118,17 @@ func (h *FileHandler) UploadFile(ctx *gin.Context) error {
 		return err
 	}
 
-	err := h.fileService.ValidatePdfFile(ctx, form.File)
+	mimeType, err := h.fileService.ValidateUploadFile(ctx, form.File)
+	if err != nil {
+		return err
+	}
+
+	err = h.quotaService.CheckFileUpload(ctx.Request.Context(), userID, form.File.Size)
 	if err != nil {
 		return err
 	}
 
-	s3Key, err := h.fileService.UploadPdfFileToS3(ctx, form.File)
+	upload, err := h.fileService.UploadFile(ctx, userID, form.File, mimeType)
 	if err != nil {
 		return err
 	}
@@ -82,9 +136,11 @@ func (h *FileHandler) UploadFile(ctx *gin.Context) error {
 	fileInput := services.CreateFileInput{
 		UserID:       userID,
 		OriginalName: form.File.Filename,
-		S3Key:        s3Key,
+		S3Key:        upload.Key,
+		KeyID:        upload.KeyID,
+		WrappedKey:   upload.WrappedKey,
 		Size:         form.File.Size,
-		MimeType:     form.File.Header.Get("Content-Type"),
+		MimeType:     mimeType,
 		Status:       string(domain.FileStatusUploading),
 	}
 
@@ -98,7 +154,7 @@ func (h *FileHandler) UploadFile(ctx *gin.Context) error {
 		FileID: file.ID,
 	}
 
-	_, err = h.fileService.EnqueuePdfProcessTask(ctx, payload)
+	_, err = h.fileService.EnqueuePdfProcessTask(ctx.Request.Context(), payload)
 	if err != nil {
 		return err
 	}
@@ -108,7 +164,9 @@ func (h *FileHandler) UploadFile(ctx *gin.Context) error {
 }
 
 // @Summary      Get all files
-// @Description  Returns a list of files owned by the User
+// @Description  Returns a list of files owned by the User. Search covers
+// @Description  declared title, subject, author and keywords too, author
+// @Description  and page filters skip files without metadata
 // @Tags         Files
 // @Security     ApiKeyAuth
 // @Accept       json
@@ -130,6 +188,9 @@ func (h *FileHandler) List(ctx *gin.Context) error {
 	params := domain.ListFileFilter{
 		UserID:   userID,
 		MimeType: req.MimeType,
+		Author:   req.Author,
+		PagesGt:  req.PagesGt,
+		PagesLt:  req.PagesLt,
 		QueryFilter: domain.QueryFilter{
 			Query: req.Query,
 		},
@@ -158,7 +219,8 @@ func (h *FileHandler) List(ctx *gin.Context) error {
 }
 
 // @Summary      Get a file from user's vault
-// @Description  Gets a URL to download the file with given ID
+// @Description  Gets a URL to download the file with given ID. Encrypted files
+// @Description  get a path of the content endpoint, which needs the API key
 // @Tags         Files
 // @Security     ApiKeyAuth
 // @Accept       json
@@ -183,10 +245,188 @@ func (h *FileHandler) Download(ctx *gin.Context) error {
 		return err
 	}
 
+	if url.URL == "" {
+		url.URL = ctx.Request.URL.Path + "/content"
+	}
+
 	ctx.JSON(http.StatusOK, toAwsUrlResponse(url))
 	return nil
 }
 
+// @Summary      Get file content
+// @Description  Streams content of the file with given ID through the API,
+// @Description  encrypted files are decrypted on the way. Supports Range and
+// @Description  If-None-Match, disposition=inline lets browsers show the file
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Produce      octet-stream
+// @Param        id path string true "File ID"
+// @Param        params query fileContentQuery false "Query parameters"
+// @Param        Range header string false "Byte range, e.g. bytes=0-1023"
+// @Param        If-None-Match header string false "ETag of a cached copy"
+// @Success      200
+// @Success      206
+// @Success      304
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      416
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/{id}/content [get]
+func (h *FileHandler) GetContent(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri fileIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var query fileContentQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		return err
+	}
+
+	content, err := h.fileService.OpenContent(ctx.Request.Context(), uri.ID, userID)
+	if err != nil {
+		return err
+	}
+	defer content.Body.Close()
+
+	file := content.File
+	disposition := query.Disposition
+	if disposition == "" {
+		disposition = "attachment"
+	}
+
+	ctx.Header("Content-Type", fileContentType(file))
+	ctx.Header("Content-Disposition", fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(file.OriginalName)))
+	ctx.Header("Cache-Control", "private, no-cache")
+	if content.ETag != "" {
+		ctx.Header("ETag", content.ETag)
+	}
+
+	// Handles ranges and conditional headers, content is seeked only as needed
+	http.ServeContent(ctx.Writer, ctx.Request, file.OriginalName, content.LastModified, content.Body)
+	return nil
+}
+
+// @Summary      Get file thumbnail
+// @Description  Returns a PNG of the first page, made by the worker once
+// @Description  the file is processed and a renderer is configured
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Produce      png
+// @Param        id path string true "File ID"
+// @Param        If-None-Match header string false "ETag of a cached copy"
+// @Success      200
+// @Success      304
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/{id}/thumbnail [get]
+func (h *FileHandler) GetThumbnail(ctx *gin.Context) error {
+	var uri fileIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	return h.servePreview(ctx, uri.ID, domain.FileThumbnailPage)
+}
+
+// @Summary      Get page preview
+// @Description  Returns a PNG of the page, pages are counted from 1. Only
+// @Description  the first pages get previews, as configured for the worker
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Produce      png
+// @Param        id   path string true "File ID"
+// @Param        page path int    true "Page number"
+// @Param        If-None-Match header string false "ETag of a cached copy"
+// @Success      200
+// @Success      304
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/{id}/pages/{page}/preview [get]
+func (h *FileHandler) GetPagePreview(ctx *gin.Context) error {
+	var uri filePageUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	return h.servePreview(ctx, uri.ID, uri.Page)
+}
+
+func (h *FileHandler) servePreview(ctx *gin.Context, fileID string, page int) error {
+	userID := ctx.MustGet("userID").(string)
+
+	content, err := h.fileService.OpenPreview(ctx.Request.Context(), fileID, userID, page)
+	if err != nil {
+		return err
+	}
+	defer content.Body.Close()
+
+	ctx.Header("Content-Type", "image/png")
+	ctx.Header("Cache-Control", "private, no-cache")
+	if content.ETag != "" {
+		ctx.Header("ETag", content.ETag)
+	}
+
+	http.ServeContent(ctx.Writer, ctx.Request, "", content.LastModified, content.Body)
+	return nil
+}
+
+// @Summary      Get tag candidates of a file
+// @Description  Returns keywords the document declares that aren't bound
+// @Description  to it as tags yet, with user's matching tags if there are
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "File ID"
+// @Success      200   {array}   TagCandidateResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/{id}/tag-candidates [get]
+func (h *FileHandler) ListTagCandidates(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri fileIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	candidates, err := h.fileService.ListTagCandidates(ctx.Request.Context(), uri.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	response := make([]TagCandidateResponse, len(candidates))
+	for i, candidate := range candidates {
+		response[i] = toTagCandidateResponse(&candidate)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+	return nil
+}
+
+// Stored mime type comes from the upload request, the file name is the fallback
+func fileContentType(file *domain.File) string {
+	mediaType, _, err := mime.ParseMediaType(file.MimeType)
+	if err == nil && mediaType != "application/octet-stream" {
+		return file.MimeType
+	}
+
+	if byExt := mime.TypeByExtension(filepath.Ext(file.OriginalName)); byExt != "" {
+		return byExt
+	}
+
+	return "application/octet-stream"
+}
+
 // @Summary      Soft delete a file
 // @Description  Marks a file with given ID as deleted if it's owned by the User
 // @Tags         Files
@@ -221,6 +461,67 @@ func (h *FileHandler) Restore(ctx *gin.Context) error {
 	return h.withOwnedFileAction(ctx, h.fileService.RestoreByID)
 }
 
+// @Summary      Reprocess a file
+// @Description  Drops extracted text, metadata and previews of the file and
+// @Description  queues it for processing again. Files still waiting for or
+// @Description  under processing can't be reprocessed
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "File ID"
+// @Success      202
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/{id}/reprocess [post]
+func (h *FileHandler) Reprocess(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri fileIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	if err := h.fileService.Reprocess(ctx.Request.Context(), uri.ID, userID); err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusAccepted)
+	return nil
+}
+
+// @Summary      Reprocess files in bulk
+// @Description  Queues all of the User's files matching the filters for
+// @Description  processing again, e.g. status=error to retry failed ones.
+// @Description  Date range is of the upload time, the end is exclusive
+// @Tags         Files
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body reprocessFilesRequest true "Filters"
+// @Success      202   {object}  ReprocessResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /files/reprocess [post]
+func (h *FileHandler) ReprocessMany(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var req reprocessFilesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return err
+	}
+
+	queued, err := h.fileService.ReprocessMany(ctx.Request.Context(), req.toFilter(userID), userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusAccepted, ReprocessResponse{Queued: queued})
+	return nil
+}
+
 func (h *FileHandler) withOwnedFileAction(
 	ctx *gin.Context,
 	fn func(context.Context, string, string) error,
@@ -1,28 +1,131 @@
 package web
 
 import (
+	"fmt"
 	"qvarkk/kvault/internal/domain"
 	"time"
 )
 
 type FileResponse struct {
-	ID           string `json:"id"`
-	S3Key        string `json:"s3_key"`
-	OriginalName string `json:"original_name"`
-	Size         int64  `json:"size"`
-	MimeType     string `json:"mime_type"`
-	Status       string `json:"status"`
-	CreatedAt    string `json:"created_at"`
+	ID            string                `json:"id"`
+	S3Key         string                `json:"s3_key"`
+	OriginalName  string                `json:"original_name"`
+	Size          int64                 `json:"size"`
+	MimeType      string                `json:"mime_type"`
+	Status        string                `json:"status"`
+	ErrorMessage  *string               `json:"error_message"`
+	CreatedAt     string                `json:"created_at"`
+	ThumbnailURL  *string               `json:"thumbnail_url"`
+	Metadata      *FileMetadataResponse `json:"metadata"`
+	TextSource    *string               `json:"text_source"`
+	OcrConfidence *float64              `json:"ocr_confidence"`
+	Tags          []TagRef              `json:"tags"`
+}
+
+type FileMetadataResponse struct {
+	Title      string   `json:"title,omitempty"`
+	Author     string   `json:"author,omitempty"`
+	Subject    string   `json:"subject,omitempty"`
+	Keywords   []string `json:"keywords"`
+	Producer   string   `json:"producer,omitempty"`
+	PageCount  int      `json:"page_count"`
+	CreatedAt  *string  `json:"created_at"`
+	ModifiedAt *string  `json:"modified_at"`
+}
+
+type ReprocessResponse struct {
+	Queued int `json:"queued"`
+}
+
+type TagCandidateResponse struct {
+	Word string  `json:"word"`
+	Tag  *TagRef `json:"tag"`
 }
 
 func toFileResponse(file *domain.File) FileResponse {
+	tags := make([]TagRef, len(file.Tags))
+	for i, tag := range file.Tags {
+		tags[i] = toTagRef(&tag)
+	}
+
+	// relative to the API host, the endpoint needs the API key
+	var thumbnailURL *string
+	if file.HasThumbnail {
+		url := fmt.Sprintf("/api/v1/files/%s/thumbnail", file.ID)
+		thumbnailURL = &url
+	}
+
+	var textSource *string
+	if file.TextSource.Valid {
+		textSource = &file.TextSource.String
+	}
+
+	var ocrConfidence *float64
+	if file.OcrConfidence.Valid {
+		ocrConfidence = &file.OcrConfidence.Float64
+	}
+
+	var errorMessage *string
+	if file.ErrorMessage.Valid {
+		errorMessage = &file.ErrorMessage.String
+	}
+
 	return FileResponse{
-		ID:           file.ID,
-		S3Key:        file.S3Key,
-		OriginalName: file.OriginalName,
-		Size:         file.Size,
-		MimeType:     file.MimeType,
-		Status:       string(file.Status),
-		CreatedAt:    file.CreatedAt.Format(time.RFC3339),
+		ID:            file.ID,
+		S3Key:         file.S3Key,
+		OriginalName:  file.OriginalName,
+		Size:          file.Size,
+		MimeType:      file.MimeType,
+		Status:        string(file.Status),
+		ErrorMessage:  errorMessage,
+		CreatedAt:     file.CreatedAt.Format(time.RFC3339),
+		ThumbnailURL:  thumbnailURL,
+		Metadata:      toFileMetadataResponse(file.Metadata),
+		TextSource:    textSource,
+		OcrConfidence: ocrConfidence,
+		Tags:          tags,
+	}
+}
+
+func toFileMetadataResponse(metadata *domain.FileMetadata) *FileMetadataResponse {
+	if metadata == nil {
+		return nil
+	}
+
+	formatTime := func(t *time.Time) *string {
+		if t == nil {
+			return nil
+		}
+		formatted := t.Format(time.RFC3339)
+		return &formatted
+	}
+
+	keywords := metadata.Keywords
+	if keywords == nil {
+		keywords = []string{}
+	}
+
+	return &FileMetadataResponse{
+		Title:      metadata.Title,
+		Author:     metadata.Author,
+		Subject:    metadata.Subject,
+		Keywords:   keywords,
+		Producer:   metadata.Producer,
+		PageCount:  metadata.PageCount,
+		CreatedAt:  formatTime(metadata.CreatedAt),
+		ModifiedAt: formatTime(metadata.ModifiedAt),
+	}
+}
+
+func toTagCandidateResponse(candidate *domain.TagCandidate) TagCandidateResponse {
+	var tag *TagRef
+	if candidate.Tag != nil {
+		ref := toTagRef(candidate.Tag)
+		tag = &ref
+	}
+
+	return TagCandidateResponse{
+		Word: candidate.Word,
+		Tag:  tag,
 	}
 }
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/health"
+	"qvarkk/kvault/logger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type HealthChecker interface {
+	Check(context.Context) *health.Report
+}
+
+type QueueStatsService interface {
+	ListQueueStats(context.Context) ([]domain.QueueStats, error)
+}
+
+// Probes live outside of /api/v1 and need no API key, so check errors are
+// only logged and never sent to the caller
+type HealthHandler struct {
+	checker HealthChecker
+}
+
+func NewHealthHandler(checker HealthChecker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+type HealthResponse struct {
+	Status string            `json:"status" example:"ok"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// The process is up and serving, dependencies aren't looked at
+func (h *HealthHandler) Liveness(ctx *gin.Context) error {
+	ctx.JSON(http.StatusOK, HealthResponse{Status: "ok"})
+	return nil
+}
+
+// Fails while any dependency is unreachable or the process is shutting down
+func (h *HealthHandler) Readiness(ctx *gin.Context) error {
+	report := h.checker.Check(ctx.Request.Context())
+
+	checks := make(map[string]string, len(report.Results))
+	for name, err := range report.Results {
+		if err != nil {
+			logger.FromContext(ctx.Request.Context()).Warn("Readiness check failed", zap.String("check", name), zap.Error(err))
+			checks[name] = "failed"
+			continue
+		}
+		checks[name] = "ok"
+	}
+
+	switch {
+	case report.Draining:
+		ctx.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "draining", Checks: checks})
+	case !report.Ready():
+		ctx.JSON(http.StatusServiceUnavailable, HealthResponse{Status: "unavailable", Checks: checks})
+	default:
+		ctx.JSON(http.StatusOK, HealthResponse{Status: "ok", Checks: checks})
+	}
+	return nil
+}
+
+type QueueHandler struct {
+	queueService QueueStatsService
+}
+
+func NewQueueHandler(queueService QueueStatsService) *QueueHandler {
+	return &QueueHandler{queueService: queueService}
+}
+
+// Sizes of task queues by state, served by the worker's probe listener
+func (h *QueueHandler) ListStats(ctx *gin.Context) error {
+	stats, err := h.queueService.ListQueueStats(ctx.Request.Context())
+	if err != nil {
+		return err
+	}
+
+	statsResponses := make([]QueueStatsResponse, len(stats))
+	for i, queue := range stats {
+		statsResponses[i] = toQueueStatsResponse(&queue)
+	}
+
+	ctx.JSON(http.StatusOK, statsResponses)
+	return nil
+}
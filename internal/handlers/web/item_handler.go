@@ -20,13 +20,19 @@ type ItemService interface {
 	UnbindTagByItemID(ctx context.Context, itemID, tagID, userID string) error
 }
 
+type ItemQuotaService interface {
+	CheckItemCreate(ctx context.Context, userID string) error
+}
+
 type ItemHandler struct {
-	itemService ItemService
+	itemService  ItemService
+	quotaService ItemQuotaService
 }
 
-func NewItemHandler(itemService ItemService) *ItemHandler {
+func NewItemHandler(itemService ItemService, quotaService ItemQuotaService) *ItemHandler {
 	return &ItemHandler{
-		itemService: itemService,
+		itemService:  itemService,
+		quotaService: quotaService,
 	}
 }
 
@@ -63,7 +69,8 @@ type unbindTagUri struct {
 }
 
 // @Summary      Create an item in your vault
-// @Description  Creates an item with data passed through body
+// @Description  Creates an item with data passed through body, fails once
+// @Description  the item limit of the user is reached
 // @Tags         Items
 // @Security     ApiKeyAuth
 // @Accept       json
@@ -82,6 +89,10 @@ func (h *ItemHandler) Create(ctx *gin.Context) error {
 		return err
 	}
 
+	if err := h.quotaService.CheckItemCreate(ctx.Request.Context(), userID); err != nil {
+		return err
+	}
+
 	itemInput := services.CreateItemInput{
 		UserID:  userID,
 		Type:    req.Type,
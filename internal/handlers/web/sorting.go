@@ -27,3 +27,13 @@ type TagSortingParams struct {
 	DescendingSortingParams
 	Column string `form:"sort_by,default=updated_at" binding:"oneof=name updated_at created_at"`
 }
+
+type TagStatsSortingParams struct {
+	DescendingSortingParams
+	Column string `form:"sort_by,default=item_count" binding:"oneof=item_count last_used_at name"`
+}
+
+type UserSortingParams struct {
+	DescendingSortingParams
+	Column string `form:"sort_by,default=created_at" binding:"oneof=email created_at updated_at"`
+}
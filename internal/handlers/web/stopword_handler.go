@@ -1,10 +1,14 @@
 package web
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"net/http"
 	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/httpx"
 	"qvarkk/kvault/internal/services"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,8 +19,16 @@ type StopwordService interface {
 	Enable(ctx context.Context, word, userID string) error
 	Disable(ctx context.Context, word, userID string) error
 	Delete(ctx context.Context, word, userID string) error
+	Import(ctx context.Context, userID string, words []string) (int, error)
+	Export(ctx context.Context, userID string) ([]string, error)
+	ListPacks(ctx context.Context, userID string) ([]domain.StopwordPack, error)
+	EnablePack(ctx context.Context, lang, userID string) error
+	DisablePack(ctx context.Context, lang, userID string) error
 }
 
+// imports larger than this are rejected whatever their format
+const maxStopwordsImportBytes = 1 << 20
+
 type StopwordHandler struct {
 	stopwordService StopwordService
 }
@@ -39,6 +51,18 @@ type stopwordUri struct {
 	Word string `uri:"word" binding:"required"`
 }
 
+type stopwordPackUri struct {
+	Lang string `uri:"lang" binding:"required"`
+}
+
+type stopwordListBody struct {
+	Words []string `json:"words" binding:"required" example:"lorem,ipsum"`
+}
+
+type exportStopwordsQuery struct {
+	Format string `form:"format,default=json" binding:"oneof=json text"`
+}
+
 // @Summary      Add a stopword to the user profile
 // @Description  Creates a stopword record in database
 // @Tags         Stopwords
@@ -189,3 +213,183 @@ func (h *StopwordHandler) withOwnedItemAction(
 	ctx.Status(http.StatusNoContent)
 	return nil
 }
+
+// @Summary      Import stopwords
+// @Description  Adds enabled stopwords in bulk, either as JSON or as plain text
+// @Description  with one word per line (lines starting with # are skipped)
+// @Tags         Stopwords
+// @Security     ApiKeyAuth
+// @Accept       json,plain
+// @Produce      json
+// @Param        body body stopwordListBody true "Stopwords"
+// @Success      200   {object}  StopwordImportResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      413   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /stopwords/import [post]
+func (h *StopwordHandler) Import(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxStopwordsImportBytes)
+
+	var words []string
+	if ctx.ContentType() == gin.MIMEJSON {
+		var req stopwordListBody
+		if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+			return toImportBodyError(err)
+		}
+		words = req.Words
+	} else {
+		scanner := bufio.NewScanner(ctx.Request.Body)
+		for scanner.Scan() {
+			words = append(words, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return toImportBodyError(err)
+		}
+	}
+
+	imported, err := h.stopwordService.Import(ctx.Request.Context(), userID, words)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, StopwordImportResponse{Imported: imported})
+	return nil
+}
+
+// Body size and line length are checked while reading, before the service
+// sees any word, so they are reported straight as public errors
+func toImportBodyError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &httpx.PublicError{
+			Err:     httpx.ErrPayloadTooLarge,
+			Message: "Import can't be larger than 1 MiB.",
+		}
+	case errors.Is(err, bufio.ErrTooLong):
+		return &httpx.PublicError{
+			Err:     httpx.ErrUnprocessableEntity,
+			Message: "Import lines can't be longer than 64 KiB.",
+		}
+	}
+	return err
+}
+
+// @Summary      Export stopwords
+// @Description  Returns enabled user stopwords as JSON or as plain text with one word per line
+// @Tags         Stopwords
+// @Security     ApiKeyAuth
+// @Produce      json,plain
+// @Param				 params query exportStopwordsQuery false "Query parameters"
+// @Success      200   {object}  stopwordListBody
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /stopwords/export [get]
+func (h *StopwordHandler) Export(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var query exportStopwordsQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		return err
+	}
+
+	words, err := h.stopwordService.Export(ctx.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	if query.Format == "text" {
+		var body strings.Builder
+		for _, word := range words {
+			body.WriteString(word)
+			body.WriteString("\n")
+		}
+
+		ctx.Header("Content-Disposition", `attachment; filename="stopwords.txt"`)
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(body.String()))
+		return nil
+	}
+
+	ctx.JSON(http.StatusOK, stopwordListBody{Words: words})
+	return nil
+}
+
+// @Summary      Get stopword packs
+// @Description  Returns default stopword language packs and whether they are enabled for the User
+// @Tags         Stopwords
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200   {object}  ListResponse[StopwordPackResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /stopwords/packs [get]
+func (h *StopwordHandler) ListPacks(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	packs, err := h.stopwordService.ListPacks(ctx.Request.Context(), userID)
+	if err != nil {
+		return err
+	}
+
+	packResponses := make([]StopwordPackResponse, len(packs))
+	for i, pack := range packs {
+		packResponses[i] = toStopwordPackResponse(&pack)
+	}
+
+	ctx.JSON(http.StatusOK, toListResponse(packResponses))
+	return nil
+}
+
+// @Summary      Enable a stopword pack
+// @Description  Makes default stopwords of the language pack active for the User
+// @Tags         Stopwords
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        lang path string true "Pack language"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /stopwords/packs/{lang}/enable [post]
+func (h *StopwordHandler) EnablePack(ctx *gin.Context) error {
+	return h.withPackAction(ctx, h.stopwordService.EnablePack)
+}
+
+// @Summary      Disable a stopword pack
+// @Description  Stops using default stopwords of the language pack for the User
+// @Tags         Stopwords
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        lang path string true "Pack language"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /stopwords/packs/{lang}/disable [post]
+func (h *StopwordHandler) DisablePack(ctx *gin.Context) error {
+	return h.withPackAction(ctx, h.stopwordService.DisablePack)
+}
+
+func (h *StopwordHandler) withPackAction(
+	ctx *gin.Context,
+	fn func(context.Context, string, string) error,
+) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri stopwordPackUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	err := fn(ctx.Request.Context(), uri.Lang, userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
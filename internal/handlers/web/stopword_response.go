@@ -20,3 +20,23 @@ func toStopwordResponse(stopword *domain.Stopword) StopwordResponse {
 		UpdatedAt: stopword.UpdatedAt.Format(time.RFC3339),
 	}
 }
+
+type StopwordImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+type StopwordPackResponse struct {
+	Lang      string `json:"lang"`
+	Name      string `json:"name"`
+	IsEnabled bool   `json:"is_enabled"`
+	WordCount int    `json:"word_count"`
+}
+
+func toStopwordPackResponse(pack *domain.StopwordPack) StopwordPackResponse {
+	return StopwordPackResponse{
+		Lang:      pack.Lang,
+		Name:      pack.Name,
+		IsEnabled: pack.IsEnabled,
+		WordCount: pack.WordCount,
+	}
+}
@@ -14,6 +14,11 @@ type TagService interface {
 	List(context.Context, domain.ListTagFilter) ([]domain.Tag, int, error)
 	Update(context.Context, services.UpdateTagInput) (*domain.Tag, error)
 	DeleteByID(ctx context.Context, tagID, userID string, block bool) error
+	Merge(context.Context, services.MergeTagsInput) (*domain.Tag, error)
+	CreateAlias(context.Context, services.CreateTagAliasInput) (*domain.TagAlias, error)
+	ListAliases(ctx context.Context, tagID, userID string) ([]domain.TagAlias, error)
+	DeleteAlias(ctx context.Context, tagID, alias, userID string) error
+	Stats(context.Context, domain.TagStatsFilter) ([]domain.TagStats, int, error)
 }
 
 type TagHandler struct {
@@ -34,6 +39,14 @@ type listTagRequest struct {
 	TagSortingParams
 }
 
+type tagStatsRequest struct {
+	Query         string `form:"q"`
+	MinCount      int    `form:"min_count" binding:"min=0"`
+	CoOccurrences int    `form:"co_occurrences,default=5" binding:"min=0,max=20"`
+	PaginationParams
+	TagStatsSortingParams
+}
+
 type updateTagRequest struct {
 	Name string `json:"name" binding:"required"`
 }
@@ -46,6 +59,19 @@ type tagIdUri struct {
 	ID string `uri:"id" binding:"required,uuid"`
 }
 
+type mergeTagsRequest struct {
+	SourceIDs []string `json:"source_ids" binding:"required,min=1,unique,dive,uuid"`
+}
+
+type createTagAliasRequest struct {
+	Alias string `json:"alias" binding:"required,max=64" example:"sensor"`
+}
+
+type tagAliasUri struct {
+	ID    string `uri:"id" binding:"required,uuid"`
+	Alias string `uri:"alias" binding:"required"`
+}
+
 // @Summary      Create a tag in your vault
 // @Description  Creates a tag with data passed through body
 // @Tags         Tags
@@ -129,6 +155,58 @@ func (h *TagHandler) List(ctx *gin.Context) error {
 	return nil
 }
 
+// @Summary      Get tag usage statistics
+// @Description  Returns a paginated list of tags with the number of bound items
+// @Description  by binding source, last usage time and most co-occurring tags
+// @Tags         Tags
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param				 params query tagStatsRequest false "Query parameters"
+// @Success      200   {object}  PaginatedResponse[TagStatsResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tags/stats [get]
+func (h *TagHandler) Stats(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var req tagStatsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	params := domain.TagStatsFilter{
+		UserID:          userID,
+		MinCount:        req.MinCount,
+		CoOccurrenceMax: req.CoOccurrences,
+		QueryFilter: domain.QueryFilter{
+			Query: req.Query,
+		},
+		PaginationFilter: domain.PaginationFilter{
+			Page:     req.Page,
+			PageSize: req.PageSize,
+		},
+		SortFilter: domain.SortFilter{
+			Direction: req.Direction,
+			Column:    req.Column,
+		},
+	}
+
+	stats, count, err := h.tagService.Stats(ctx.Request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	statsResponses := make([]TagStatsResponse, len(stats))
+	for i, stat := range stats {
+		statsResponses[i] = toTagStatsResponse(&stat)
+	}
+
+	ctx.JSON(http.StatusOK, toPaginatedResponse(statsResponses, count, req.Page, req.PageSize))
+	return nil
+}
+
 // @Summary      Update a tag name in your vault
 // @Description  Updates tag name by ID
 // @Tags         Tags
@@ -206,3 +284,154 @@ func (h *TagHandler) Delete(ctx *gin.Context) error {
 	ctx.Status(http.StatusNoContent)
 	return nil
 }
+
+// @Summary      Merge tags into a tag
+// @Description  Moves all item bindings from source tags to the tag with given ID,
+// @Description  deletes source tags and keeps their names as aliases of the target
+// @Tags         Tags
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string            true  "Target tag ID"
+// @Param        body body      mergeTagsRequest  true  "Tags to merge"
+// @Success      200  {object}  TagResponse
+// @Failure      401  {object}  httpx.ErrorResponse
+// @Failure      404  {object}  httpx.ErrorResponse
+// @Failure      422  {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500  {object}  httpx.ErrorResponse
+// @Router       /tags/{id}/merge [post]
+func (h *TagHandler) Merge(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagIdUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var req mergeTagsRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	mergeInput := services.MergeTagsInput{
+		UserID:    userID,
+		TargetID:  uri.ID,
+		SourceIDs: req.SourceIDs,
+	}
+
+	tag, err := h.tagService.Merge(ctx.Request.Context(), mergeInput)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toTagResponse(tag))
+	return nil
+}
+
+// @Summary      Get tag aliases
+// @Description  Returns aliases which auto-tagging resolves to the tag with given ID
+// @Tags         Tags
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Tag ID"
+// @Success      200   {object}  ListResponse[TagAliasResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tags/{id}/aliases [get]
+func (h *TagHandler) ListAliases(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagIdUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	aliases, err := h.tagService.ListAliases(ctx.Request.Context(), uri.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	aliasResponses := make([]TagAliasResponse, len(aliases))
+	for i, alias := range aliases {
+		aliasResponses[i] = toTagAliasResponse(&alias)
+	}
+
+	ctx.JSON(http.StatusOK, toListResponse(aliasResponses))
+	return nil
+}
+
+// @Summary      Add a tag alias
+// @Description  Creates an alias which auto-tagging resolves to the tag with given ID
+// @Tags         Tags
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id   path  string                 true  "Tag ID"
+// @Param        body body  createTagAliasRequest  true  "Alias data"
+// @Success      201   {object}  TagAliasResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tags/{id}/aliases [post]
+func (h *TagHandler) CreateAlias(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagIdUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var req createTagAliasRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	aliasInput := services.CreateTagAliasInput{
+		UserID: userID,
+		TagID:  uri.ID,
+		Alias:  req.Alias,
+	}
+
+	alias, err := h.tagService.CreateAlias(ctx.Request.Context(), aliasInput)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusCreated, toTagAliasResponse(alias))
+	return nil
+}
+
+// @Summary      Delete a tag alias
+// @Description  Deletes an alias of the tag with given ID
+// @Tags         Tags
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path  string  true  "Tag ID"
+// @Param        alias path  string  true  "Alias"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tags/{id}/aliases/{alias} [delete]
+func (h *TagHandler) DeleteAlias(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagAliasUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	err := h.tagService.DeleteAlias(ctx.Request.Context(), uri.ID, uri.Alias, userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
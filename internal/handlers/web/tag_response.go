@@ -34,3 +34,62 @@ func toTagRef(tag *domain.Tag) TagRef {
 		Name: tag.Name,
 	}
 }
+
+type TagAliasResponse struct {
+	Alias     string `json:"alias"`
+	TagID     string `json:"tag_id"`
+	CreatedAt string `json:"created_at"`
+}
+
+func toTagAliasResponse(alias *domain.TagAlias) TagAliasResponse {
+	return TagAliasResponse{
+		Alias:     alias.Alias,
+		TagID:     alias.TagID,
+		CreatedAt: alias.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type TagStatsResponse struct {
+	ID            string                    `json:"id"`
+	Name          string                    `json:"name"`
+	ItemCount     int                       `json:"item_count"`
+	AutoCount     int                       `json:"auto_count"`
+	RuleCount     int                       `json:"rule_count"`
+	ManualCount   int                       `json:"manual_count"`
+	LastUsedAt    *string                   `json:"last_used_at"`
+	CoOccurrences []TagCoOccurrenceResponse `json:"co_occurrences"`
+}
+
+type TagCoOccurrenceResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func toTagStatsResponse(stats *domain.TagStats) TagStatsResponse {
+	var lastUsedAt *string
+	if stats.LastUsedAt.Valid {
+		formatted := stats.LastUsedAt.Time.Format(time.RFC3339)
+		lastUsedAt = &formatted
+	}
+
+	coOccurrences := make([]TagCoOccurrenceResponse, len(stats.CoOccurrences))
+	for i, co := range stats.CoOccurrences {
+		coOccurrences[i] = TagCoOccurrenceResponse{
+			ID:    co.TagID,
+			Name:  co.Name,
+			Count: co.Count,
+		}
+	}
+
+	return TagStatsResponse{
+		ID:            stats.ID,
+		Name:          stats.Name,
+		ItemCount:     stats.ItemCount,
+		AutoCount:     stats.AutoCount,
+		RuleCount:     stats.RuleCount,
+		ManualCount:   stats.ManualCount,
+		LastUsedAt:    lastUsedAt,
+		CoOccurrences: coOccurrences,
+	}
+}
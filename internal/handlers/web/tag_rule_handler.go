@@ -0,0 +1,309 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TagRuleService interface {
+	CreateNew(context.Context, services.CreateTagRuleInput) (*domain.TagRule, error)
+	List(context.Context, domain.ListTagRuleFilter) ([]domain.TagRule, error)
+	GetByID(ctx context.Context, ruleID, userID string) (*domain.TagRule, error)
+	Update(context.Context, services.UpdateTagRuleInput) (*domain.TagRule, error)
+	DeleteByID(ctx context.Context, ruleID, userID string) error
+	DryRun(context.Context, domain.TagRuleDryRunFilter) ([]domain.Item, int, error)
+}
+
+type TagRuleHandler struct {
+	tagRuleService TagRuleService
+}
+
+func NewTagRuleHandler(tagRuleService TagRuleService) *TagRuleHandler {
+	return &TagRuleHandler{tagRuleService: tagRuleService}
+}
+
+type createTagRuleRequest struct {
+	Name      string   `json:"name" binding:"required,max=100" example:"RFC documents"`
+	TagID     string   `json:"tag_id" binding:"required,uuid"`
+	Kind      string   `json:"kind" binding:"required,oneof=regex keywords" example:"regex"`
+	Field     string   `json:"field" binding:"omitempty,oneof=title content any" example:"title"`
+	Pattern   string   `json:"pattern" binding:"required_if=Kind regex" example:"RFC\\s?\\d+"`
+	Keywords  []string `json:"keywords" binding:"required_if=Kind keywords,omitempty,dive,max=100"`
+	IsEnabled *bool    `json:"is_enabled"`
+}
+
+type listTagRuleRequest struct {
+	TagID string `form:"tag_id" binding:"omitempty,uuid"`
+}
+
+type updateTagRuleRequest struct {
+	Name      *string   `json:"name" binding:"omitempty,max=100"`
+	TagID     *string   `json:"tag_id" binding:"omitempty,uuid"`
+	Kind      *string   `json:"kind" binding:"omitempty,oneof=regex keywords"`
+	Field     *string   `json:"field" binding:"omitempty,oneof=title content any"`
+	Pattern   *string   `json:"pattern"`
+	Keywords  *[]string `json:"keywords" binding:"omitempty,dive,max=100"`
+	IsEnabled *bool     `json:"is_enabled"`
+}
+
+type dryRunTagRuleRequest struct {
+	Kind     string   `json:"kind" binding:"required,oneof=regex keywords" example:"keywords"`
+	Field    string   `json:"field" binding:"omitempty,oneof=title content any" example:"any"`
+	Pattern  string   `json:"pattern" binding:"required_if=Kind regex"`
+	Keywords []string `json:"keywords" binding:"required_if=Kind keywords,omitempty,dive,max=100" example:"lidar,radar"`
+}
+
+type tagRuleIDUri struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// @Summary      Create a tag rule
+// @Description  Creates a rule which binds a tag to items and files whose title
+// @Description  or content matches a regex pattern or contains any of the keywords
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body body createTagRuleRequest true "Rule definition"
+// @Success      201   {object}  TagRuleResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tag-rules [post]
+func (h *TagRuleHandler) Create(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var req createTagRuleRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	isEnabled := true
+	if req.IsEnabled != nil {
+		isEnabled = *req.IsEnabled
+	}
+
+	ruleInput := services.CreateTagRuleInput{
+		UserID:    userID,
+		TagID:     req.TagID,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Field:     req.Field,
+		Pattern:   req.Pattern,
+		Keywords:  req.Keywords,
+		IsEnabled: isEnabled,
+	}
+
+	rule, err := h.tagRuleService.CreateNew(ctx.Request.Context(), ruleInput)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusCreated, toTagRuleResponse(rule))
+	return nil
+}
+
+// @Summary      Get all tag rules
+// @Description  Returns a list of tag rules owned by the User
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param				 params query listTagRuleRequest false "Query parameters"
+// @Success      200   {object}  ListResponse[TagRuleResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tag-rules [get]
+func (h *TagRuleHandler) List(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var req listTagRuleRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		return err
+	}
+
+	params := domain.ListTagRuleFilter{
+		UserID: userID,
+		TagID:  req.TagID,
+	}
+
+	rules, err := h.tagRuleService.List(ctx.Request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	ruleResponses := make([]TagRuleResponse, len(rules))
+	for i, rule := range rules {
+		ruleResponses[i] = toTagRuleResponse(&rule)
+	}
+
+	ctx.JSON(http.StatusOK, toListResponse(ruleResponses))
+	return nil
+}
+
+// @Summary      Get a tag rule
+// @Description  Gets a tag rule by ID
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Rule ID"
+// @Success      200   {object}  TagRuleResponse
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tag-rules/{id} [get]
+func (h *TagRuleHandler) Get(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagRuleIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	rule, err := h.tagRuleService.GetByID(ctx.Request.Context(), uri.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toTagRuleResponse(rule))
+	return nil
+}
+
+// @Summary      Update a tag rule
+// @Description  Partially updates a tag rule by ID
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string                true  "Rule ID"
+// @Param        body body      updateTagRuleRequest  true  "Fields to update"
+// @Success      200  {object}  TagRuleResponse
+// @Failure      401  {object}  httpx.ErrorResponse
+// @Failure      404  {object}  httpx.ErrorResponse
+// @Failure      422  {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500  {object}  httpx.ErrorResponse
+// @Router       /tag-rules/{id} [patch]
+func (h *TagRuleHandler) Update(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagRuleIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	var req updateTagRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		return err
+	}
+
+	ruleInput := services.UpdateTagRuleInput{
+		RuleID:    uri.ID,
+		UserID:    userID,
+		TagID:     req.TagID,
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Field:     req.Field,
+		Pattern:   req.Pattern,
+		Keywords:  req.Keywords,
+		IsEnabled: req.IsEnabled,
+	}
+
+	rule, err := h.tagRuleService.Update(ctx.Request.Context(), ruleInput)
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, toTagRuleResponse(rule))
+	return nil
+}
+
+// @Summary      Delete a tag rule
+// @Description  Deletes a tag rule, tags it has already bound are kept
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        id path string true "Rule ID"
+// @Success      204
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      404   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tag-rules/{id} [delete]
+func (h *TagRuleHandler) Delete(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var uri tagRuleIDUri
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		return err
+	}
+
+	err := h.tagRuleService.DeleteByID(ctx.Request.Context(), uri.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	ctx.Status(http.StatusNoContent)
+	return nil
+}
+
+// @Summary      Dry run a tag rule
+// @Description  Returns a paginated list of existing items the given rule definition
+// @Description  would bind its tag to, nothing is saved
+// @Tags         Tag rules
+// @Security     ApiKeyAuth
+// @Accept       json
+// @Produce      json
+// @Param        body   body   dryRunTagRuleRequest  true   "Rule definition"
+// @Param        params query  PaginationParams      false  "Query parameters"
+// @Success      200   {object}  PaginatedResponse[ItemResponse]
+// @Failure      401   {object}  httpx.ErrorResponse
+// @Failure      422   {object}  httpx.ErrorResponse "Validation Error"
+// @Failure      500   {object}  httpx.ErrorResponse
+// @Router       /tag-rules/dry-run [post]
+func (h *TagRuleHandler) DryRun(ctx *gin.Context) error {
+	userID := ctx.MustGet("userID").(string)
+
+	var query PaginationParams
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		return err
+	}
+
+	var req dryRunTagRuleRequest
+	if err := ctx.ShouldBindBodyWithJSON(&req); err != nil {
+		return err
+	}
+
+	params := domain.TagRuleDryRunFilter{
+		UserID:   userID,
+		Kind:     domain.TagRuleKind(req.Kind),
+		Field:    domain.TagRuleField(req.Field),
+		Pattern:  req.Pattern,
+		Keywords: req.Keywords,
+		PaginationFilter: domain.PaginationFilter{
+			Page:     query.Page,
+			PageSize: query.PageSize,
+		},
+	}
+
+	items, total, err := h.tagRuleService.DryRun(ctx.Request.Context(), params)
+	if err != nil {
+		return err
+	}
+
+	itemResponses := make([]ItemResponse, len(items))
+	for i, item := range items {
+		itemResponses[i] = toItemResponse(&item)
+	}
+
+	ctx.JSON(http.StatusOK, toPaginatedResponse(itemResponses, total, query.Page, query.PageSize))
+	return nil
+}
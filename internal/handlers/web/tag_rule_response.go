@@ -0,0 +1,34 @@
+package web
+
+import (
+	"qvarkk/kvault/internal/domain"
+	"time"
+)
+
+type TagRuleResponse struct {
+	ID        string   `json:"id"`
+	TagID     string   `json:"tag_id"`
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Field     string   `json:"field"`
+	Pattern   string   `json:"pattern,omitempty"`
+	Keywords  []string `json:"keywords,omitempty"`
+	IsEnabled bool     `json:"is_enabled"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+func toTagRuleResponse(rule *domain.TagRule) TagRuleResponse {
+	return TagRuleResponse{
+		ID:        rule.ID,
+		TagID:     rule.TagID,
+		Name:      rule.Name,
+		Kind:      string(rule.Kind),
+		Field:     string(rule.Field),
+		Pattern:   rule.Pattern.String,
+		Keywords:  rule.Keywords,
+		IsEnabled: rule.IsEnabled,
+		CreatedAt: rule.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: rule.UpdatedAt.Format(time.RFC3339),
+	}
+}
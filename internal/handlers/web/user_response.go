@@ -6,28 +6,60 @@ import (
 )
 
 type UserResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	APIKey    string `json:"api_key,omitempty"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID         string  `json:"id"`
+	Email      string  `json:"email"`
+	Role       string  `json:"role"`
+	APIKey     string  `json:"api_key,omitempty"`
+	DisabledAt *string `json:"disabled_at,omitempty"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
 }
 
 func toUserResponse(user *domain.User) UserResponse {
-	return UserResponse{
+	response := UserResponse{
 		ID:        user.ID,
 		Email:     user.Email,
+		Role:      string(user.Role),
 		CreatedAt: user.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
 	}
+
+	if user.DisabledAt.Valid {
+		disabledAt := user.DisabledAt.Time.Format(time.RFC3339)
+		response.DisabledAt = &disabledAt
+	}
+
+	return response
 }
 
-func toUserResponseWithApiKey(user *domain.User) UserResponse {
-	return UserResponse{
-		ID:        user.ID,
-		Email:     user.Email,
-		APIKey:    user.APIKey,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: user.UpdatedAt.Format(time.RFC3339),
+func toUserResponseWithApiKey(user *domain.User, apiKey *domain.ApiKey) UserResponse {
+	response := toUserResponse(user)
+	if apiKey != nil {
+		response.APIKey = apiKey.Key
+	}
+	return response
+}
+
+type QuotaResponse struct {
+	MaxStorageBytes int64 `json:"max_storage_bytes"`
+	MaxFileBytes    int64 `json:"max_file_bytes"`
+	MaxItems        int64 `json:"max_items"`
+}
+
+func toQuotaResponse(quota *domain.UserQuota) QuotaResponse {
+	return QuotaResponse{
+		MaxStorageBytes: quota.MaxStorageBytes,
+		MaxFileBytes:    quota.MaxFileBytes,
+		MaxItems:        quota.MaxItems,
 	}
 }
+
+// Zero limits are not enforced
+type AccountUsageResponse struct {
+	UserUsageResponse
+	Limits QuotaResponse `json:"limits"`
+}
+
+type AccountDeletionResponse struct {
+	TaskID string `json:"task_id"`
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/metrics"
+	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/internal/tracing"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Records how long each task took, by type and whether it failed
+func MetricsMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		start := time.Now()
+		err := next.ProcessTask(ctx, t)
+
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+
+		metrics.TaskDuration.WithLabelValues(t.Type(), result).Observe(time.Since(start).Seconds())
+		return err
+	})
+}
+
+// Continues the trace carried in the payload, tasks enqueued outside a
+// request start a new one
+func TracingMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		// a malformed payload is reported by the task handler
+		var origin tasks.Origin
+		_ = json.Unmarshal(t.Payload(), &origin)
+
+		attrs := []attribute.KeyValue{
+			attribute.String("messaging.system", "asynq"),
+			attribute.String("messaging.operation.type", "process"),
+		}
+		if id, ok := asynq.GetTaskID(ctx); ok {
+			attrs = append(attrs, attribute.String("messaging.message.id", id))
+		}
+		if queue, ok := asynq.GetQueueName(ctx); ok {
+			attrs = append(attrs, attribute.String("messaging.destination.name", queue))
+		}
+		if retry, ok := asynq.GetRetryCount(ctx); ok {
+			attrs = append(attrs, attribute.Int("messaging.asynq.retry_count", retry))
+		}
+
+		ctx, span := tracing.Tracer().Start(
+			tracing.Extract(ctx, origin.TraceContext),
+			"process "+t.Type(),
+			trace.WithSpanKind(trace.SpanKindConsumer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		err := next.ProcessTask(ctx, t)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return err
+	})
+}
+
+// Tags the task logger with the ID of the request that enqueued it, tasks
+// from elsewhere are logged with their own ID only
+func LoggingMiddleware(next asynq.Handler) asynq.Handler {
+	return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		var origin tasks.Origin
+		_ = json.Unmarshal(t.Payload(), &origin)
+
+		fields := []zap.Field{zap.String("task_type", t.Type())}
+		if id, ok := asynq.GetTaskID(ctx); ok {
+			fields = append(fields, zap.String("task_id", id))
+		}
+		if origin.RequestID != "" {
+			fields = append(fields, zap.String("request_id", origin.RequestID))
+			// audit events recorded by the task point at the same request
+			ctx = domain.WithRequestMeta(ctx, domain.RequestMeta{RequestID: origin.RequestID})
+		}
+
+		ctx = logger.WithContext(ctx, logger.Logger.Named(logger.ComponentWorker).With(fields...))
+		return next.ProcessTask(ctx, t)
+	})
+}
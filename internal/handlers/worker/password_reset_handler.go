@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/logger"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// The email isn't logged, the task is enqueued for any address submitted
+func (h *AccountTaskHandler) HandlePasswordResetMailTask(ctx context.Context, t *asynq.Task) error {
+	log := logger.FromContext(ctx)
+
+	var p tasks.PasswordResetMailPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		log.Error("Failed to parse task payload", zap.Error(err))
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	if err := h.accountService.SendPasswordReset(ctx, p.Email); err != nil {
+		log.Error("Failed to send password reset", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
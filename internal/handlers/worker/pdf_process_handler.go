@@ -3,6 +3,8 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/services"
 	"qvarkk/kvault/internal/tasks"
@@ -13,7 +15,8 @@ import (
 )
 
 type FileTaskService interface {
-	ExtractTextFromFile(context.Context, *domain.File) (string, error)
+	ExtractContentFromFile(context.Context, *domain.File) (*services.ExtractedContent, error)
+	GeneratePreviews(context.Context, *domain.File) error
 	UpdateFile(context.Context, services.UpdateFileInput) (*domain.File, error)
 }
 
@@ -30,14 +33,19 @@ func NewFileTaskHandler(fileService FileTaskService) *FileTaskHandler {
 	}
 }
 
+// Failures that can't go away on retry skip the remaining attempts. The
+// file is marked as failed only then or once retries run out, so a retried
+// run doesn't flip it between error and processing
 func (h *FileTaskHandler) HandlePdfProcessTask(ctx context.Context, t *asynq.Task) (err error) {
+	log := logger.FromContext(ctx)
+
 	var p tasks.PdfProcessPayload
 	if err = json.Unmarshal(t.Payload(), &p); err != nil {
-		logger.Logger.Error("Failed to parse task payload", zap.Error(err), zap.String("file_id", p.FileID))
-		return err
+		log.Error("Failed to parse task payload", zap.Error(err), zap.String("file_id", p.FileID))
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
 	}
 
-	logger.Logger.Info(
+	log.Info(
 		"Starting extracting text content",
 		zap.String("file_id", p.FileID),
 		zap.String("user_id", p.UserID),
@@ -49,18 +57,50 @@ func (h *FileTaskHandler) HandlePdfProcessTask(ctx context.Context, t *asynq.Tas
 	}
 
 	defer func() {
-		if err != nil && p.FileID != "" {
+		if err == nil {
+			return
+		}
+
+		permanent := isPermanent(err)
+		retried, _ := asynq.GetRetryCount(ctx)
+		maxRetry, _ := asynq.GetMaxRetry(ctx)
+
+		if !permanent && retried < maxRetry {
+			log.Warn(
+				"Failed to process file, task will be retried",
+				zap.Error(err),
+				zap.String("file_id", p.FileID),
+				zap.Int("retried", retried),
+				zap.Int("max_retry", maxRetry),
+			)
+			return
+		}
+
+		log.Error(
+			"Failed to process file",
+			zap.Error(err),
+			zap.String("file_id", p.FileID),
+			zap.Bool("permanent", permanent),
+		)
+
+		// nothing to mark when the file is gone
+		if !errors.Is(err, services.ErrFileNotFound) {
 			input := baseInput
 			input.Status = Ptr(domain.FileStatusError)
-			_, updateErr := h.fileService.UpdateFile(context.Background(), input)
+			input.ErrorMessage = Ptr(errorMessage(err))
+			_, updateErr := h.fileService.UpdateFile(context.WithoutCancel(ctx), input)
 			if updateErr != nil {
-				logger.Logger.Error(
+				log.Error(
 					"Failed to update file status to error",
 					zap.Error(updateErr),
 					zap.String("file_id", p.FileID),
 				)
 			}
 		}
+
+		if permanent {
+			err = fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
 	}()
 
 	input := baseInput
@@ -70,18 +110,30 @@ func (h *FileTaskHandler) HandlePdfProcessTask(ctx context.Context, t *asynq.Tas
 		return err
 	}
 
-	text, err := h.fileService.ExtractTextFromFile(ctx, file)
+	content, err := h.fileService.ExtractContentFromFile(ctx, file)
 	if err != nil {
 		return err
 	}
 
 	input = baseInput
-	input.TextContent = Ptr(text)
+	input.TextContent = Ptr(content.Text)
+	input.Metadata = content.Metadata
+	input.TextSource = Ptr(content.TextSource)
+	input.OcrConfidence = content.OcrConfidence
 	file, err = h.fileService.UpdateFile(ctx, input)
 	if err != nil {
 		return err
 	}
 
+	// text is what makes a file usable, so it doesn't wait for previews
+	if err := h.fileService.GeneratePreviews(ctx, file); err != nil {
+		log.Warn(
+			"Failed to generate file previews",
+			zap.Error(err),
+			zap.String("file_id", p.FileID),
+		)
+	}
+
 	input = baseInput
 	input.Status = Ptr(domain.FileStatusReady)
 	file, err = h.fileService.UpdateFile(ctx, input)
@@ -89,7 +141,7 @@ func (h *FileTaskHandler) HandlePdfProcessTask(ctx context.Context, t *asynq.Tas
 		return err
 	}
 
-	logger.Logger.Info(
+	log.Info(
 		"Successfully extracted text content",
 		zap.String("file_id", file.ID),
 		zap.String("user_id", p.UserID),
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"errors"
+	"math/rand/v2"
+	"qvarkk/kvault/internal/services"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Files that are gone or can't be parsed, rendered or recognized fail the
+// same way on every run, anything else, like storage or database hiccups,
+// is worth retrying
+func isPermanent(err error) bool {
+	return errors.Is(err, services.ErrFileNotFound) ||
+		errors.Is(err, services.ErrFileUnreadable)
+}
+
+type errorMessageRule struct {
+	target  error
+	message string
+}
+
+var errorMessageRules = []errorMessageRule{
+	{
+		target:  services.ErrFileUnreadable,
+		message: "File content can't be read, it may be damaged or malformed.",
+	},
+}
+
+const internalErrorMessage = "File couldn't be processed because of an internal error."
+
+// Messages are shown to the file owner, so they name the kind of failure
+// and never its cause
+func errorMessage(err error) string {
+	for _, rule := range errorMessageRules {
+		if errors.Is(err, rule.target) {
+			return rule.message
+		}
+	}
+	return internalErrorMessage
+}
+
+// Doubles the delay from base with every retry up to maxDelay, with up to
+// a quarter of jitter so failed tasks don't come back all at once
+func RetryDelay(base, maxDelay time.Duration) asynq.RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		delay := maxDelay
+		if n < 32 && base<<n > 0 && base<<n < maxDelay {
+			delay = base << n
+		}
+
+		jitter := time.Duration(rand.Int64N(int64(delay)/4 + 1))
+		return delay - jitter
+	}
+}
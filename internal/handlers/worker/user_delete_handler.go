@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/logger"
+
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+type AccountTaskService interface {
+	DeleteUserData(ctx context.Context, userID string) error
+	SendPasswordReset(ctx context.Context, email string) error
+}
+
+type AccountTaskHandler struct {
+	accountService AccountTaskService
+}
+
+func NewAccountTaskHandler(accountService AccountTaskService) *AccountTaskHandler {
+	return &AccountTaskHandler{
+		accountService: accountService,
+	}
+}
+
+func (h *AccountTaskHandler) HandleUserDeleteTask(ctx context.Context, t *asynq.Task) error {
+	log := logger.FromContext(ctx)
+
+	var p tasks.UserDeletePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		log.Error("Failed to parse task payload", zap.Error(err))
+		return err
+	}
+
+	log.Info("Starting user deletion", zap.String("user_id", p.UserID))
+
+	if err := h.accountService.DeleteUserData(ctx, p.UserID); err != nil {
+		log.Error("Failed to delete user", zap.Error(err), zap.String("user_id", p.UserID))
+		return err
+	}
+
+	log.Info("Successfully deleted user", zap.String("user_id", p.UserID))
+	return nil
+}
@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Check func(ctx context.Context) error
+
+// Runs named dependency checks for readiness probes. Once draining, the
+// service reports itself as not ready so no new traffic is sent its way
+type Checker struct {
+	names    []string
+	checks   map[string]Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// Errors of failed checks by name, nil for passed ones
+type Report struct {
+	Draining bool
+	Results  map[string]error
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		checks:  make(map[string]Check),
+		timeout: timeout,
+	}
+}
+
+// Not safe to call once the checker is serving probes
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Checks run concurrently, each one is cut off after the timeout
+func (c *Checker) Check(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := &Report{
+		Draining: c.draining.Load(),
+		Results:  make(map[string]error, len(c.names)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, name := range c.names {
+		check := c.checks[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := check(ctx)
+
+			mu.Lock()
+			report.Results[name] = err
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return report
+}
+
+func (r *Report) Ready() bool {
+	if r.Draining {
+		return false
+	}
+	for _, err := range r.Results {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
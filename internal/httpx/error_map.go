@@ -25,6 +25,27 @@ var serviceErrorRules = []mappingRule{
 			Message: "Access forbidden.",
 		},
 	},
+	{
+		target: services.ErrRateLimited,
+		public: &PublicError{
+			Err:     ErrTooManyRequests,
+			Message: "Rate limit exceeded, see Retry-After header.",
+		},
+	},
+	{
+		target: services.ErrTooManyAttempts,
+		public: &PublicError{
+			Err:     ErrTooManyRequests,
+			Message: "Too many failed attempts, try again later.",
+		},
+	},
+	{
+		target: services.ErrChallengeFailed,
+		public: &PublicError{
+			Err:     ErrForbidden,
+			Message: "Challenge solution is missing or invalid.",
+		},
+	},
 	{
 		target: services.ErrInvalidCredentials,
 		public: &PublicError{
@@ -46,6 +67,97 @@ var serviceErrorRules = []mappingRule{
 			Message: "User with this email already exists.",
 		},
 	},
+	{
+		target: services.ErrUserDisabled,
+		public: &PublicError{
+			Err:     ErrForbidden,
+			Message: "This account is disabled.",
+		},
+	},
+	{
+		target: services.ErrAdminSelfAction,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Admins can't disable their own account.",
+		},
+	},
+	{
+		target: services.ErrPasswordMismatch,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Current password is incorrect.",
+		},
+	},
+	{
+		target: services.ErrPasswordResetInvalid,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Password reset token is invalid or has expired.",
+		},
+	},
+	{
+		target: services.ErrFileTooLarge,
+		public: &PublicError{
+			Err:     ErrPayloadTooLarge,
+			Message: "File is larger than your max file size.",
+		},
+	},
+	{
+		target: services.ErrStorageQuotaExceeded,
+		public: &PublicError{
+			Err:     ErrPayloadTooLarge,
+			Message: "File doesn't fit into your storage quota.",
+		},
+	},
+	{
+		target: services.ErrItemQuotaExceeded,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "You have reached your item limit.",
+		},
+	},
+	{
+		target: services.ErrApiKeyNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "API key with given ID does not exist.",
+		},
+	},
+	{
+		target: services.ErrApiKeyAlreadyExists,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "API key with this name already exists.",
+		},
+	},
+	{
+		target: services.ErrApiKeyInvalid,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "API key needs a name not starting with \"login-\" and an expiry date in the future.",
+		},
+	},
+	{
+		target: services.ErrApiKeyScopeMissing,
+		public: &PublicError{
+			Err:     ErrForbidden,
+			Message: "API key is missing the scope required for this route.",
+		},
+	},
+	{
+		target: services.ErrApiKeyScopeExceeded,
+		public: &PublicError{
+			Err:     ErrForbidden,
+			Message: "API key can't grant scopes it doesn't have.",
+		},
+	},
+	{
+		target: services.ErrApiKeyScopeInsufficient,
+		public: &PublicError{
+			Err:     ErrForbidden,
+			Message: "API key can't manage a key with scopes it doesn't have.",
+		},
+	},
 	{
 		target: services.ErrItemNotFound,
 		public: &PublicError{
@@ -60,6 +172,48 @@ var serviceErrorRules = []mappingRule{
 			Message: "File with given ID does not exist.",
 		},
 	},
+	{
+		target: services.ErrFileBusy,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "This file is still waiting for processing or being processed.",
+		},
+	},
+	{
+		target: services.ErrFilePreviewNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "File has no preview of this page.",
+		},
+	},
+	{
+		target: services.ErrTaskNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "Archived task with given ID does not exist in the queue.",
+		},
+	},
+	{
+		target: services.ErrLogComponentNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "Log component with given name does not exist.",
+		},
+	},
+	{
+		target: services.ErrLogComponentUnused,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Log component isn't used by this process, its level can't be changed here.",
+		},
+	},
+	{
+		target: services.ErrInvalidLogLevel,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Log level has to be one of debug, info, warn or error.",
+		},
+	},
 	{
 		target: services.ErrStopwordNotFound,
 		public: &PublicError{
@@ -74,6 +228,27 @@ var serviceErrorRules = []mappingRule{
 			Message: "This stopword already exists.",
 		},
 	},
+	{
+		target: services.ErrStopwordImportInvalid,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Import should contain between 1 and 10000 words.",
+		},
+	},
+	{
+		target: services.ErrStopwordPackNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "This stopword pack does not exist.",
+		},
+	},
+	{
+		target: services.ErrDefaultStopwordExists,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "This default stopword already exists in the pack.",
+		},
+	},
 	{
 		target: services.ErrTagNotFound,
 		public: &PublicError{
@@ -89,10 +264,45 @@ var serviceErrorRules = []mappingRule{
 		},
 	},
 	{
-		target: services.ErrPdfFileFormat,
+		target: services.ErrTagMergeInvalid,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Target tag can't be one of the merged tags.",
+		},
+	},
+	{
+		target: services.ErrTagAliasNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "This tag alias does not exist.",
+		},
+	},
+	{
+		target: services.ErrTagAliasAlreadyExists,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "This tag alias already exists.",
+		},
+	},
+	{
+		target: services.ErrTagRuleNotFound,
+		public: &PublicError{
+			Err:     ErrNotFound,
+			Message: "This tag rule does not exist.",
+		},
+	},
+	{
+		target: services.ErrTagRuleInvalid,
+		public: &PublicError{
+			Err:     ErrUnprocessableEntity,
+			Message: "Tag rule needs a valid regex pattern or at least one keyword.",
+		},
+	},
+	{
+		target: services.ErrUnsupportedFileFormat,
 		public: &PublicError{
 			Err:     ErrUnprocessableEntity,
-			Message: "File should be of a PDF content type.",
+			Message: "File should be a PDF, PNG, JPEG or TIFF with a matching extension.",
 		},
 	},
 }
@@ -12,7 +12,9 @@ var (
 	ErrUnauthorized        = errors.New("Wrong credentials. Please check and try again.")
 	ErrForbidden           = errors.New("Access to the requested entity is forbidden.")
 	ErrNotFound            = errors.New("The requested resource was not found.")
+	ErrPayloadTooLarge     = errors.New("The request payload is too large.")
 	ErrUnprocessableEntity = errors.New("The request could not be processed. Please check your input.")
+	ErrTooManyRequests     = errors.New("Too many requests. Please slow down and try again later.")
 	ErrInternalServer      = errors.New("An internal server error occurred.")
 )
 
@@ -21,7 +23,9 @@ var errorStatusMap = map[error]int{
 	ErrUnauthorized:        http.StatusUnauthorized,
 	ErrForbidden:           http.StatusForbidden,
 	ErrNotFound:            http.StatusNotFound,
+	ErrPayloadTooLarge:     http.StatusRequestEntityTooLarge,
 	ErrUnprocessableEntity: http.StatusUnprocessableEntity,
+	ErrTooManyRequests:     http.StatusTooManyRequests,
 	ErrInternalServer:      http.StatusInternalServerError,
 }
 
@@ -38,6 +42,8 @@ type ErrorResponse struct {
 	Instance   string              `json:"instance"`
 	Detail     string              `json:"detail"`
 	Validation []ValidationDetails `json:"validation,omitempty"`
+	// Same as the X-Request-ID response header, for quoting in bug reports
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func (e *PublicError) Error() string {
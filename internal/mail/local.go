@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Writes messages to the log instead of sending them, for local development
+type LogSender struct {
+	from string
+}
+
+func NewLogSender(from string) *LogSender {
+	return &LogSender{from: from}
+}
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	logger.FromContext(ctx).Info(
+		"Mail message",
+		zap.String("from", s.from),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// Saves every message as an .eml file in the directory, for local development
+type FileSender struct {
+	from string
+	dir  string
+}
+
+func NewFileSender(from, dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mail: failed to create %s: %w", dir, err)
+	}
+
+	return &FileSender{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	path := filepath.Join(s.dir, name)
+
+	if err := os.WriteFile(path, formatMessage(s.from, msg), 0o644); err != nil {
+		return fmt.Errorf("mail: failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/config"
+)
+
+const (
+	DriverSmtp = "smtp"
+	DriverLog  = "log"
+	DriverFile = "file"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(context.Context, Message) error
+}
+
+// Picks a sender implementation by configured driver
+func NewSender(config config.MailConfig) (Sender, error) {
+	switch config.Driver {
+	case DriverSmtp:
+		return NewSmtpSender(config), nil
+	case DriverLog:
+		return NewLogSender(config.From), nil
+	case DriverFile:
+		return NewFileSender(config.From, config.Dir)
+	default:
+		return nil, fmt.Errorf("mail: unknown driver %q", config.Driver)
+	}
+}
@@ -0,0 +1,56 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"qvarkk/kvault/config"
+	"strconv"
+	"strings"
+)
+
+type SmtpSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSmtpSender(config config.MailConfig) *SmtpSender {
+	var auth smtp.Auth
+	if config.SmtpUser != "" {
+		auth = smtp.PlainAuth("", config.SmtpUser, config.SmtpPassword, config.SmtpHost)
+	}
+
+	return &SmtpSender{
+		addr: net.JoinHostPort(config.SmtpHost, strconv.Itoa(config.SmtpPort)),
+		from: config.From,
+		auth: auth,
+	}
+}
+
+// net/smtp has no context support, ctx is only checked before dialing
+func (s *SmtpSender) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, formatMessage(s.from, msg))
+	if err != nil {
+		return fmt.Errorf("mail: failed to send to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+func formatMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "kvault"
+
+// Registered in the default registry, which also has Go runtime and
+// process metrics. Both binaries share the definitions, each one only
+// fills in what it does
+var (
+	HttpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "task",
+		Name:      "duration_seconds",
+		Help:      "Duration of processed background tasks by type and result.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"type", "result"})
+
+	ExtractedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extraction",
+		Name:      "bytes_total",
+		Help:      "Size of files text was extracted from by MIME type.",
+	}, []string{"mime_type"})
+
+	ExtractedPages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "extraction",
+		Name:      "pages_total",
+		Help:      "Pages text was extracted from by source, text_layer, ocr or empty.",
+	}, []string{"source"})
+
+	BlobOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "blob",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of blob store operations by driver and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"driver", "operation"})
+
+	BlobOperationErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "blob",
+		Name:      "operation_errors_total",
+		Help:      "Failed blob store operations by driver and operation, missing objects excluded.",
+	}, []string{"driver", "operation"})
+
+	Items = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "items",
+		Help:      "Items that aren't deleted.",
+	})
+
+	Files = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "files",
+		Help:      "Files that aren't deleted by processing status.",
+	}, []string{"status"})
+
+	Tags = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tags",
+		Help:      "Tags of all users.",
+	})
+)
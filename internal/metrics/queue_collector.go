@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+type QueueStatsLister interface {
+	ListQueueStats(context.Context) ([]domain.QueueStats, error)
+}
+
+// Reads queue sizes from Redis on every scrape, nothing is reported when
+// Redis can't be reached
+type QueueCollector struct {
+	lister  QueueStatsLister
+	tasks   *prometheus.Desc
+	latency *prometheus.Desc
+}
+
+func NewQueueCollector(lister QueueStatsLister) *QueueCollector {
+	return &QueueCollector{
+		lister: lister,
+		tasks: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "queue", "tasks"),
+			"Tasks in the queue by state.",
+			[]string{"queue", "state"}, nil,
+		),
+		latency: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "queue", "latency_seconds"),
+			"Time the oldest pending task of the queue has been waiting.",
+			[]string{"queue"}, nil,
+		),
+	}
+}
+
+func (c *QueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.tasks
+	ch <- c.latency
+}
+
+func (c *QueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stats, err := c.lister.ListQueueStats(ctx)
+	if err != nil {
+		logger.Logger.Warn("Failed to collect queue stats", zap.Error(err))
+		return
+	}
+
+	for _, queue := range stats {
+		states := map[string]int{
+			"pending":   queue.Pending,
+			"active":    queue.Active,
+			"scheduled": queue.Scheduled,
+			"retry":     queue.Retry,
+			"archived":  queue.Archived,
+			"completed": queue.Completed,
+		}
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(c.tasks, prometheus.GaugeValue, float64(count), queue.Queue, state)
+		}
+		ch <- prometheus.MustNewConstMetric(c.latency, prometheus.GaugeValue, queue.Latency.Seconds(), queue.Queue)
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Logs one structured line per request once it's done. Has to run before
+// RequestMeta to see the request ID, probes and scrapes aren't logged
+func AccessLog() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		if isInfraRoute(ctx) {
+			return
+		}
+
+		status := ctx.Writer.Status()
+		level := zapcore.InfoLevel
+		if status >= 500 {
+			level = zapcore.ErrorLevel
+		}
+
+		logger.Access.Log(level, "request",
+			zap.String("request_id", domain.RequestMetaFrom(ctx.Request.Context()).RequestID),
+			zap.String("method", ctx.Request.Method),
+			zap.String("path", ctx.Request.URL.Path),
+			zap.String("route", ctx.FullPath()),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.Int("bytes", max(ctx.Writer.Size(), 0)),
+			zap.String("user_id", ctx.GetString("userID")),
+			zap.String("client_ip", ctx.ClientIP()),
+			zap.String("user_agent", ctx.Request.UserAgent()),
+		)
+	}
+}
+
+// Probes and metrics scrapes come every few seconds and tell nothing
+// about client traffic
+func isInfraRoute(ctx *gin.Context) bool {
+	switch ctx.FullPath() {
+	case "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	return false
+}
@@ -2,20 +2,34 @@ package middleware
 
 import (
 	"context"
+	"net/http"
 	"qvarkk/kvault/internal/domain"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-type UserService interface {
-	Authenticate(context.Context, string) (*domain.User, error)
+type ApiKeyService interface {
+	Authenticate(context.Context, string, domain.ApiKeyScope) (*domain.ApiKey, *domain.User, error)
 }
 
-func AuthRequired(userService UserService) gin.HandlerFunc {
+// Safe methods require readScope and everything else requires writeScope.
+// Empty scopes let any valid key through
+func AuthRequired(apiKeyService ApiKeyService, readScope, writeScope domain.ApiKeyScope) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
+		// raw keys in the header are still accepted for older clients
 		api_key := ctx.GetHeader("Authorization")
+		if key, ok := strings.CutPrefix(api_key, "Bearer "); ok {
+			api_key = strings.TrimSpace(key)
+		}
+
+		scope := writeScope
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			scope = readScope
+		}
 
-		user, err := userService.Authenticate(ctx.Request.Context(), api_key)
+		apiKey, user, err := apiKeyService.Authenticate(ctx.Request.Context(), api_key, scope)
 		if err != nil {
 			ctx.Error(err)
 			ctx.Abort()
@@ -23,6 +37,9 @@ func AuthRequired(userService UserService) gin.HandlerFunc {
 		}
 
 		ctx.Set("userID", user.ID)
+		ctx.Set("userRole", user.Role)
+		ctx.Set("apiKeyID", apiKey.ID)
+		ctx.Set("apiKeyScopes", []string(apiKey.Scopes))
 		ctx.Next()
 	}
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"errors"
+	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/httpx"
 	"qvarkk/kvault/internal/services"
 	"qvarkk/kvault/logger"
@@ -41,8 +42,9 @@ func ErrorHandlingMiddleware() gin.HandlerFunc {
 			}
 		}
 
+		log := logger.FromContext(c.Request.Context())
 		for _, ginErr := range c.Errors {
-			logger.Logger.Error("request error",
+			log.Error("request error",
 				zap.String("path", c.FullPath()),
 				zap.String("method", c.Request.Method),
 				zap.Error(ginErr.Err),
@@ -50,6 +52,7 @@ func ErrorHandlingMiddleware() gin.HandlerFunc {
 		}
 
 		errResponse := publicErr.ToErrorResponse(c.FullPath())
+		errResponse.RequestID = domain.RequestMetaFrom(c.Request.Context()).RequestID
 		c.AbortWithStatusJSON(errResponse.Status, errResponse)
 	}
 }
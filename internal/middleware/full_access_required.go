@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lets through keys with admin scope or every default scope, account-wide
+// actions must not be reachable with a narrowed key. Has to be used after
+// AuthRequired
+func FullAccessRequired() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scopes, _ := ctx.Get("apiKeyScopes")
+		granted, _ := scopes.([]string)
+		if !hasFullAccess(granted) {
+			apiKeyID, _ := ctx.Get("apiKeyID")
+			errMsg := fmt.Sprintf("api key %v has neither admin nor all default scopes", apiKeyID)
+			ctx.Error(services.NewServiceError(services.ErrApiKeyScopeMissing, errMsg, nil))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func hasFullAccess(granted []string) bool {
+	if slices.Contains(granted, string(domain.ApiKeyScopeAdmin)) {
+		return true
+	}
+	for _, scope := range domain.DefaultApiKeyScopes {
+		if !slices.Contains(granted, string(scope)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
+	"qvarkk/kvault/logger"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*domain.RateLimitResult, error)
+}
+
+const (
+	RateLimitByUser = "user"
+	RateLimitByKey  = "key"
+	RateLimitByIp   = "ip"
+)
+
+type RateLimitRule struct {
+	// Separate budget name, e.g. route group
+	Name   string
+	Limit  int
+	Window time.Duration
+	// RateLimitByUser, RateLimitByKey or RateLimitByIp
+	KeyBy string
+}
+
+// Has to be used after AuthRequired unless keyed by IP. Requests pass
+// through when the limiter fails, Redis being down shouldn't take the API
+// with it
+func RateLimit(limiter RateLimiter, rule RateLimitRule) gin.HandlerFunc {
+	policy := fmt.Sprintf("%d;w=%d", rule.Limit, int(rule.Window.Seconds()))
+
+	return func(ctx *gin.Context) {
+		subject := ctx.GetString("userID")
+		switch rule.KeyBy {
+		case RateLimitByKey:
+			subject = ctx.GetString("apiKeyID")
+		case RateLimitByIp:
+			subject = ctx.ClientIP()
+		}
+
+		key := fmt.Sprintf("ratelimit:%s:%s:%s", rule.Name, rule.KeyBy, subject)
+		result, err := limiter.Allow(ctx.Request.Context(), key, rule.Limit, rule.Window)
+		if err != nil {
+			logger.FromContext(ctx.Request.Context()).Warn("Rate limiter failed", zap.Error(err), zap.String("key", key))
+			ctx.Next()
+			return
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(result.Reset.Seconds())))
+		ctx.Header("RateLimit-Policy", policy)
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", reset)
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", reset)
+			errMsg := fmt.Sprintf("%s rate limit exceeded by %s", rule.Name, subject)
+			ctx.Error(services.NewServiceError(services.ErrRateLimited, errMsg, nil))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// IDs sent by clients end up in logs and audit events, anything else is
+// replaced with a generated one
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Puts client details and a logger tagged with the request ID into the
+// request context for services to pick up. The ID is echoed back to the client
+func RequestMeta() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+		ctx.Header(requestIDHeader, requestID)
+
+		meta := domain.RequestMeta{
+			RequestID: requestID,
+			ClientIP:  ctx.ClientIP(),
+			UserAgent: ctx.Request.UserAgent(),
+		}
+
+		reqCtx := domain.WithRequestMeta(ctx.Request.Context(), meta)
+		log := logger.Logger.Named(logger.ComponentHttp).With(zap.String("request_id", requestID))
+		reqCtx = logger.WithContext(reqCtx, log)
+		ctx.Request = ctx.Request.WithContext(reqCtx)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Has to be used after AuthRequired
+func RoleRequired(role domain.UserRole) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		userRole, _ := ctx.Get("userRole")
+		if userRole != role {
+			errMsg := fmt.Sprintf("%s role is required", role)
+			ctx.Error(services.NewServiceError(services.ErrForbidden, errMsg, nil))
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// Starts a span per request named after the route template, continuing
+// the trace of the caller if it sent one. Probes, metrics scrapes and docs
+// aren't traced
+func Tracing(service string) gin.HandlerFunc {
+	return otelgin.Middleware(service, otelgin.WithGinFilter(func(ctx *gin.Context) bool {
+		return !isInfraRoute(ctx) && !strings.HasPrefix(ctx.FullPath(), "/swagger/")
+	}))
+}
@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/config"
+	"time"
+)
+
+const (
+	EngineNone      = ""
+	EngineTesseract = "tesseract"
+	EngineNop       = "nop"
+)
+
+// Confidence is the mean over recognized words, from 0 to 100
+type Result struct {
+	Text       string
+	Confidence float64
+}
+
+type Engine interface {
+	Recognize(ctx context.Context, imagePath string) (*Result, error)
+}
+
+// Picks an engine by configured name, nil means that OCR is disabled
+func NewEngine(config config.OcrConfig) (Engine, error) {
+	switch config.Engine {
+	case EngineNone:
+		return nil, nil
+	case EngineTesseract:
+		timeout := time.Duration(config.TimeoutSeconds) * time.Second
+		return NewTesseract(config.EnginePath, config.Languages, timeout)
+	case EngineNop:
+		return Nop{}, nil
+	default:
+		return nil, fmt.Errorf("ocr: unknown engine %q", config.Engine)
+	}
+}
+
+// Stand-in that recognizes nothing, for running the worker without an engine
+type Nop struct{}
+
+func (Nop) Recognize(context.Context, string) (*Result, error) {
+	return &Result{}, nil
+}
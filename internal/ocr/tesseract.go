@@ -0,0 +1,132 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tesseract gave up on the image by itself, running it again won't help
+var ErrUnreadableImage = errors.New("ocr: image can't be read")
+
+// Runs the tesseract binary and reads words with their confidence from
+// its TSV output
+type Tesseract struct {
+	binary    string
+	languages string
+	timeout   time.Duration
+}
+
+func NewTesseract(binary, languages string, timeout time.Duration) (*Tesseract, error) {
+	if binary == "" {
+		binary = EngineTesseract
+	}
+
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("ocr: tesseract not found: %w", err)
+	}
+
+	return &Tesseract{
+		binary:    resolved,
+		languages: languages,
+		timeout:   timeout,
+	}, nil
+}
+
+func (t *Tesseract) Recognize(ctx context.Context, imagePath string) (*Result, error) {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	args := []string{imagePath, "stdout"}
+	if t.languages != "" {
+		args = append(args, "-l", t.languages)
+	}
+	args = append(args, "tsv")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// being killed on timeout or cancellation says nothing about the image
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrUnreadableImage, err)
+		}
+		return nil, fmt.Errorf("ocr: tesseract failed: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return parseTsv(&stdout)
+}
+
+// Columns are level, page_num, block_num, par_num, line_num, word_num,
+// left, top, width, height, conf and text. Words are level 5 rows
+func parseTsv(output *bytes.Buffer) (*Result, error) {
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		lines      []string
+		line       []string
+		lineKey    string
+		confidence float64
+		words      int
+	)
+
+	flush := func() {
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+			line = nil
+		}
+	}
+
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+
+		text := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil || conf < 0 || text == "" {
+			continue
+		}
+
+		key := strings.Join(fields[1:5], ":")
+		if key != lineKey {
+			flush()
+			lineKey = key
+		}
+
+		line = append(line, text)
+		confidence += conf
+		words++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ocr: failed to read tesseract output: %w", err)
+	}
+	flush()
+
+	result := &Result{Text: strings.Join(lines, "\n")}
+	if words > 0 {
+		result.Confidence = confidence / float64(words)
+	}
+	return result, nil
+}
@@ -2,12 +2,20 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"qvarkk/kvault/internal/tracing"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
 )
 
+const driverName = "postgres"
+
 type Postgres struct {
 	DB *sqlx.DB
 }
@@ -17,13 +25,17 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// Records a span for every query made within a trace
+	Traced bool
 }
 
 func NewPostgres(config Config) (*Postgres, error) {
-	db, err := sqlx.Connect("postgres", config.DSN)
+	sqlDB, err := open(config)
 	if err != nil {
 		return nil, err
 	}
+
+	db := sqlx.NewDb(sqlDB, driverName)
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
@@ -31,12 +43,33 @@ func NewPostgres(config Config) (*Postgres, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := db.PingContext(ctx); err != nil {
-		return nil, err
+		return nil, errors.Join(err, db.Close())
 	}
 
 	return &Postgres{DB: db}, nil
 }
 
+func open(config Config) (*sql.DB, error) {
+	if !config.Traced {
+		return sql.Open(driverName, config.DSN)
+	}
+
+	// pings, connection resets and row iteration would only add noise,
+	// queries outside a request or task like probes are skipped too
+	return otelsql.Open(driverName, config.DSN,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return tracing.HasParent(ctx)
+			},
+		}),
+	)
+}
+
 func (p *Postgres) Close() error {
 	return p.DB.Close()
 }
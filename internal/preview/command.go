@@ -0,0 +1,88 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// The tool gave up on the PDF by itself, running it again won't help
+var ErrUnreadablePdf = errors.New("preview: PDF can't be rendered")
+
+// Runs poppler's pdftoppm or MuPDF's mutool, each page is written to
+// a temporary directory and read back
+type CommandRenderer struct {
+	tool    string
+	binary  string
+	timeout time.Duration
+}
+
+func NewCommandRenderer(tool, binary string, timeout time.Duration) (*CommandRenderer, error) {
+	if binary == "" {
+		binary = tool
+	}
+
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("preview: %s not found: %w", tool, err)
+	}
+
+	return &CommandRenderer{
+		tool:    tool,
+		binary:  resolved,
+		timeout: timeout,
+	}, nil
+}
+
+func (r *CommandRenderer) RenderPage(ctx context.Context, pdfPath string, page, width int) ([]byte, error) {
+	outDir, err := os.MkdirTemp("", "kvault-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(outDir)
+
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	outRoot := filepath.Join(outDir, "page")
+	cmd := exec.CommandContext(ctx, r.binary, r.args(pdfPath, outRoot, page, width)...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		// being killed on timeout or cancellation says nothing about the PDF
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && ctx.Err() == nil {
+			err = fmt.Errorf("%w: %w", ErrUnreadablePdf, err)
+		}
+		return nil, fmt.Errorf("preview: %s failed on page %d: %w: %s", r.tool, page, err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return os.ReadFile(outRoot + ".png")
+}
+
+// pdftoppm appends the extension to the output root by itself
+func (r *CommandRenderer) args(pdfPath, outRoot string, page, width int) []string {
+	pageArg := strconv.Itoa(page)
+
+	if r.tool == RendererMutool {
+		return []string{"draw", "-q", "-F", "png", "-w", strconv.Itoa(width), "-o", outRoot + ".png", pdfPath, pageArg}
+	}
+
+	return []string{
+		"-png", "-singlefile",
+		"-f", pageArg, "-l", pageArg,
+		"-scale-to-x", strconv.Itoa(width), "-scale-to-y", "-1",
+		pdfPath, outRoot,
+	}
+}
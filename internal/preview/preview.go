@@ -0,0 +1,33 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/config"
+	"time"
+)
+
+const (
+	RendererNone     = ""
+	RendererPdftoppm = "pdftoppm"
+	RendererMutool   = "mutool"
+)
+
+type Renderer interface {
+	// Renders a page of the PDF at path, counted from 1, as a PNG of given width
+	RenderPage(ctx context.Context, pdfPath string, page, width int) ([]byte, error)
+}
+
+// Picks a renderer by configured name, nil means that previews are disabled
+func NewRenderer(config config.PreviewConfig) (Renderer, error) {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+
+	switch config.Renderer {
+	case RendererNone:
+		return nil, nil
+	case RendererPdftoppm, RendererMutool:
+		return NewCommandRenderer(config.Renderer, config.RendererPath, timeout)
+	default:
+		return nil, fmt.Errorf("preview: unknown renderer %q", config.Renderer)
+	}
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Counts failed attempts and holds temporary blocks under plain string keys
+type AttemptTracker struct {
+	client *goredis.Client
+}
+
+func NewAttemptTracker(client *goredis.Client) *AttemptTracker {
+	return &AttemptTracker{client: client}
+}
+
+// Increments the counter, the window starts with the first failure
+func (t *AttemptTracker) AddFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	pipe := t.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+// Blocks the key for at least d, a longer existing block is kept
+func (t *AttemptTracker) Block(ctx context.Context, key string, d time.Duration) error {
+	remaining, err := t.BlockedFor(ctx, key)
+	if err != nil {
+		return err
+	}
+	if remaining >= d {
+		return nil
+	}
+	return t.client.Set(ctx, key, 1, d).Err()
+}
+
+// Returns the longest remaining block among keys, zero if none is blocked
+func (t *AttemptTracker) BlockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	var longest time.Duration
+	for _, key := range keys {
+		ttl, err := t.client.PTTL(ctx, key).Result()
+		if err != nil {
+			return 0, err
+		}
+		longest = max(longest, ttl)
+	}
+	return longest, nil
+}
+
+func (t *AttemptTracker) Reset(ctx context.Context, keys ...string) error {
+	return t.client.Del(ctx, keys...).Err()
+}
@@ -1,13 +1,17 @@
 package redis
 
 import (
+	"context"
 	"errors"
 
 	"github.com/hibiken/asynq"
+	goredis "github.com/redis/go-redis/v9"
 )
 
 type Redis struct {
 	AsynqClient *asynq.Client
+	Inspector   *asynq.Inspector
+	Client      *goredis.Client
 }
 
 type Config struct {
@@ -15,24 +19,35 @@ type Config struct {
 	Username string
 	Password string
 	DB       int
+	// Records a span for every command made within a trace
+	Traced bool
 }
 
 func NewRedis(config Config) (*Redis, error) {
-	redisConnOpt := asynq.RedisClientOpt{
+	rdb := goredis.NewClient(&goredis.Options{
 		Addr:     config.Addr,
 		Username: config.Username,
 		Password: config.Password,
 		DB:       config.DB,
-	}
+	})
 
-	client := asynq.NewClient(redisConnOpt)
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		return nil, errors.Join(err, rdb.Close())
+	}
 
-	if err := client.Ping(); err != nil {
-		cErr := client.Close()
-		return nil, errors.Join(err, cErr)
+	if config.Traced {
+		rdb.AddHook(tracingHook{addr: config.Addr})
 	}
 
+	// asynq shares the connection so enqueues show up in traces too
 	return &Redis{
-		AsynqClient: client,
+		AsynqClient: asynq.NewClientFromRedisClient(rdb),
+		Inspector:   asynq.NewInspectorFromRedisClient(rdb),
+		Client:      rdb,
 	}, nil
 }
+
+// asynq can't close a shared connection, closing the client is enough
+func (r *Redis) Close() error {
+	return r.Client.Close()
+}
@@ -0,0 +1,71 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Sliding window log: every request is a sorted set member scored by its time
+// in milliseconds. Returns {allowed, remaining, reset ms}
+var slidingWindowScript = goredis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	count = count + 1
+	allowed = 1
+end
+
+local reset = 0
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, limit - count, reset}
+`)
+
+type RateLimiter struct {
+	client *goredis.Client
+}
+
+func NewRateLimiter(client *goredis.Client) *RateLimiter {
+	return &RateLimiter{client: client}
+}
+
+func (l *RateLimiter) Allow(
+	ctx context.Context,
+	key string,
+	limit int,
+	window time.Duration,
+) (*domain.RateLimitResult, error) {
+	now := time.Now()
+	member := fmt.Sprintf("%d", now.UnixNano())
+
+	res, err := slidingWindowScript.Run(
+		ctx, l.client, []string{key},
+		now.UnixMilli(), window.Milliseconds(), limit, member,
+	).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.RateLimitResult{
+		Allowed:   res[0] == 1,
+		Limit:     limit,
+		Remaining: int(max(res[1], 0)),
+		Reset:     time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"qvarkk/kvault/internal/tracing"
+	"strconv"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Records commands issued within a trace. asynq polls Redis all the time
+// outside of any request, those commands get no span
+type tracingHook struct {
+	addr string
+}
+
+func (h tracingHook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return next
+}
+
+func (h tracingHook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		if !tracing.HasParent(ctx) {
+			return next(ctx, cmd)
+		}
+
+		ctx, span := h.start(ctx, "redis "+cmd.Name(), cmd.Name())
+		defer span.End()
+
+		err := next(ctx, cmd)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		if !tracing.HasParent(ctx) {
+			return next(ctx, cmds)
+		}
+
+		names := make([]string, len(cmds))
+		for i, cmd := range cmds {
+			names[i] = cmd.Name()
+		}
+
+		ctx, span := h.start(ctx, "redis pipeline", strings.Join(names, " "))
+		defer span.End()
+		span.SetAttributes(attribute.Int("db.operation.batch.size", len(cmds)))
+
+		err := next(ctx, cmds)
+		recordError(span, err)
+		return err
+	}
+}
+
+func (h tracingHook) start(ctx context.Context, name, operation string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system.name", "redis"),
+		attribute.String("db.operation.name", operation),
+	}
+	if host, port, err := net.SplitHostPort(h.addr); err == nil {
+		attrs = append(attrs, attribute.String("server.address", host))
+		if n, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, attribute.Int("server.port", n))
+		}
+	}
+
+	return tracing.Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// A missing key is an answer, not a failure
+func recordError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, goredis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
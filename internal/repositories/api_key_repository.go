@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"qvarkk/kvault/internal/domain"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ApiKeyRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewApiKeyRepo(db *sqlx.DB) *ApiKeyRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &ApiKeyRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+func (r *ApiKeyRepo) CreateNew(ctx context.Context, apiKey *domain.ApiKey) error {
+	sql, args, err := r.queryBuilder.
+		Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes", "expires_at").
+		Values(apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, apiKey.ExpiresAt).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(apiKey)
+	return toRepositoryError(err)
+}
+
+func (r *ApiKeyRepo) CreateNewTx(ctx context.Context, tx *sqlx.Tx, apiKey *domain.ApiKey) error {
+	sql, args, err := r.queryBuilder.
+		Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes", "expires_at").
+		Values(apiKey.UserID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, apiKey.ExpiresAt).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = tx.QueryRowxContext(ctx, sql, args...).StructScan(apiKey)
+	return toRepositoryError(err)
+}
+
+func (r *ApiKeyRepo) IsPrefixUnique(ctx context.Context, prefix string) (bool, error) {
+	sql, args, err := r.queryBuilder.
+		Select("1").From("api_keys").
+		Where(sq.Eq{"prefix": prefix}).
+		Limit(1).ToSql()
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	var exists bool
+	err = r.db.GetContext(ctx, &exists, sql, args...)
+	err = toRepositoryError(err)
+	if errors.Is(err, ErrNotFound) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// Returns keys that are neither revoked nor expired
+func (r *ApiKeyRepo) List(ctx context.Context, userID string) ([]domain.ApiKey, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("api_keys").
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil}).
+		Where(sq.Or{sq.Eq{"expires_at": nil}, sq.Expr("expires_at > now()")}).
+		OrderBy("created_at ASC").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var apiKeys []domain.ApiKey
+	err = r.db.SelectContext(ctx, &apiKeys, sql, args...)
+	return apiKeys, toRepositoryError(err)
+}
+
+func (r *ApiKeyRepo) GetByID(ctx context.Context, keyID string) (*domain.ApiKey, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("api_keys").
+		Where(sq.Eq{"id": keyID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var apiKey domain.ApiKey
+	err = r.db.GetContext(ctx, &apiKey, sql, args...)
+	return &apiKey, toRepositoryError(err)
+}
+
+// Finds a usable key by its prefix and hash and bumps its last_used_at in the same statement
+func (r *ApiKeyRepo) Use(ctx context.Context, prefix, keyHash string) (*domain.ApiKey, error) {
+	sql, args, err := r.queryBuilder.
+		Update("api_keys").
+		Set("last_used_at", time.Now()).
+		Where(sq.Eq{"prefix": prefix, "key_hash": keyHash, "revoked_at": nil}).
+		Where(sq.Or{sq.Eq{"expires_at": nil}, sq.Expr("expires_at > now()")}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var apiKey domain.ApiKey
+	err = r.db.GetContext(ctx, &apiKey, sql, args...)
+	return &apiKey, toRepositoryError(err)
+}
+
+// Replaces key prefix and hash and returns the updated key
+func (r *ApiKeyRepo) UpdateKey(ctx context.Context, keyID, prefix, keyHash string) (*domain.ApiKey, error) {
+	sql, args, err := r.queryBuilder.
+		Update("api_keys").
+		Set("prefix", prefix).
+		Set("key_hash", keyHash).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": keyID, "revoked_at": nil}).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var apiKey domain.ApiKey
+	err = r.db.GetContext(ctx, &apiKey, sql, args...)
+	return &apiKey, toRepositoryError(err)
+}
+
+func (r *ApiKeyRepo) Revoke(ctx context.Context, keyID string) error {
+	sql, args, err := r.queryBuilder.
+		Update("api_keys").
+		Set("revoked_at", time.Now()).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": keyID, "revoked_at": nil}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Revokes login keys of the user past the newest keep ones
+func (r *ApiKeyRepo) RevokeOldLoginKeysTx(ctx context.Context, tx *sqlx.Tx, userID string, keep int) error {
+	now := time.Now()
+	sql, args, err := r.queryBuilder.
+		Update("api_keys").
+		Set("revoked_at", now).
+		Set("updated_at", now).
+		Where(
+			`id IN (
+				SELECT id FROM api_keys
+				WHERE user_id = ? AND name LIKE ? AND revoked_at IS NULL
+				ORDER BY created_at DESC
+				OFFSET ?
+			)`,
+			userID, domain.LoginApiKeyNamePrefix+"%", keep,
+		).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Revokes every login key of the user but exceptID, which may be empty
+func (r *ApiKeyRepo) RevokeLoginKeysTx(ctx context.Context, tx *sqlx.Tx, userID, exceptID string) error {
+	now := time.Now()
+	query := r.queryBuilder.
+		Update("api_keys").
+		Set("revoked_at", now).
+		Set("updated_at", now).
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil}).
+		Where(sq.Like{"name": domain.LoginApiKeyNamePrefix + "%"})
+	if exceptID != "" {
+		query = query.Where(sq.NotEq{"id": exceptID})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Adds the scope to live keys with given name of the users
+func (r *ApiKeyRepo) AddScopeTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userIDs []string,
+	name string,
+	scope domain.ApiKeyScope,
+) error {
+	sql, args, err := r.queryBuilder.
+		Update("api_keys").
+		Set("scopes", sq.Expr("array_append(scopes, ?)", scope)).
+		Set("updated_at", time.Now()).
+		Where("user_id = ANY(?)", pq.Array(userIDs)).
+		Where(sq.Eq{"name": name, "revoked_at": nil}).
+		Where("NOT (? = ANY(scopes))", scope).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/sync/errgroup"
+)
+
+type AuditRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewAuditRepo(db *sqlx.DB) *AuditRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &AuditRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+func (r *AuditRepo) CreateNew(ctx context.Context, event *domain.AuditEvent) error {
+	details := event.Details
+	if len(details) == 0 {
+		details = []byte("{}")
+	}
+
+	sql, args, err := r.queryBuilder.
+		Insert("audit_events").
+		Columns(
+			"actor_id", "user_id", "action", "target_type", "target_id",
+			"details", "request_id", "ip", "user_agent",
+		).
+		Values(
+			event.ActorID, event.UserID, event.Action, event.TargetType, event.TargetID,
+			details, event.RequestID, event.IP, event.UserAgent,
+		).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(event)
+	return toRepositoryError(err)
+}
+
+func (r *AuditRepo) List(ctx context.Context, params domain.ListAuditEventFilter) ([]domain.AuditEvent, int, error) {
+	offset := uint64(params.PageSize * (params.Page - 1))
+	baseQuery := r.queryBuilder.
+		Select().
+		From("audit_events")
+
+	if params.UserID != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"user_id": params.UserID})
+	}
+	if params.ActorID != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"actor_id": params.ActorID})
+	}
+	if params.Action != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"action": params.Action})
+	}
+	if params.TargetType != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"target_type": params.TargetType})
+	}
+	if params.TargetID != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"target_id": params.TargetID})
+	}
+	if params.From != nil {
+		baseQuery = baseQuery.Where(sq.GtOrEq{"created_at": *params.From})
+	}
+	if params.To != nil {
+		baseQuery = baseQuery.Where(sq.Lt{"created_at": *params.To})
+	}
+
+	eventsSql, eventsArgs, err := baseQuery.
+		Columns("*").
+		OrderBy(fmt.Sprintf("%s %s", params.Column, params.Direction)).
+		Offset(offset).
+		Limit(uint64(params.PageSize)).
+		ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	countSql, countArgs, err := baseQuery.Columns("COUNT(*)").ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	g, _ := errgroup.WithContext(ctx)
+
+	var events []domain.AuditEvent
+	g.Go(func() error {
+		if err := r.db.SelectContext(ctx, &events, eventsSql, eventsArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	var count int
+	g.Go(func() error {
+		if err := r.db.GetContext(ctx, &count, countSql, countArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	_ = g.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, 0, toRepositoryError(cause)
+	}
+
+	return events, count, nil
+}
@@ -11,13 +11,15 @@ import (
 )
 
 const (
-	CodeNameUniqueViolation = "unique_violation"
+	CodeNameUniqueViolation          = "unique_violation"
+	CodeNameInvalidRegularExpression = "invalid_regular_expression"
 )
 
 var (
 	ErrUnknown       = errors.New("repo: unknown database error")
 	ErrNotFound      = errors.New("repo: requested entity was not found")
 	ErrAlreadyExists = errors.New("repo: entity already exists")
+	ErrInvalidInput  = errors.New("repo: invalid input")
 )
 
 func toRepositoryError(err error) error {
@@ -31,6 +33,8 @@ func toRepositoryError(err error) error {
 		switch pqErr.Code.Name() {
 		case CodeNameUniqueViolation:
 			return wrapError(ErrAlreadyExists, err)
+		case CodeNameInvalidRegularExpression:
+			return wrapError(ErrInvalidInput, err)
 		}
 	} else if errors.Is(err, sql.ErrNoRows) {
 		return wrapError(ErrNotFound, err)
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type FilePreviewRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewFilePreviewRepo(db *sqlx.DB) *FilePreviewRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &FilePreviewRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+func (r *FilePreviewRepo) GetByFilePage(ctx context.Context, fileID string, page int) (*domain.FilePreview, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("file_previews").
+		Where(sq.Eq{"file_id": fileID, "page": page}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var preview domain.FilePreview
+	err = r.db.GetContext(ctx, &preview, sql, args...)
+	return &preview, toRepositoryError(err)
+}
+
+// Returns which of the given files have a thumbnail
+func (r *FilePreviewRepo) FindThumbnailed(ctx context.Context, fileIDs []string) (map[string]bool, error) {
+	if len(fileIDs) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	sql, args, err := r.queryBuilder.
+		Select("file_id").
+		From("file_previews").
+		Where(sq.Eq{"file_id": fileIDs, "page": domain.FileThumbnailPage}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var ids []string
+	if err := r.db.SelectContext(ctx, &ids, sql, args...); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	result := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result, nil
+}
+
+// Swaps all previews of the file for the given ones and returns S3 keys
+// of the removed rows
+func (r *FilePreviewRepo) ReplaceForFileTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	fileID string,
+	previews []domain.FilePreview,
+) ([]string, error) {
+	sql, args, err := r.queryBuilder.
+		Delete("file_previews").
+		Where(sq.Eq{"file_id": fileID}).
+		Suffix("RETURNING s3_key").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var oldKeys []string
+	if err := tx.SelectContext(ctx, &oldKeys, sql, args...); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	if len(previews) == 0 {
+		return oldKeys, nil
+	}
+
+	query := r.queryBuilder.
+		Insert("file_previews").
+		Columns("file_id", "page", "s3_key", "width", "height", "size", "key_id", "wrapped_key")
+
+	for _, p := range previews {
+		// lib/pq sends an empty slice instead of NULL for nil bytes
+		var wrappedKey any
+		if p.KeyID.Valid {
+			wrappedKey = p.WrappedKey
+		}
+		query = query.Values(fileID, p.Page, p.S3Key, p.Width, p.Height, p.Size, p.KeyID, wrappedKey)
+	}
+
+	sql, args, err = query.ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return oldKeys, toRepositoryError(err)
+}
@@ -25,9 +25,17 @@ func NewFileRepo(db *sqlx.DB) *FileRepo {
 }
 
 func (r *FileRepo) CreateNew(ctx context.Context, file *domain.File) error {
+	columns := []string{"user_id", "original_name", "s3_key", "size", "mime_type", "status"}
+	values := []any{file.UserID, file.OriginalName, file.S3Key, file.Size, file.MimeType, file.Status}
+
+	if file.KeyID.Valid {
+		columns = append(columns, "key_id", "wrapped_key")
+		values = append(values, file.KeyID, file.WrappedKey)
+	}
+
 	sql, args, err := r.queryBuilder.
-		Insert("files").Columns("user_id", "original_name", "s3_key", "size", "mime_type", "status").
-		Values(file.UserID, file.OriginalName, file.S3Key, file.Size, file.MimeType, file.Status).
+		Insert("files").Columns(columns...).
+		Values(values...).
 		Suffix("RETURNING *").ToSql()
 	if err != nil {
 		return toRepositoryError(err)
@@ -56,6 +64,16 @@ func (r *FileRepo) List(ctx context.Context, params domain.ListFileFilter) ([]do
 		baseQuery = baseQuery.Where(sq.Eq{"mime_type": params.MimeType})
 	}
 
+	if params.Author != "" {
+		baseQuery = baseQuery.Where("metadata->>'author' ILIKE ?", containsPattern(params.Author))
+	}
+	if params.PagesGt != nil {
+		baseQuery = baseQuery.Where("(metadata->>'page_count')::int > ?", *params.PagesGt)
+	}
+	if params.PagesLt != nil {
+		baseQuery = baseQuery.Where("(metadata->>'page_count')::int < ?", *params.PagesLt)
+	}
+
 	// TODO: unify orderby with handler somehow, sql injection possible
 	// TODO: refactor repetition in ItemsRepo.List
 	filesQuery := baseQuery.
@@ -162,6 +180,10 @@ func (r *FileRepo) UpdateTx(
 	sql, args, err := r.queryBuilder.
 		Update("files").
 		Set("text_content", file.TextContent).
+		Set("metadata", file.Metadata).
+		Set("text_source", file.TextSource).
+		Set("ocr_confidence", file.OcrConfidence).
+		Set("error_message", file.ErrorMessage).
 		Set("status", file.Status).
 		Set("updated_at", "now()").
 		Where(sq.Eq{"id": file.ID}).
@@ -175,6 +197,73 @@ func (r *FileRepo) UpdateTx(
 	return toRepositoryError(err)
 }
 
+// Drops everything the worker extracted and puts the file back in the
+// queue state
+func (r *FileRepo) ResetContentTx(ctx context.Context, tx *sqlx.Tx, fileID string) error {
+	sql, args, err := r.queryBuilder.
+		Update("files").
+		Set("text_content", nil).
+		Set("metadata", nil).
+		Set("text_source", nil).
+		Set("ocr_confidence", nil).
+		Set("error_message", nil).
+		Set("status", domain.FileStatusUploading).
+		Set("updated_at", "now()").
+		Where(sq.Eq{"id": fileID}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Returns IDs and owners of up to limit matching files ordered by ID, after
+// the given one when it's set. Files queued or being processed are skipped
+func (r *FileRepo) ListForReprocess(
+	ctx context.Context,
+	params domain.ReprocessFileFilter,
+	afterID string,
+	limit uint64,
+) ([]domain.File, error) {
+	query := r.queryBuilder.
+		Select("id", "user_id").
+		From("files").
+		Where(sq.Eq{"deleted_at": nil}).
+		Where(sq.NotEq{"status": []domain.FileStatus{domain.FileStatusUploading, domain.FileStatusProcessing}}).
+		OrderBy("id").
+		Limit(limit)
+
+	if params.UserID != "" {
+		query = query.Where(sq.Eq{"user_id": params.UserID})
+	}
+	if params.Status != "" {
+		query = query.Where(sq.Eq{"status": params.Status})
+	}
+	if params.MimeType != "" {
+		query = query.Where(sq.Eq{"mime_type": params.MimeType})
+	}
+	if params.CreatedFrom != nil {
+		query = query.Where(sq.GtOrEq{"created_at": *params.CreatedFrom})
+	}
+	if params.CreatedTo != nil {
+		query = query.Where(sq.Lt{"created_at": *params.CreatedTo})
+	}
+	if afterID != "" {
+		query = query.Where(sq.Gt{"id": afterID})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var files []domain.File
+	err = r.db.SelectContext(ctx, &files, sql, args...)
+	return files, toRepositoryError(err)
+}
+
 func (r *FileRepo) SoftDeleteByIDTx(ctx context.Context, tx *sqlx.Tx, fileID string) error {
 	sql, args, err := r.queryBuilder.
 		Update("files").
@@ -202,3 +291,28 @@ func (r *FileRepo) RestoreByIDTx(ctx context.Context, tx *sqlx.Tx, fileID string
 	_, err = tx.ExecContext(ctx, sql, args...)
 	return toRepositoryError(err)
 }
+
+// Returns S3 keys of all user's files and their previews, soft deleted
+// files included
+func (r *FileRepo) ListS3KeysByUser(ctx context.Context, userID string) ([]string, error) {
+	// nested builders must keep ? placeholders, the outer one numbers them
+	previewKeys := sq.
+		Select("p.s3_key").
+		From("file_previews p").
+		Join("files f ON f.id = p.file_id").
+		Where(sq.Eq{"f.user_id": userID})
+
+	sql, args, err := r.queryBuilder.
+		Select("s3_key").
+		From("files").
+		Where(sq.Eq{"user_id": userID}).
+		SuffixExpr(sq.ConcatExpr("UNION ALL ", previewKeys)).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var keys []string
+	err = r.db.SelectContext(ctx, &keys, sql, args...)
+	return keys, toRepositoryError(err)
+}
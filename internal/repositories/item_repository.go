@@ -38,6 +38,19 @@ func (r *ItemRepo) CreateNew(ctx context.Context, item *domain.Item) error {
 	return toRepositoryError(err)
 }
 
+func (r *ItemRepo) CreateNewTx(ctx context.Context, tx *sqlx.Tx, item *domain.Item) error {
+	sql, args, err := r.queryBuilder.
+		Insert("items").Columns("user_id", "type", "title", "content").
+		Values(item.UserID, item.Type, item.Title, item.Content).
+		Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = tx.QueryRowxContext(ctx, sql, args...).StructScan(item)
+	return toRepositoryError(err)
+}
+
 func (r *ItemRepo) List(ctx context.Context, f domain.ListItemFilter) ([]domain.Item, int, error) {
 	var items []domain.Item
 	var count int
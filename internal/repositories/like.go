@@ -0,0 +1,11 @@
+package repositories
+
+import "strings"
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// LIKE pattern matching query anywhere in the value, wildcards typed by
+// users are matched literally
+func containsPattern(query string) string {
+	return "%" + likeEscaper.Replace(query) + "%"
+}
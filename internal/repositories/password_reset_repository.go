@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type PasswordResetRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewPasswordResetRepo(db *sqlx.DB) *PasswordResetRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &PasswordResetRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+func (r *PasswordResetRepo) CreateNew(ctx context.Context, token *domain.PasswordResetToken) error {
+	sql, args, err := r.queryBuilder.
+		Insert("password_reset_tokens").
+		Columns("user_id", "token_hash", "expires_at").
+		Values(token.UserID, token.TokenHash, token.ExpiresAt).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(token)
+	return toRepositoryError(err)
+}
+
+// Marks a valid token as used and returns it
+func (r *PasswordResetRepo) ConsumeTx(ctx context.Context, tx *sqlx.Tx, tokenHash string) (*domain.PasswordResetToken, error) {
+	sql, args, err := r.queryBuilder.
+		Update("password_reset_tokens").
+		Set("used_at", time.Now()).
+		Where(sq.Eq{"token_hash": tokenHash, "used_at": nil}).
+		Where("expires_at > now()").
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var token domain.PasswordResetToken
+	err = tx.GetContext(ctx, &token, sql, args...)
+	return &token, toRepositoryError(err)
+}
+
+// Marks all unused tokens of the user as used
+func (r *PasswordResetRepo) InvalidateForUserTx(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	sql, args, err := r.queryBuilder.
+		Update("password_reset_tokens").
+		Set("used_at", time.Now()).
+		Where(sq.Eq{"user_id": userID, "used_at": nil}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
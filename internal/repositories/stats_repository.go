@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+type StatsRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewStatsRepo(db *sqlx.DB) *StatsRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &StatsRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+// Counts over all users, soft deleted rows are left out
+func (r *StatsRepo) GetTotals(ctx context.Context) (*domain.Totals, error) {
+	sql, args, err := r.queryBuilder.
+		Select(
+			"(SELECT COUNT(*) FROM items WHERE deleted_at IS NULL) AS item_count",
+			"(SELECT COUNT(*) FROM tags) AS tag_count",
+		).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var totals domain.Totals
+	if err := r.db.GetContext(ctx, &totals, sql, args...); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	sql, args, err = r.queryBuilder.
+		Select("status", "COUNT(*) AS count").
+		From("files").
+		Where(sq.Eq{"deleted_at": nil}).
+		GroupBy("status").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var rows []struct {
+		Status domain.FileStatus `db:"status"`
+		Count  int               `db:"count"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, sql, args...); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	totals.FilesByStatus = make(map[domain.FileStatus]int, len(rows))
+	for _, row := range rows {
+		totals.FilesByStatus[row.Status] = row.Count
+	}
+
+	return &totals, nil
+}
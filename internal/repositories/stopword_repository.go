@@ -7,6 +7,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type StopwordRepo struct {
@@ -68,7 +69,7 @@ func (r *StopwordRepo) GetActiveStopwords(
 		OrderBy(fmt.Sprintf("%s %s", params.Column, params.Direction))
 
 	if params.Query != "" {
-		query = query.Where("word LIKE ?", "%"+params.Query+"%")
+		query = query.Where("word LIKE ?", containsPattern(params.Query))
 	}
 	if params.Source != "" {
 		query = query.Where(sq.Eq{"source": params.Source})
@@ -192,11 +193,13 @@ func (r *StopwordRepo) DeleteTx(
 	return toRepositoryError(err)
 }
 
-func (r *StopwordRepo) IsDefaultTx(ctx context.Context, tx *sqlx.Tx, word string) (bool, error) {
+// Reports whether the word comes from one of the packs enabled for the user
+func (r *StopwordRepo) IsDefaultTx(ctx context.Context, tx *sqlx.Tx, word, userID string) (bool, error) {
 	query, args, err := r.queryBuilder.
 		Select("COUNT(*)").
 		From("stopwords_default").
 		Where(sq.Eq{"word": word}).
+		Where("word IN (SELECT word FROM active_default_stopwords(?))", userID).
 		ToSql()
 	if err != nil {
 		return false, toRepositoryError(err)
@@ -206,3 +209,169 @@ func (r *StopwordRepo) IsDefaultTx(ctx context.Context, tx *sqlx.Tx, word string
 	err = tx.GetContext(ctx, &count, query, args...)
 	return count > 0, toRepositoryError(err)
 }
+
+// Drops overrides of given words which come from enabled packs, which turns them back on
+func (r *StopwordRepo) DeleteDefaultOverridesTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+	words []string,
+) error {
+	sql, args, err := r.queryBuilder.
+		Delete("stopwords").
+		Where(sq.Eq{"user_id": userID}).
+		Where("word = ANY(?)", pq.StringArray(words)).
+		Where("word IN (SELECT word FROM active_default_stopwords(?))", userID).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Adds given words which don't come from enabled packs as enabled user stopwords
+func (r *StopwordRepo) UpsertManyEnabledTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	userID string,
+	words []string,
+) (int64, error) {
+	wordsQuery := r.queryBuilder.
+		Select().
+		Column(sq.Expr("unnest(?::text[]) AS word", pq.StringArray(words)))
+
+	selectQuery := r.queryBuilder.
+		Select().
+		Column(sq.Expr("?::uuid", userID)).
+		Column("w.word").
+		Column(sq.Expr("?::stopword_source", domain.StopwordSourceUser)).
+		Column("true").
+		FromSelect(wordsQuery, "w").
+		Where("w.word NOT IN (SELECT word FROM active_default_stopwords(?))", userID)
+
+	sql, args, err := r.queryBuilder.
+		Insert("stopwords").
+		Columns("user_id", "word", "source", "is_enabled").
+		Select(selectQuery).
+		Suffix("ON CONFLICT (user_id, word) DO UPDATE SET source = EXCLUDED.source, is_enabled = true, updated_at = now()").
+		ToSql()
+	if err != nil {
+		return 0, toRepositoryError(err)
+	}
+
+	res, err := tx.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return 0, toRepositoryError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	return affected, toRepositoryError(err)
+}
+
+func (r *StopwordRepo) ListPacks(ctx context.Context, userID string) ([]domain.StopwordPack, error) {
+	sql, args, err := r.queryBuilder.
+		Select(
+			"sp.lang",
+			"sp.name",
+			"COALESCE(usp.is_enabled, sp.enabled_by_default) AS is_enabled",
+			"(SELECT COUNT(*) FROM stopwords_default sd WHERE sd.lang = sp.lang) AS word_count",
+		).
+		From("stopword_packs sp").
+		LeftJoin("user_stopword_packs usp ON usp.lang = sp.lang AND usp.user_id = ?", userID).
+		OrderBy("sp.lang ASC").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var packs []domain.StopwordPack
+	err = r.db.SelectContext(ctx, &packs, sql, args...)
+	return packs, toRepositoryError(err)
+}
+
+func (r *StopwordRepo) PackExists(ctx context.Context, lang string) (bool, error) {
+	sql, args, err := r.queryBuilder.
+		Select("COUNT(*)").
+		From("stopword_packs").
+		Where(sq.Eq{"lang": lang}).
+		ToSql()
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	var count int
+	err = r.db.GetContext(ctx, &count, sql, args...)
+	return count > 0, toRepositoryError(err)
+}
+
+func (r *StopwordRepo) SetPackEnabled(ctx context.Context, userID, lang string, isEnabled bool) error {
+	sql, args, err := r.queryBuilder.
+		Insert("user_stopword_packs").
+		Columns("user_id", "lang", "is_enabled").
+		Values(userID, lang, isEnabled).
+		Suffix("ON CONFLICT (user_id, lang) DO UPDATE SET is_enabled = EXCLUDED.is_enabled, updated_at = now()").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *StopwordRepo) ListDefault(ctx context.Context, lang, query string) ([]domain.DefaultStopword, error) {
+	q := r.queryBuilder.
+		Select("lang", "word").
+		From("stopwords_default").
+		OrderBy("lang ASC", "word ASC")
+
+	if lang != "" {
+		q = q.Where(sq.Eq{"lang": lang})
+	}
+	if query != "" {
+		q = q.Where("word LIKE ?", containsPattern(query))
+	}
+
+	sql, args, err := q.ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var stopwords []domain.DefaultStopword
+	err = r.db.SelectContext(ctx, &stopwords, sql, args...)
+	return stopwords, toRepositoryError(err)
+}
+
+func (r *StopwordRepo) CreateDefault(ctx context.Context, stopword *domain.DefaultStopword) error {
+	sql, args, err := r.queryBuilder.
+		Insert("stopwords_default").
+		Columns("lang", "word").
+		Values(stopword.Lang, stopword.Word).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *StopwordRepo) DeleteDefault(ctx context.Context, lang, word string) (bool, error) {
+	sql, args, err := r.queryBuilder.
+		Delete("stopwords_default").
+		Where(sq.Eq{"lang": lang, "word": word}).
+		ToSql()
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	res, err := r.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, toRepositoryError(err)
+}
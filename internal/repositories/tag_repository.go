@@ -8,11 +8,16 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"golang.org/x/sync/errgroup"
 )
 
 type ItemTagsByID map[string][]domain.Tag
 
+type FileTagsByID map[string][]domain.Tag
+
+type TagCoOccurrencesByID map[string][]domain.TagCoOccurrence
+
 type TagRepo struct {
 	db           *sqlx.DB
 	queryBuilder sq.StatementBuilderType
@@ -53,7 +58,7 @@ func (r *TagRepo) List(
 		Where(sq.Eq{"user_id": params.UserID})
 
 	if params.Query != "" {
-		baseQuery = baseQuery.Where("name LIKE ?", "%"+params.Query+"%")
+		baseQuery = baseQuery.Where("name LIKE ?", containsPattern(params.Query))
 	}
 
 	tagsSql, tagsArgs, err := baseQuery.
@@ -227,3 +232,400 @@ func (r *TagRepo) FindByItemIDs(
 
 	return result, nil
 }
+
+func (r *TagRepo) FindByFileIDs(
+	ctx context.Context,
+	fileIDs []string,
+) (FileTagsByID, error) {
+	if len(fileIDs) == 0 {
+		return FileTagsByID{}, nil
+	}
+
+	sql, args, err := r.queryBuilder.
+		Select("t.id", "t.name", "t.user_id", "t.created_at", "t.updated_at", "ft.file_id").
+		From("tags t").
+		Join("file_tags ft ON ft.tag_id = t.id").
+		Where(sq.Eq{"ft.file_id": fileIDs}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+	defer rows.Close()
+
+	result := make(FileTagsByID)
+	for rows.Next() {
+		var tag domain.Tag
+		var fileID string
+		err := rows.Scan(
+			&tag.ID,
+			&tag.Name,
+			&tag.UserID,
+			&tag.CreatedAt,
+			&tag.UpdatedAt,
+			&fileID,
+		)
+		if err != nil {
+			return nil, toRepositoryError(err)
+		}
+		result[fileID] = append(result[fileID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	return result, nil
+}
+
+// Finds user's tags by lowercased words, aliases take precedence over
+// tag names like in auto-tagging
+func (r *TagRepo) ResolveNames(ctx context.Context, userID string, words []string) (map[string]domain.Tag, error) {
+	if len(words) == 0 {
+		return map[string]domain.Tag{}, nil
+	}
+
+	aliases := sq.
+		Select("ta.alias AS word", "1 AS priority", "t.*").
+		From("tag_aliases ta").
+		Join("tags t ON t.id = ta.tag_id").
+		Where(sq.Eq{"ta.user_id": userID}).
+		Where("ta.alias = ANY(?)", pq.Array(words))
+
+	sql, args, err := r.queryBuilder.
+		Select("lower(t.name) AS word", "0 AS priority", "t.*").
+		From("tags t").
+		Where(sq.Eq{"t.user_id": userID}).
+		Where("lower(t.name) = ANY(?)", pq.Array(words)).
+		SuffixExpr(sq.ConcatExpr("UNION ALL ", aliases, " ORDER BY priority")).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var rows []struct {
+		Word     string `db:"word"`
+		Priority int    `db:"priority"`
+		domain.Tag
+	}
+	if err := r.db.SelectContext(ctx, &rows, sql, args...); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	result := make(map[string]domain.Tag, len(rows))
+	for _, row := range rows {
+		result[row.Word] = row.Tag
+	}
+	return result, nil
+}
+
+// Moves every item binding from source tags onto the target tag. When an
+// item was bound to several of them manual wins, then auto, then rule
+func (r *TagRepo) RebindItemsTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	targetID string,
+	sourceIDs []string,
+) error {
+	return r.rebindTx(ctx, tx, "item_tags", "item_id", targetID, sourceIDs)
+}
+
+// Same as RebindItemsTx but for file bindings
+func (r *TagRepo) RebindFilesTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	targetID string,
+	sourceIDs []string,
+) error {
+	return r.rebindTx(ctx, tx, "file_tags", "file_id", targetID, sourceIDs)
+}
+
+// Rule bindings are dropped whenever rules are re-evaluated, so an auto
+// binding must not be folded into a rule one or it would be lost
+const mergedTagSource = "CASE WHEN bool_or(source = 'manual') THEN 'manual'::tag_source" +
+	" WHEN bool_or(source = 'auto') THEN 'auto'::tag_source" +
+	" ELSE 'rule'::tag_source END"
+
+func (r *TagRepo) rebindTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	bindTable, bindColumn string,
+	targetID string,
+	sourceIDs []string,
+) error {
+	selectQuery := r.queryBuilder.
+		Select(bindColumn).
+		Column(sq.Expr("?::uuid", targetID)).
+		Column(mergedTagSource).
+		From(bindTable).
+		Where(sq.Eq{"tag_id": sourceIDs}).
+		GroupBy(bindColumn)
+
+	sql, args, err := r.queryBuilder.
+		Insert(bindTable).
+		Columns(bindColumn, "tag_id", "source").
+		Select(selectQuery).
+		Suffix("ON CONFLICT (" + bindColumn + ", tag_id) DO UPDATE SET source = CASE" +
+			" WHEN " + bindTable + ".source = 'rule' THEN EXCLUDED.source" +
+			" WHEN EXCLUDED.source = 'manual' THEN EXCLUDED.source" +
+			" ELSE " + bindTable + ".source END").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) RepointRulesTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	targetID string,
+	sourceIDs []string,
+) error {
+	sql, args, err := r.queryBuilder.
+		Update("tag_rules").
+		Set("tag_id", targetID).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"tag_id": sourceIDs}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) DeleteByIDsTx(ctx context.Context, tx *sqlx.Tx, tagIDs []string) error {
+	sql, args, err := r.queryBuilder.
+		Delete("tags").
+		Where(sq.Eq{"id": tagIDs}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) CreateAlias(ctx context.Context, alias *domain.TagAlias) error {
+	sql, args, err := r.queryBuilder.
+		Insert("tag_aliases").
+		Columns("alias", "user_id", "tag_id").
+		Values(alias.Alias, alias.UserID, alias.TagID).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(alias)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) UpsertAliasTx(ctx context.Context, tx *sqlx.Tx, alias *domain.TagAlias) error {
+	sql, args, err := r.queryBuilder.
+		Insert("tag_aliases").
+		Columns("alias", "user_id", "tag_id").
+		Values(alias.Alias, alias.UserID, alias.TagID).
+		Suffix("ON CONFLICT (user_id, alias) DO UPDATE SET tag_id = EXCLUDED.tag_id").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) RepointAliasesTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	targetID string,
+	sourceIDs []string,
+) error {
+	sql, args, err := r.queryBuilder.
+		Update("tag_aliases").
+		Set("tag_id", targetID).
+		Where(sq.Eq{"tag_id": sourceIDs}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRepo) ListAliases(ctx context.Context, tagID string) ([]domain.TagAlias, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("tag_aliases").
+		Where(sq.Eq{"tag_id": tagID}).
+		OrderBy("alias ASC").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var aliases []domain.TagAlias
+	err = r.db.SelectContext(ctx, &aliases, sql, args...)
+	return aliases, toRepositoryError(err)
+}
+
+func (r *TagRepo) DeleteAlias(ctx context.Context, tagID, alias string) (bool, error) {
+	sql, args, err := r.queryBuilder.
+		Delete("tag_aliases").
+		Where(sq.Eq{"tag_id": tagID}).
+		Where(sq.Eq{"alias": alias}).
+		ToSql()
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	res, err := r.db.ExecContext(ctx, sql, args...)
+	if err != nil {
+		return false, toRepositoryError(err)
+	}
+
+	affected, err := res.RowsAffected()
+	return affected > 0, toRepositoryError(err)
+}
+
+func (r *TagRepo) Stats(
+	ctx context.Context,
+	params domain.TagStatsFilter,
+) ([]domain.TagStats, int, error) {
+	offset := uint64(params.PageSize * (params.Page - 1))
+	baseQuery := r.queryBuilder.
+		Select(
+			"t.*",
+			"COUNT(i.id) AS item_count",
+			"COUNT(i.id) FILTER (WHERE it.source = 'auto') AS auto_count",
+			"COUNT(i.id) FILTER (WHERE it.source = 'rule') AS rule_count",
+			"COUNT(i.id) FILTER (WHERE it.source = 'manual') AS manual_count",
+			"MAX(it.created_at) FILTER (WHERE i.id IS NOT NULL) AS last_used_at",
+		).
+		From("tags t").
+		LeftJoin("item_tags it ON it.tag_id = t.id").
+		LeftJoin("items i ON i.id = it.item_id AND i.deleted_at IS NULL").
+		Where(sq.Eq{"t.user_id": params.UserID}).
+		GroupBy("t.id")
+
+	if params.Query != "" {
+		baseQuery = baseQuery.Where("t.name LIKE ?", containsPattern(params.Query))
+	}
+
+	if params.MinCount > 0 {
+		baseQuery = baseQuery.Having("COUNT(i.id) >= ?", params.MinCount)
+	}
+
+	statsSql, statsArgs, err := baseQuery.
+		OrderBy(fmt.Sprintf("%s %s NULLS LAST", params.Column, params.Direction), "t.name ASC").
+		Offset(offset).
+		Limit(uint64(params.PageSize)).
+		ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	countSql, countArgs, err := r.queryBuilder.
+		Select("COUNT(*)").
+		FromSelect(baseQuery, "s").
+		ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	g, _ := errgroup.WithContext(ctx)
+
+	var stats []domain.TagStats
+	g.Go(func() error {
+		if err := r.db.SelectContext(ctx, &stats, statsSql, statsArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	var count int
+	g.Go(func() error {
+		if err := r.db.GetContext(ctx, &count, countSql, countArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	_ = g.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, 0, toRepositoryError(cause)
+	}
+
+	return stats, count, nil
+}
+
+// Returns up to limit most frequent tags sharing active items with each of given tags
+func (r *TagRepo) FindCoOccurrences(
+	ctx context.Context,
+	tagIDs []string,
+	limit int,
+) (TagCoOccurrencesByID, error) {
+	if len(tagIDs) == 0 || limit <= 0 {
+		return TagCoOccurrencesByID{}, nil
+	}
+
+	pairsQuery := r.queryBuilder.
+		Select(
+			"a.tag_id",
+			"b.tag_id AS co_tag_id",
+			"t.name",
+			"COUNT(*) AS count",
+			"ROW_NUMBER() OVER (PARTITION BY a.tag_id ORDER BY COUNT(*) DESC, t.name ASC) AS rank",
+		).
+		From("item_tags a").
+		Join("item_tags b ON b.item_id = a.item_id AND b.tag_id <> a.tag_id").
+		Join("items i ON i.id = a.item_id AND i.deleted_at IS NULL").
+		Join("tags t ON t.id = b.tag_id").
+		Where(sq.Eq{"a.tag_id": tagIDs}).
+		GroupBy("a.tag_id", "b.tag_id", "t.name")
+
+	sql, args, err := r.queryBuilder.
+		Select("tag_id", "co_tag_id", "name", "count").
+		FromSelect(pairsQuery, "p").
+		Where(sq.LtOrEq{"rank": limit}).
+		OrderBy("tag_id", "rank").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+	defer rows.Close()
+
+	result := make(TagCoOccurrencesByID)
+	for rows.Next() {
+		var co domain.TagCoOccurrence
+		var tagID string
+		if err := rows.Scan(&tagID, &co.TagID, &co.Name, &co.Count); err != nil {
+			return nil, toRepositoryError(err)
+		}
+		result[tagID] = append(result[tagID], co)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	return result, nil
+}
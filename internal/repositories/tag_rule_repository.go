@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
+)
+
+type TagRuleRepo struct {
+	db           *sqlx.DB
+	queryBuilder sq.StatementBuilderType
+}
+
+func NewTagRuleRepo(db *sqlx.DB) *TagRuleRepo {
+	builder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+	return &TagRuleRepo{
+		db:           db,
+		queryBuilder: builder,
+	}
+}
+
+func (r *TagRuleRepo) CreateNew(ctx context.Context, rule *domain.TagRule) error {
+	sql, args, err := r.queryBuilder.
+		Insert("tag_rules").
+		Columns("user_id", "tag_id", "name", "kind", "field", "pattern", "keywords", "is_enabled").
+		Values(rule.UserID, rule.TagID, rule.Name, rule.Kind, rule.Field, rule.Pattern, rule.Keywords, rule.IsEnabled).
+		Suffix("RETURNING *").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(rule)
+	return toRepositoryError(err)
+}
+
+func (r *TagRuleRepo) List(ctx context.Context, params domain.ListTagRuleFilter) ([]domain.TagRule, error) {
+	query := r.queryBuilder.
+		Select("*").
+		From("tag_rules").
+		Where(sq.Eq{"user_id": params.UserID}).
+		OrderBy("created_at ASC")
+
+	if params.TagID != "" {
+		query = query.Where(sq.Eq{"tag_id": params.TagID})
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var rules []domain.TagRule
+	err = r.db.SelectContext(ctx, &rules, sql, args...)
+	return rules, toRepositoryError(err)
+}
+
+func (r *TagRuleRepo) GetByID(ctx context.Context, ruleID string) (*domain.TagRule, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("tag_rules").
+		Where(sq.Eq{"id": ruleID}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var rule domain.TagRule
+	err = r.db.GetContext(ctx, &rule, sql, args...)
+	return &rule, toRepositoryError(err)
+}
+
+func (r *TagRuleRepo) GetByIDForUpdate(ctx context.Context, tx *sqlx.Tx, ruleID string) (*domain.TagRule, error) {
+	sql, args, err := r.queryBuilder.
+		Select("*").
+		From("tag_rules").
+		Where(sq.Eq{"id": ruleID}).
+		Suffix("FOR UPDATE").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var rule domain.TagRule
+	err = tx.GetContext(ctx, &rule, sql, args...)
+	return &rule, toRepositoryError(err)
+}
+
+func (r *TagRuleRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, rule *domain.TagRule) error {
+	sql, args, err := r.queryBuilder.
+		Update("tag_rules").
+		Set("tag_id", rule.TagID).
+		Set("name", rule.Name).
+		Set("kind", rule.Kind).
+		Set("field", rule.Field).
+		Set("pattern", rule.Pattern).
+		Set("keywords", rule.Keywords).
+		Set("is_enabled", rule.IsEnabled).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": rule.ID}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *TagRuleRepo) DeleteByID(ctx context.Context, ruleID string) error {
+	sql, args, err := r.queryBuilder.
+		Delete("tag_rules").
+		Where(sq.Eq{"id": ruleID}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Lets Postgres compile the pattern so rules are checked by the same engine that runs them
+func (r *TagRuleRepo) ValidatePattern(ctx context.Context, pattern string) error {
+	var matches bool
+	err := r.db.GetContext(ctx, &matches, "SELECT '' ~ $1", pattern)
+	return toRepositoryError(err)
+}
+
+// Re-evaluates enabled rules of the item owner, replacing previous rule bindings
+func (r *TagRuleRepo) ApplyToItemTx(ctx context.Context, tx *sqlx.Tx, itemID string) error {
+	return r.applyTx(ctx, tx, "item_tags", "item_id", "items", "title", "content", itemID)
+}
+
+// Re-evaluates enabled rules of the file owner, replacing previous rule bindings
+func (r *TagRuleRepo) ApplyToFileTx(ctx context.Context, tx *sqlx.Tx, fileID string) error {
+	return r.applyTx(ctx, tx, "file_tags", "file_id", "files", "original_name", "text_content", fileID)
+}
+
+func (r *TagRuleRepo) applyTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	bindTable, bindColumn, subjectTable, titleColumn, contentColumn string,
+	subjectID string,
+) error {
+	deleteSql, deleteArgs, err := r.queryBuilder.
+		Delete(bindTable).
+		Where(sq.Eq{bindColumn: subjectID}).
+		Where(sq.Eq{"source": domain.TagSourceRule}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteSql, deleteArgs...); err != nil {
+		return toRepositoryError(err)
+	}
+
+	matchesQuery := r.queryBuilder.
+		Select("s.id", "r.tag_id", "'rule'::tag_source").
+		From(subjectTable + " s").
+		Join("tag_rules r ON r.user_id = s.user_id AND r.is_enabled").
+		Where(sq.Eq{"s.id": subjectID}).
+		Where("tag_rule_matches(r.kind, r.pattern, r.keywords, tag_rule_subject(r.field, s." + titleColumn + ", s." + contentColumn + "))")
+
+	insertSql, insertArgs, err := r.queryBuilder.
+		Insert(bindTable).
+		Columns(bindColumn, "tag_id", "source").
+		Select(matchesQuery).
+		// Rule rows are gone by now, so a conflict is an auto or manual
+		// binding. Upgrading auto to rule would get it deleted on the next
+		// run and auto-tagging never puts it back
+		Suffix("ON CONFLICT (" + bindColumn + ", tag_id) DO NOTHING").
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, insertSql, insertArgs...)
+	return toRepositoryError(err)
+}
+
+// Returns active items the given rule definition would bind its tag to
+func (r *TagRuleRepo) DryRun(
+	ctx context.Context,
+	params domain.TagRuleDryRunFilter,
+) ([]domain.Item, int, error) {
+	offset := uint64(params.PageSize * (params.Page - 1))
+	baseQuery := r.queryBuilder.
+		Select().
+		From("items i").
+		Where(sq.Eq{"i.user_id": params.UserID}).
+		Where(sq.Eq{"i.deleted_at": nil}).
+		Where(
+			"tag_rule_matches(?::tag_rule_kind, ?, ?::text[], tag_rule_subject(?::tag_rule_field, i.title, i.content))",
+			params.Kind, params.Pattern, pq.StringArray(params.Keywords), params.Field,
+		)
+
+	itemsSql, itemsArgs, err := baseQuery.
+		Columns("i.*").
+		OrderBy("i.updated_at DESC").
+		Offset(offset).
+		Limit(uint64(params.PageSize)).
+		ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	countSql, countArgs, err := baseQuery.Columns("COUNT(*)").ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	g, _ := errgroup.WithContext(ctx)
+
+	var items []domain.Item
+	g.Go(func() error {
+		if err := r.db.SelectContext(ctx, &items, itemsSql, itemsArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	var count int
+	g.Go(func() error {
+		if err := r.db.GetContext(ctx, &count, countSql, countArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	_ = g.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, 0, toRepositoryError(cause)
+	}
+
+	return items, count, nil
+}
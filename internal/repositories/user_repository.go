@@ -2,17 +2,19 @@ package repositories
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"qvarkk/kvault/internal/domain"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
-	UserFieldID     = "id"
-	UserFieldEmail  = "email"
-	UserFieldApiKey = "api_key"
+	UserFieldID    = "id"
+	UserFieldEmail = "email"
 )
 
 type UserRepo struct {
@@ -29,55 +31,127 @@ func NewUserRepo(db *sqlx.DB) *UserRepo {
 	}
 }
 
-func (r *UserRepo) CreateNew(ctx context.Context, user *domain.User) error {
+func (r *UserRepo) CreateNewTx(ctx context.Context, tx *sqlx.Tx, user *domain.User) error {
 	sql, args, err := r.queryBuilder.
-		Insert("users").Columns("email", "password", "api_key").
-		Values(user.Email, user.Password, user.APIKey).
+		Insert("users").Columns("email", "password").
+		Values(user.Email, user.Password).
 		Suffix("RETURNING *").ToSql()
 	if err != nil {
 		return toRepositoryError(err)
 	}
 
-	err = r.db.QueryRowxContext(ctx, sql, args...).StructScan(user)
+	err = tx.QueryRowxContext(ctx, sql, args...).StructScan(user)
 	return toRepositoryError(err)
 }
 
-func (r *UserRepo) IsApiKeyUnique(ctx context.Context, apiKey string) (bool, error) {
-	sql, args, err := r.queryBuilder.
-		Select("1").From("users").
-		Where(sq.Eq{"api_key": apiKey}).
-		Limit(1).ToSql()
+func (r *UserRepo) GetByID(ctx context.Context, userID string) (*domain.User, error) {
+	return r.getByField(ctx, UserFieldID, userID)
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.getByField(ctx, UserFieldEmail, email)
+}
+
+func (r *UserRepo) List(ctx context.Context, params domain.ListUserFilter) ([]domain.User, int, error) {
+	offset := uint64(params.PageSize * (params.Page - 1))
+	baseQuery := r.queryBuilder.
+		Select().
+		From("users")
+
+	if params.Query != "" {
+		baseQuery = baseQuery.Where("email ILIKE ?", containsPattern(params.Query))
+	}
+	if params.Role != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"role": params.Role})
+	}
+	if params.Disabled != nil {
+		if *params.Disabled {
+			baseQuery = baseQuery.Where(sq.NotEq{"disabled_at": nil})
+		} else {
+			baseQuery = baseQuery.Where(sq.Eq{"disabled_at": nil})
+		}
+	}
+
+	// TODO: orderby injection, same as in other lists
+	usersSql, usersArgs, err := baseQuery.
+		Columns("*").
+		OrderBy(fmt.Sprintf("%s %s", params.Column, params.Direction)).
+		Offset(offset).
+		Limit(uint64(params.PageSize)).
+		ToSql()
 	if err != nil {
-		return false, toRepositoryError(err)
+		return nil, 0, toRepositoryError(err)
 	}
 
-	var exists bool
-	err = r.db.Get(&exists, sql, args...)
-	err = toRepositoryError(err)
-	if errors.Is(err, ErrNotFound) {
-		return true, nil
+	countSql, countArgs, err := baseQuery.Columns("COUNT(*)").ToSql()
+	if err != nil {
+		return nil, 0, toRepositoryError(err)
 	}
 
-	return false, err
-}
+	ctx, cancel := context.WithCancelCause(ctx)
+	g, _ := errgroup.WithContext(ctx)
 
-func (r *UserRepo) GetByID(ctx context.Context, userID string) (*domain.User, error) {
-	return r.getByField(ctx, UserFieldID, userID)
-}
+	var users []domain.User
+	g.Go(func() error {
+		if err := r.db.SelectContext(ctx, &users, usersSql, usersArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
 
-func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
-	return r.getByField(ctx, UserFieldEmail, email)
+	var count int
+	g.Go(func() error {
+		if err := r.db.GetContext(ctx, &count, countSql, countArgs...); err != nil {
+			cancel(err)
+			return err
+		}
+		return nil
+	})
+
+	_ = g.Wait()
+
+	if cause := context.Cause(ctx); cause != nil {
+		return nil, 0, toRepositoryError(cause)
+	}
+
+	return users, count, nil
 }
 
-func (r *UserRepo) GetByApiKey(ctx context.Context, apiKey string) (*domain.User, error) {
-	return r.getByField(ctx, UserFieldApiKey, apiKey)
+// Sets or clears disabled_at and returns updated user
+func (r *UserRepo) SetDisabled(ctx context.Context, userID string, isDisabled bool) (*domain.User, error) {
+	query := r.queryBuilder.
+		Update("users").
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": userID})
+
+	if isDisabled {
+		query = query.Set("disabled_at", sq.Expr("COALESCE(disabled_at, now())"))
+	} else {
+		query = query.Set("disabled_at", nil)
+	}
+
+	sql, args, err := query.Suffix("RETURNING *").ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var user domain.User
+	err = r.db.GetContext(ctx, &user, sql, args...)
+	return &user, toRepositoryError(err)
 }
 
-// Updates API key and returns updated user
-func (r *UserRepo) UpdateApiKey(ctx context.Context, userID string, apiKey string) (*domain.User, error) {
+// Replaces all quota overrides of the user, nil values reset them to defaults
+func (r *UserRepo) UpdateQuota(ctx context.Context, userID string, override domain.UserQuotaOverride) (*domain.User, error) {
 	sql, args, err := r.queryBuilder.
-		Update("users").Set("api_key", apiKey).Set("updated_at", "now()").
-		Where(sq.Eq{"id": userID}).Suffix("RETURNING *").ToSql()
+		Update("users").
+		Set("max_storage_bytes", override.MaxStorageBytes).
+		Set("max_file_bytes", override.MaxFileBytes).
+		Set("max_items", override.MaxItems).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING *").
+		ToSql()
 	if err != nil {
 		return nil, toRepositoryError(err)
 	}
@@ -87,6 +161,131 @@ func (r *UserRepo) UpdateApiKey(ctx context.Context, userID string, apiKey strin
 	return &user, toRepositoryError(err)
 }
 
+// Makes users with given emails admins and returns their IDs
+func (r *UserRepo) PromoteToAdminTx(ctx context.Context, tx *sqlx.Tx, emails []string) ([]string, error) {
+	sql, args, err := r.queryBuilder.
+		Update("users").
+		Set("role", domain.UserRoleAdmin).
+		Set("updated_at", time.Now()).
+		Where("email = ANY(?)", pq.Array(emails)).
+		Where(sq.NotEq{"role": domain.UserRoleAdmin}).
+		Suffix("RETURNING id").
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var userIDs []string
+	err = tx.SelectContext(ctx, &userIDs, sql, args...)
+	return userIDs, toRepositoryError(err)
+}
+
+// Soft deleted items and files count towards neither usage nor storage
+func (r *UserRepo) GetUsage(ctx context.Context, userID string) (*domain.UserUsage, error) {
+	sql, args, err := r.queryBuilder.
+		Select(
+			"u.id AS user_id",
+			"(SELECT COUNT(*) FROM items i WHERE i.user_id = u.id AND i.deleted_at IS NULL) AS item_count",
+			"(SELECT COUNT(*) FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS file_count",
+			"(SELECT COALESCE(SUM(f.size), 0) FROM files f WHERE f.user_id = u.id AND f.deleted_at IS NULL) AS storage_bytes",
+		).
+		From("users u").
+		Where(sq.Eq{"u.id": userID}).
+		ToSql()
+	if err != nil {
+		return nil, toRepositoryError(err)
+	}
+
+	var usage domain.UserUsage
+	err = r.db.GetContext(ctx, &usage, sql, args...)
+	return &usage, toRepositoryError(err)
+}
+
+func (r *UserRepo) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	sql, args, err := r.updatePasswordSql(userID, passwordHash)
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *UserRepo) UpdatePasswordTx(ctx context.Context, tx *sqlx.Tx, userID, passwordHash string) error {
+	sql, args, err := r.updatePasswordSql(userID, passwordHash)
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+func (r *UserRepo) updatePasswordSql(userID, passwordHash string) (string, []any, error) {
+	return r.queryBuilder.
+		Update("users").
+		Set("password", passwordHash).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+}
+
+// Disables the user and records the deletion request
+func (r *UserRepo) MarkForDeletion(ctx context.Context, userID string) error {
+	sql, args, err := r.queryBuilder.
+		Update("users").
+		Set("disabled_at", sq.Expr("COALESCE(disabled_at, now())")).
+		Set("deletion_requested_at", sq.Expr("COALESCE(deletion_requested_at, now())")).
+		Set("updated_at", time.Now()).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = r.db.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
+// Tables referencing users without ON DELETE CASCADE, in deletion order
+var userOwnedTables = []string{
+	"items",
+	"files",
+	"tag_rules",
+	"tag_aliases",
+	"tags",
+	"stopwords",
+	"user_stopword_packs",
+}
+
+// Removes the user with everything they own, API keys and reset tokens go by cascade
+func (r *UserRepo) HardDeleteTx(ctx context.Context, tx *sqlx.Tx, userID string) error {
+	for _, table := range userOwnedTables {
+		sql, args, err := r.queryBuilder.
+			Delete(table).
+			Where(sq.Eq{"user_id": userID}).
+			ToSql()
+		if err != nil {
+			return toRepositoryError(err)
+		}
+
+		if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+			return toRepositoryError(err)
+		}
+	}
+
+	sql, args, err := r.queryBuilder.
+		Delete("users").
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+	if err != nil {
+		return toRepositoryError(err)
+	}
+
+	_, err = tx.ExecContext(ctx, sql, args...)
+	return toRepositoryError(err)
+}
+
 func (r *UserRepo) getByField(ctx context.Context, field string, value string) (*domain.User, error) {
 	sql, args, err := r.queryBuilder.
 		Select("*").From("users").
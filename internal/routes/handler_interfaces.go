@@ -6,7 +6,43 @@ type AuthHandler interface {
 	RegisterUser(*gin.Context) error
 	AuthenticateUser(*gin.Context) error
 	GetAuthenticatedUser(*gin.Context) error
+	GetAuthenticatedUserUsage(*gin.Context) error
 	RotateApiKey(*gin.Context) error
+	ChangePassword(*gin.Context) error
+	ForgotPassword(*gin.Context) error
+	ResetPassword(*gin.Context) error
+	DeleteAuthenticatedUser(*gin.Context) error
+}
+
+type ApiKeyHandler interface {
+	Create(*gin.Context) error
+	List(*gin.Context) error
+	Revoke(*gin.Context) error
+	Rotate(*gin.Context) error
+}
+
+type AdminHandler interface {
+	ListUsers(*gin.Context) error
+	GetUser(*gin.Context) error
+	DisableUser(*gin.Context) error
+	EnableUser(*gin.Context) error
+	RotateUserKeys(*gin.Context) error
+	GetUserUsage(*gin.Context) error
+	SetUserQuota(*gin.Context) error
+	ReprocessFiles(*gin.Context) error
+	ListArchivedTasks(*gin.Context) error
+	RetryArchivedTask(*gin.Context) error
+	DeleteArchivedTask(*gin.Context) error
+	ListLogLevels(*gin.Context) error
+	SetLogLevel(*gin.Context) error
+	ResetLogLevel(*gin.Context) error
+	ListDefaultStopwords(*gin.Context) error
+	CreateDefaultStopword(*gin.Context) error
+	DeleteDefaultStopword(*gin.Context) error
+}
+
+type AuditHandler interface {
+	List(*gin.Context) error
 }
 
 type UserHandler interface {
@@ -28,8 +64,18 @@ type FileHandler interface {
 	UploadFile(*gin.Context) error
 	List(*gin.Context) error
 	Download(*gin.Context) error
+	GetContent(*gin.Context) error
+	GetThumbnail(*gin.Context) error
+	GetPagePreview(*gin.Context) error
+	ListTagCandidates(*gin.Context) error
 	Delete(*gin.Context) error
 	Restore(*gin.Context) error
+	Reprocess(*gin.Context) error
+	ReprocessMany(*gin.Context) error
+}
+
+type BlobHandler interface {
+	Download(*gin.Context) error
 }
 
 type StopwordHandler interface {
@@ -38,6 +84,11 @@ type StopwordHandler interface {
 	Enable(*gin.Context) error
 	Disable(*gin.Context) error
 	Delete(*gin.Context) error
+	Import(*gin.Context) error
+	Export(*gin.Context) error
+	ListPacks(*gin.Context) error
+	EnablePack(*gin.Context) error
+	DisablePack(*gin.Context) error
 }
 
 type TagHandler interface {
@@ -45,4 +96,27 @@ type TagHandler interface {
 	List(*gin.Context) error
 	Update(*gin.Context) error
 	Delete(*gin.Context) error
+	Merge(*gin.Context) error
+	ListAliases(*gin.Context) error
+	CreateAlias(*gin.Context) error
+	DeleteAlias(*gin.Context) error
+	Stats(*gin.Context) error
+}
+
+type TagRuleHandler interface {
+	Create(*gin.Context) error
+	List(*gin.Context) error
+	Get(*gin.Context) error
+	Update(*gin.Context) error
+	Delete(*gin.Context) error
+	DryRun(*gin.Context) error
+}
+
+type HealthHandler interface {
+	Liveness(*gin.Context) error
+	Readiness(*gin.Context) error
+}
+
+type QueueHandler interface {
+	ListStats(*gin.Context) error
 }
@@ -1,12 +1,19 @@
 package routes
 
 import (
+	"fmt"
+	"qvarkk/kvault/config"
+	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/handlers/web"
 	"qvarkk/kvault/internal/middleware"
+	"qvarkk/kvault/internal/tracing"
 
 	_ "qvarkk/kvault/docs"
 
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
@@ -14,56 +21,221 @@ import (
 type HandlerServices struct {
 	Auth     web.AuthService
 	AuthUser web.AuthUserService
+	Account  web.AccountService
+	ApiKey   web.ApiKeyService
 	User     web.UserService
 	Item     web.ItemService
 	File     web.FileService
 	Stopword web.StopwordService
 	Tag      web.TagService
+	TagRule  web.TagRuleService
+	Admin    web.AdminService
+	Audit    web.AuditService
+	Quota    QuotaService
+	Queue    web.AdminQueueService
+	Log      web.AdminLogService
+	Health   web.HealthChecker
+	// Set only with the local blob driver
+	Blob web.BlobService
+}
+
+type QuotaService interface {
+	web.UsageService
+	web.UploadQuotaService
+	web.ItemQuotaService
+	web.AdminQuotaService
 }
 
 type MiddlewareServices struct {
-	User middleware.UserService
+	ApiKey      middleware.ApiKeyService
+	RateLimiter middleware.RateLimiter
 }
 
-func SetupRouter(hs *HandlerServices, ms *MiddlewareServices) *gin.Engine {
-	r := gin.Default()
+func SetupRouter(hs *HandlerServices, ms *MiddlewareServices, cfg *config.Config) (*gin.Engine, error) {
+	r := gin.New()
+	// client IPs key rate limits and lockouts, so forwarded headers count
+	// only when they come from a configured proxy
+	if err := r.SetTrustedProxies(cfg.Api.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("routes: invalid trusted proxies: %w", err)
+	}
+	// services get the request context, and the span in it, from handlers
+	// passing *gin.Context
+	r.ContextWithFallback = true
+	if cfg.Tracing.Exporter != tracing.ExporterNone {
+		r.Use(middleware.Tracing("kvault-api"))
+	}
+	r.Use(middleware.AccessLog(), gin.Recovery())
+	if cfg.Metrics.Enabled {
+		r.Use(middleware.Metrics())
+	}
 	r.Use(middleware.ErrorHandlingMiddleware())
+	r.Use(middleware.RequestMeta())
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	registerHealthRoutes(r, web.NewHealthHandler(hs.Health))
+	if cfg.Metrics.Enabled {
+		registerMetricsRoutes(r)
+	}
 
 	api := r.Group("/api/v1")
-	auth := middleware.AuthRequired(ms.User)
+	auth := func(readScope, writeScope domain.ApiKeyScope) gin.HandlerFunc {
+		return middleware.AuthRequired(ms.ApiKey, readScope, writeScope)
+	}
+
+	var (
+		anyScope       = auth("", "")
+		itemsScope     = auth(domain.ApiKeyScopeItemsRead, domain.ApiKeyScopeItemsWrite)
+		filesScope     = auth(domain.ApiKeyScopeFilesRead, domain.ApiKeyScopeFilesWrite)
+		stopwordsScope = auth(domain.ApiKeyScopeStopwordsRead, domain.ApiKeyScopeStopwordsWrite)
+		tagsScope      = auth(domain.ApiKeyScopeTagsRead, domain.ApiKeyScopeTagsWrite)
+		adminScope     = auth(domain.ApiKeyScopeAdmin, domain.ApiKeyScopeAdmin)
+	)
+
+	rateLimitBy := func(name string, limit int, keyBy string) gin.HandlerFunc {
+		if !cfg.RateLimit.Enabled {
+			return func(ctx *gin.Context) { ctx.Next() }
+		}
+		return middleware.RateLimit(ms.RateLimiter, middleware.RateLimitRule{
+			Name:   name,
+			Limit:  limit,
+			Window: time.Duration(cfg.RateLimit.WindowSeconds) * time.Second,
+			KeyBy:  keyBy,
+		})
+	}
+	rateLimit := func(name string, limit int) gin.HandlerFunc {
+		return rateLimitBy(name, limit, cfg.RateLimit.KeyBy)
+	}
+
+	// upload and search budgets are shared by all groups using them
+	var (
+		uploadLimit = rateLimit("upload", cfg.RateLimit.UploadLimit)
+		searchLimit = rateLimit("search", cfg.RateLimit.SearchLimit)
+		groupLimit  = func(name string) gin.HandlerFunc {
+			return rateLimit(name, cfg.RateLimit.DefaultLimit)
+		}
+	)
+
+	// password reset routes have no user yet, so both share a budget per IP
+	resetLimit := rateLimitBy("password-reset", cfg.RateLimit.PasswordResetLimit, middleware.RateLimitByIp)
+
+	registerAuthRoutes(api, anyScope, groupLimit("auth"), resetLimit, web.NewAuthHandler(hs.Auth, hs.AuthUser, hs.Account, hs.Quota))
+	registerApiKeyRoutes(api, anyScope, groupLimit("keys"), web.NewApiKeyHandler(hs.ApiKey))
+	registerUserRoutes(api, adminScope, groupLimit("users"), web.NewUserHandler(hs.User))
+	registerItemRoutes(api, itemsScope, groupLimit("items"), searchLimit, web.NewItemHandler(hs.Item, hs.Quota))
+	registerFileRoutes(api, filesScope, groupLimit("files"), uploadLimit, searchLimit, web.NewFileHandler(hs.File, hs.Quota))
+	registerStopwordRoutes(api, stopwordsScope, groupLimit("stopwords"), web.NewStopwordHandler(hs.Stopword))
+	registerTagRoutes(api, tagsScope, groupLimit("tags"), web.NewTagHandler(hs.Tag))
+	registerTagRuleRoutes(api, tagsScope, groupLimit("tag-rules"), web.NewTagRuleHandler(hs.TagRule))
+	registerAdminRoutes(api, adminScope, groupLimit("admin"), web.NewAdminHandler(hs.Admin, hs.Quota, hs.File, hs.Queue, hs.Log))
+	registerAuditRoutes(api, anyScope, groupLimit("audit"), web.NewAuditHandler(hs.Audit))
+
+	if hs.Blob != nil {
+		registerBlobRoutes(api, web.NewBlobHandler(hs.Blob))
+	}
+
+	return r, nil
+}
+
+// Listener of the worker, it has no API of its own
+func SetupWorkerRouter(checker web.HealthChecker, queueService web.QueueStatsService, cfg *config.Config) *gin.Engine {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(middleware.ErrorHandlingMiddleware())
 
-	registerAuthRoutes(api, auth, web.NewAuthHandler(hs.Auth, hs.AuthUser))
-	registerUserRoutes(api, auth, web.NewUserHandler(hs.User))
-	registerItemRoutes(api, auth, web.NewItemHandler(hs.Item))
-	registerFileRoutes(api, auth, web.NewFileHandler(hs.File))
-	registerStopwordRoutes(api, auth, web.NewStopwordHandler(hs.Stopword))
-	registerTagRoutes(api, auth, web.NewTagHandler(hs.Tag))
+	registerHealthRoutes(r, web.NewHealthHandler(checker))
+	registerQueueRoutes(r, web.NewQueueHandler(queueService))
+	if cfg.Metrics.Enabled {
+		registerMetricsRoutes(r)
+	}
 
 	return r
 }
 
-func registerAuthRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h AuthHandler) {
+func registerMetricsRoutes(r gin.IRoutes) {
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+}
+
+func registerQueueRoutes(r gin.IRoutes, h QueueHandler) {
+	r.GET("/queues", web.APIWrap(h.ListStats))
+}
+
+// Probes go without auth and rate limits, orchestrators poll them often
+func registerHealthRoutes(r gin.IRoutes, h HealthHandler) {
+	r.GET("/healthz", web.APIWrap(h.Liveness))
+	r.GET("/readyz", web.APIWrap(h.Readiness))
+}
+
+func registerAuthRoutes(api *gin.RouterGroup, auth, limit, resetLimit gin.HandlerFunc, h AuthHandler) {
 	group := api.Group("/auth")
 	group.POST("/register", web.APIWrap(h.RegisterUser))
 	group.POST("/login", web.APIWrap(h.AuthenticateUser))
+	group.POST("/password/forgot", resetLimit, web.APIWrap(h.ForgotPassword))
+	group.POST("/password/reset", resetLimit, web.APIWrap(h.ResetPassword))
 
-	protected := group.Group("/", auth)
+	protected := group.Group("/", auth, limit)
 	protected.GET("/me", web.APIWrap(h.GetAuthenticatedUser))
-	protected.POST("/refresh", web.APIWrap(h.RotateApiKey))
+	protected.GET("/me/usage", web.APIWrap(h.GetAuthenticatedUserUsage))
+
+	// a narrowed key must not be enough to take over or drop the account
+	fullAccess := protected.Group("/", middleware.FullAccessRequired())
+	fullAccess.DELETE("/me", web.APIWrap(h.DeleteAuthenticatedUser))
+	fullAccess.POST("/refresh", web.APIWrap(h.RotateApiKey))
+	fullAccess.POST("/password", web.APIWrap(h.ChangePassword))
+}
+
+func registerApiKeyRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h ApiKeyHandler) {
+	group := api.Group("/auth/keys", auth, limit)
+	group.POST("", web.APIWrap(h.Create))
+	group.GET("", web.APIWrap(h.List))
+	group.DELETE("/:id", web.APIWrap(h.Revoke))
+	group.POST("/:id/rotate", web.APIWrap(h.Rotate))
 }
 
-func registerUserRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h UserHandler) {
-	group := api.Group("/users", auth)
-	// TODO: RBAC, fix the idea that /users route only gets user by email lol
+func registerUserRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h UserHandler) {
+	group := api.Group("/users", auth, middleware.RoleRequired(domain.UserRoleAdmin), limit)
 	group.GET("", web.APIWrap(h.GetByEmail))
 }
 
-func registerItemRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h ItemHandler) {
-	group := api.Group("/items", auth)
-	group.POST("", web.APIWrap(h.Create))
+func registerAdminRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h AdminHandler) {
+	group := api.Group("/admin", auth, middleware.RoleRequired(domain.UserRoleAdmin), limit)
+
+	users := group.Group("/users")
+	users.GET("", web.APIWrap(h.ListUsers))
+	users.GET("/:id", web.APIWrap(h.GetUser))
+	users.POST("/:id/disable", web.APIWrap(h.DisableUser))
+	users.POST("/:id/enable", web.APIWrap(h.EnableUser))
+	users.POST("/:id/rotate-keys", web.APIWrap(h.RotateUserKeys))
+	users.GET("/:id/usage", web.APIWrap(h.GetUserUsage))
+	users.PUT("/:id/quota", web.APIWrap(h.SetUserQuota))
+
+	files := group.Group("/files")
+	files.POST("/reprocess", web.APIWrap(h.ReprocessFiles))
+
+	archived := group.Group("/tasks/archived")
+	archived.GET("", web.APIWrap(h.ListArchivedTasks))
+	archived.POST("/:id/retry", web.APIWrap(h.RetryArchivedTask))
+	archived.DELETE("/:id", web.APIWrap(h.DeleteArchivedTask))
+
+	logLevels := group.Group("/log-levels")
+	logLevels.GET("", web.APIWrap(h.ListLogLevels))
+	logLevels.PUT("/:component", web.APIWrap(h.SetLogLevel))
+	logLevels.DELETE("/:component", web.APIWrap(h.ResetLogLevel))
+
+	stopwords := group.Group("/stopwords")
+	stopwords.GET("", web.APIWrap(h.ListDefaultStopwords))
+	stopwords.POST("", web.APIWrap(h.CreateDefaultStopword))
+	stopwords.DELETE("/:lang/:word", web.APIWrap(h.DeleteDefaultStopword))
+}
+
+func registerAuditRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h AuditHandler) {
+	group := api.Group("/audit", auth, limit)
 	group.GET("", web.APIWrap(h.List))
+}
+
+func registerItemRoutes(api *gin.RouterGroup, auth, limit, searchLimit gin.HandlerFunc, h ItemHandler) {
+	group := api.Group("/items", auth, limit)
+	group.POST("", web.APIWrap(h.Create))
+	group.GET("", searchLimit, web.APIWrap(h.List))
 	group.GET("/:id", web.APIWrap(h.Get))
 	group.PATCH("/:id", web.APIWrap(h.Update))
 	group.DELETE("/:id", web.APIWrap(h.Delete))
@@ -73,28 +245,64 @@ func registerItemRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h ItemHandle
 	group.DELETE("/:id/tags/:tag_id", web.APIWrap(h.UnbindTag))
 }
 
-func registerFileRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h FileHandler) {
-	group := api.Group("/files", auth)
-	group.POST("/upload", web.APIWrap(h.UploadFile))
-	group.GET("", web.APIWrap(h.List))
+func registerFileRoutes(api *gin.RouterGroup, auth, limit, uploadLimit, searchLimit gin.HandlerFunc, h FileHandler) {
+	group := api.Group("/files", auth, limit)
+	group.POST("/upload", uploadLimit, web.APIWrap(h.UploadFile))
+	group.POST("/reprocess", web.APIWrap(h.ReprocessMany))
+	group.GET("", searchLimit, web.APIWrap(h.List))
 	group.GET("/:id", web.APIWrap(h.Download))
+	group.GET("/:id/content", web.APIWrap(h.GetContent))
+	group.HEAD("/:id/content", web.APIWrap(h.GetContent))
+	group.GET("/:id/thumbnail", web.APIWrap(h.GetThumbnail))
+	group.GET("/:id/pages/:page/preview", web.APIWrap(h.GetPagePreview))
+	group.GET("/:id/tag-candidates", web.APIWrap(h.ListTagCandidates))
 	group.DELETE("/:id", web.APIWrap(h.Delete))
 	group.POST("/:id/restore", web.APIWrap(h.Restore))
+	group.POST("/:id/reprocess", web.APIWrap(h.Reprocess))
 }
 
-func registerStopwordRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h StopwordHandler) {
-	group := api.Group("/stopwords", auth)
+// Links are signed, so no API key is needed
+func registerBlobRoutes(api *gin.RouterGroup, h BlobHandler) {
+	group := api.Group("/blobs")
+	group.GET("/*key", web.APIWrap(h.Download))
+}
+
+func registerStopwordRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h StopwordHandler) {
+	group := api.Group("/stopwords", auth, limit)
 	group.POST("", web.APIWrap(h.Create))
 	group.GET("", web.APIWrap(h.List))
 	group.POST("/:word/enable", web.APIWrap(h.Enable))
 	group.POST("/:word/disable", web.APIWrap(h.Disable))
 	group.DELETE("/:word", web.APIWrap(h.Delete))
+
+	group.POST("/import", web.APIWrap(h.Import))
+	group.GET("/export", web.APIWrap(h.Export))
+
+	group.GET("/packs", web.APIWrap(h.ListPacks))
+	group.POST("/packs/:lang/enable", web.APIWrap(h.EnablePack))
+	group.POST("/packs/:lang/disable", web.APIWrap(h.DisablePack))
+}
+
+func registerTagRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h TagHandler) {
+	group := api.Group("/tags", auth, limit)
+	group.POST("", web.APIWrap(h.Create))
+	group.GET("", web.APIWrap(h.List))
+	group.GET("/stats", web.APIWrap(h.Stats))
+	group.PATCH("/:id", web.APIWrap(h.Update))
+	group.DELETE("/:id", web.APIWrap(h.Delete))
+	group.POST("/:id/merge", web.APIWrap(h.Merge))
+
+	group.GET("/:id/aliases", web.APIWrap(h.ListAliases))
+	group.POST("/:id/aliases", web.APIWrap(h.CreateAlias))
+	group.DELETE("/:id/aliases/:alias", web.APIWrap(h.DeleteAlias))
 }
 
-func registerTagRoutes(api *gin.RouterGroup, auth gin.HandlerFunc, h TagHandler) {
-	group := api.Group("/tags", auth)
+func registerTagRuleRoutes(api *gin.RouterGroup, auth, limit gin.HandlerFunc, h TagRuleHandler) {
+	group := api.Group("/tag-rules", auth, limit)
 	group.POST("", web.APIWrap(h.Create))
 	group.GET("", web.APIWrap(h.List))
+	group.POST("/dry-run", web.APIWrap(h.DryRun))
+	group.GET("/:id", web.APIWrap(h.Get))
 	group.PATCH("/:id", web.APIWrap(h.Update))
 	group.DELETE("/:id", web.APIWrap(h.Delete))
 }
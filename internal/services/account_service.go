@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/redis"
+	"qvarkk/kvault/internal/repositories"
+	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type AccountUserRepo interface {
+	GetByID(context.Context, string) (*domain.User, error)
+	GetByEmail(context.Context, string) (*domain.User, error)
+	UpdatePasswordTx(ctx context.Context, tx *sqlx.Tx, userID, passwordHash string) error
+	MarkForDeletion(ctx context.Context, userID string) error
+}
+
+type PasswordResetRepo interface {
+	ConsumeTx(ctx context.Context, tx *sqlx.Tx, tokenHash string) (*domain.PasswordResetToken, error)
+	InvalidateForUserTx(ctx context.Context, tx *sqlx.Tx, userID string) error
+}
+
+type AccountApiKeyRepo interface {
+	RevokeLoginKeysTx(ctx context.Context, tx *sqlx.Tx, userID, exceptID string) error
+}
+
+type ResetMailLimiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*domain.RateLimitResult, error)
+}
+
+// Reset mails allowed per email within Window
+type PasswordResetLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+const passwordResetMailRetries = 3
+
+type AccountService struct {
+	userRepo          AccountUserRepo
+	passwordResetRepo PasswordResetRepo
+	apiKeyRepo        AccountApiKeyRepo
+	transactor        Transactor
+	redis             *redis.Redis
+	guard             AuthGuard
+	limiter           ResetMailLimiter
+	audit             AuditRecorder
+	resetLimit        PasswordResetLimit
+}
+
+type ChangePasswordInput struct {
+	UserID string
+	// Key the change is made with, kept even if it's a login key
+	ApiKeyID        string
+	CurrentPassword string
+	NewPassword     string
+}
+
+func NewAccountService(
+	userRepo AccountUserRepo,
+	passwordResetRepo PasswordResetRepo,
+	apiKeyRepo AccountApiKeyRepo,
+	transactor Transactor,
+	redis *redis.Redis,
+	guard AuthGuard,
+	limiter ResetMailLimiter,
+	audit AuditRecorder,
+	resetLimit PasswordResetLimit,
+) *AccountService {
+	return &AccountService{
+		userRepo:          userRepo,
+		passwordResetRepo: passwordResetRepo,
+		apiKeyRepo:        apiKeyRepo,
+		transactor:        transactor,
+		redis:             redis,
+		guard:             guard,
+		limiter:           limiter,
+		audit:             audit,
+		resetLimit:        resetLimit,
+	}
+}
+
+// Login keys other than the one used for the change are revoked, other
+// keys stay valid
+func (s *AccountService) ChangePassword(ctx context.Context, input ChangePasswordInput) error {
+	user, err := s.userRepo.GetByID(ctx, input.UserID)
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to get user", err)
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.CurrentPassword))
+	if err != nil {
+		return NewServiceError(ErrPasswordMismatch, "failed to compare password hashes", err)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to hash password", err)
+	}
+
+	err = s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		err := s.userRepo.UpdatePasswordTx(ctx, tx, user.ID, string(passwordHash))
+		if err != nil {
+			return NewServiceError(ErrInternal, "update password internal error", err)
+		}
+
+		err = s.apiKeyRepo.RevokeLoginKeysTx(ctx, tx, user.ID, input.ApiKeyID)
+		if err != nil {
+			return NewServiceError(ErrInternal, "revoke login api keys internal error", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.recordAccountEvent(ctx, domain.AuditActionPasswordChanged, user.ID)
+	return nil
+}
+
+func (s *AccountService) recordAccountEvent(ctx context.Context, action domain.AuditAction, userID string) {
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   userID,
+	})
+}
+
+// Enqueues a reset mail for the worker, which finds out whether the user
+// exists, so every email takes the same path. Requests past the budget of
+// the email are dropped just as silently
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	key := "ratelimit:password-reset:email:" + normalizeEmail(email)
+	result, err := s.limiter.Allow(ctx, key, s.resetLimit.Limit, s.resetLimit.Window)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Password reset limiter failed", zap.Error(err))
+	} else if !result.Allowed {
+		return nil
+	}
+
+	task, err := tasks.NewPasswordResetMailTask(ctx, tasks.PasswordResetMailPayload{Email: email})
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to create password reset mail task", err)
+	}
+
+	// a mail that comes hours late is worse than none
+	_, err = s.redis.AsynqClient.EnqueueContext(ctx, task, asynq.MaxRetry(passwordResetMailRetries))
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to enqueue password reset mail task", err)
+	}
+
+	return nil
+}
+
+// Sets a new password by a mailed token, other unused tokens and all login
+// keys are revoked. Unknown tokens count as failures of the client IP
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword, clientIP string) error {
+	if err := s.guard.Check(ctx, "", clientIP); err != nil {
+		return err
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to hash password", err)
+	}
+
+	var userID string
+
+	err = s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		resetToken, err := s.passwordResetRepo.ConsumeTx(ctx, tx, hashApiKey(token))
+		if err != nil {
+			if errors.Is(err, repositories.ErrNotFound) {
+				return NewServiceError(ErrPasswordResetInvalid, "token not found", err)
+			}
+			return NewServiceError(ErrInternal, "consume reset token internal error", err)
+		}
+
+		err = s.userRepo.UpdatePasswordTx(ctx, tx, resetToken.UserID, string(passwordHash))
+		if err != nil {
+			return NewServiceError(ErrInternal, "update password internal error", err)
+		}
+
+		err = s.passwordResetRepo.InvalidateForUserTx(ctx, tx, resetToken.UserID)
+		if err != nil {
+			return NewServiceError(ErrInternal, "invalidate reset tokens internal error", err)
+		}
+
+		err = s.apiKeyRepo.RevokeLoginKeysTx(ctx, tx, resetToken.UserID, "")
+		if err != nil {
+			return NewServiceError(ErrInternal, "revoke login api keys internal error", err)
+		}
+
+		userID = resetToken.UserID
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrPasswordResetInvalid) {
+			s.guard.RecordFailure(ctx, "", clientIP)
+		}
+		return err
+	}
+
+	s.recordAccountEvent(ctx, domain.AuditActionPasswordReset, userID)
+	return nil
+}
+
+// Disables the account right away and enqueues removal of all its data
+// once password is confirmed. Repeated requests return ID of the already
+// enqueued task
+func (s *AccountService) RequestDeletion(ctx context.Context, userID, password string) (string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", NewServiceError(ErrInternal, "failed to get user", err)
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return "", NewServiceError(ErrPasswordMismatch, "failed to compare password hashes", err)
+	}
+
+	err = s.userRepo.MarkForDeletion(ctx, userID)
+	if err != nil {
+		return "", NewServiceError(ErrInternal, "mark user for deletion internal error", err)
+	}
+
+	task, err := tasks.NewUserDeleteTask(ctx, tasks.UserDeletePayload{UserID: userID})
+	if err != nil {
+		return "", NewServiceError(ErrInternal, "failed to create user deletion task", err)
+	}
+
+	taskID := "user-delete:" + userID
+	_, err = s.redis.AsynqClient.EnqueueContext(ctx, task, asynq.TaskID(taskID))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		return "", NewServiceError(ErrInternal, "failed to enqueue user deletion task", err)
+	}
+
+	s.recordAccountEvent(ctx, domain.AuditActionDeletionRequested, userID)
+
+	return taskID, nil
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/mail"
+	"qvarkk/kvault/internal/repositories"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AccountTaskFileRepo interface {
+	ListS3KeysByUser(ctx context.Context, userID string) ([]string, error)
+}
+
+type AccountTaskUserRepo interface {
+	GetByID(context.Context, string) (*domain.User, error)
+	GetByEmail(context.Context, string) (*domain.User, error)
+	HardDeleteTx(ctx context.Context, tx *sqlx.Tx, userID string) error
+}
+
+type PasswordResetTaskRepo interface {
+	CreateNew(context.Context, *domain.PasswordResetToken) error
+}
+
+type MailSender interface {
+	Send(context.Context, mail.Message) error
+}
+
+type PasswordResetConfig struct {
+	TokenTTL time.Duration
+	// Reset page of the frontend, may be empty
+	Url string
+}
+
+const passwordResetTokenBytes = 32
+
+type AccountTaskService struct {
+	fileRepo          AccountTaskFileRepo
+	userRepo          AccountTaskUserRepo
+	passwordResetRepo PasswordResetTaskRepo
+	transactor        Transactor
+	blobStore         BlobStore
+	mailSender        MailSender
+	resetConfig       PasswordResetConfig
+}
+
+func NewAccountTaskService(
+	fileRepo AccountTaskFileRepo,
+	userRepo AccountTaskUserRepo,
+	passwordResetRepo PasswordResetTaskRepo,
+	transactor Transactor,
+	blobStore BlobStore,
+	mailSender MailSender,
+	resetConfig PasswordResetConfig,
+) *AccountTaskService {
+	return &AccountTaskService{
+		fileRepo:          fileRepo,
+		userRepo:          userRepo,
+		passwordResetRepo: passwordResetRepo,
+		transactor:        transactor,
+		blobStore:         blobStore,
+		mailSender:        mailSender,
+		resetConfig:       resetConfig,
+	}
+}
+
+// Removes stored files and all rows of a user who requested deletion.
+// Users that are gone or never asked for it are skipped
+func (s *AccountTaskService) DeleteUserData(ctx context.Context, userID string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		return NewServiceError(ErrInternal, "failed to get user", err)
+	}
+
+	if !user.DeletionRequestedAt.Valid {
+		return nil
+	}
+
+	keys, err := s.fileRepo.ListS3KeysByUser(ctx, userID)
+	if err != nil {
+		return NewServiceError(ErrInternal, "list user files internal error", err)
+	}
+
+	if err := s.blobStore.Delete(ctx, keys...); err != nil {
+		return NewServiceError(ErrInternal, "failed to delete stored user files", err)
+	}
+
+	return s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.userRepo.HardDeleteTx(ctx, tx, userID); err != nil {
+			return NewServiceError(ErrInternal, "delete user internal error", err)
+		}
+		return nil
+	})
+}
+
+// Mails a reset token if an enabled user has the email, anything else is
+// skipped without telling anyone
+func (s *AccountTaskService) SendPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		return NewServiceError(ErrInternal, "failed to find user", err)
+	}
+
+	if user.DisabledAt.Valid {
+		return nil
+	}
+
+	tokenBytes := make([]byte, passwordResetTokenBytes)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return NewServiceError(ErrInternal, "failed to generate reset token", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+
+	resetToken := &domain.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashApiKey(token),
+		ExpiresAt: time.Now().Add(s.resetConfig.TokenTTL),
+	}
+
+	if err := s.passwordResetRepo.CreateNew(ctx, resetToken); err != nil {
+		return NewServiceError(ErrInternal, "create reset token internal error", err)
+	}
+
+	body := fmt.Sprintf("Use this token to reset your password: %s", token)
+	if s.resetConfig.Url != "" {
+		body = fmt.Sprintf("Follow this link to reset your password: %s?token=%s", s.resetConfig.Url, url.QueryEscape(token))
+	}
+	body += fmt.Sprintf("\n\nIt expires in %s. If you didn't ask for a reset, ignore this message.", s.resetConfig.TokenTTL)
+
+	err = s.mailSender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "KVault password reset",
+		Body:    body,
+	})
+	if err != nil {
+		return NewServiceError(ErrInternal, "failed to send password reset mail", err)
+	}
+	return nil
+}
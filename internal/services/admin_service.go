@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/repositories"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AdminUserRepo interface {
+	GetByID(context.Context, string) (*domain.User, error)
+	List(context.Context, domain.ListUserFilter) ([]domain.User, int, error)
+	SetDisabled(ctx context.Context, userID string, isDisabled bool) (*domain.User, error)
+	PromoteToAdminTx(ctx context.Context, tx *sqlx.Tx, emails []string) ([]string, error)
+	GetUsage(ctx context.Context, userID string) (*domain.UserUsage, error)
+}
+
+type AdminApiKeyRepo interface {
+	apiKeyRotator
+	List(ctx context.Context, userID string) ([]domain.ApiKey, error)
+	AddScopeTx(ctx context.Context, tx *sqlx.Tx, userIDs []string, name string, scope domain.ApiKeyScope) error
+}
+
+type AdminStopwordRepo interface {
+	ListDefault(ctx context.Context, lang, query string) ([]domain.DefaultStopword, error)
+	CreateDefault(context.Context, *domain.DefaultStopword) error
+	DeleteDefault(ctx context.Context, lang, word string) (bool, error)
+	PackExists(ctx context.Context, lang string) (bool, error)
+}
+
+type AdminService struct {
+	userRepo     AdminUserRepo
+	apiKeyRepo   AdminApiKeyRepo
+	stopwordRepo AdminStopwordRepo
+	transactor   Transactor
+	audit        AuditRecorder
+}
+
+func NewAdminService(
+	userRepo AdminUserRepo,
+	apiKeyRepo AdminApiKeyRepo,
+	stopwordRepo AdminStopwordRepo,
+	transactor Transactor,
+	audit AuditRecorder,
+) *AdminService {
+	return &AdminService{
+		userRepo:     userRepo,
+		apiKeyRepo:   apiKeyRepo,
+		stopwordRepo: stopwordRepo,
+		transactor:   transactor,
+		audit:        audit,
+	}
+}
+
+// Gives admin role to existing users with given emails, used on startup
+func (s *AdminService) PromoteAdmins(ctx context.Context, emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+
+	return s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		userIDs, err := s.userRepo.PromoteToAdminTx(ctx, tx, emails)
+		if err != nil {
+			return NewServiceError(ErrInternal, "failed to promote admins", err)
+		}
+
+		if len(userIDs) == 0 {
+			return nil
+		}
+
+		err = s.apiKeyRepo.AddScopeTx(ctx, tx, userIDs, domain.DefaultApiKeyName, domain.ApiKeyScopeAdmin)
+		if err != nil {
+			return NewServiceError(ErrInternal, "failed to grant admin scope", err)
+		}
+
+		return nil
+	})
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, params domain.ListUserFilter) ([]domain.User, int, error) {
+	users, count, err := s.userRepo.List(ctx, params)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "list users internal error", err)
+	}
+	return users, count, nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to find user %s", userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrUserNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+	return user, nil
+}
+
+func (s *AdminService) DisableUser(ctx context.Context, userID, adminID string) (*domain.User, error) {
+	if userID == adminID {
+		return nil, NewServiceError(ErrAdminSelfAction, "admin tried to disable themselves", nil)
+	}
+	return s.setDisabled(ctx, userID, adminID, true)
+}
+
+func (s *AdminService) EnableUser(ctx context.Context, userID, adminID string) (*domain.User, error) {
+	return s.setDisabled(ctx, userID, adminID, false)
+}
+
+func (s *AdminService) setDisabled(ctx context.Context, userID, adminID string, isDisabled bool) (*domain.User, error) {
+	user, err := s.userRepo.SetDisabled(ctx, userID, isDisabled)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to update user %s", userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrUserNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+
+	action := domain.AuditActionUserEnabled
+	if isDisabled {
+		action = domain.AuditActionUserDisabled
+	}
+	s.recordUserEvent(ctx, action, userID, adminID, nil)
+
+	return user, nil
+}
+
+func (s *AdminService) recordUserEvent(
+	ctx context.Context,
+	action domain.AuditAction,
+	userID, adminID string,
+	details map[string]any,
+) {
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    adminID,
+		UserID:     userID,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   userID,
+		Details:    details,
+	})
+}
+
+// Replaces values of all live keys of the user. New values are thrown away,
+// so the user has to log in again or rotate keys to get working ones
+func (s *AdminService) RotateUserKeys(ctx context.Context, userID, adminID string) (int, error) {
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return 0, err
+	}
+
+	apiKeys, err := s.apiKeyRepo.List(ctx, userID)
+	if err != nil {
+		return 0, NewServiceError(ErrInternal, "list api keys internal error", err)
+	}
+
+	for _, apiKey := range apiKeys {
+		if _, err := rotateApiKey(ctx, s.apiKeyRepo, apiKey.ID); err != nil {
+			return 0, err
+		}
+	}
+
+	s.recordUserEvent(ctx, domain.AuditActionUserKeysRotated, userID, adminID, map[string]any{"rotated": len(apiKeys)})
+
+	return len(apiKeys), nil
+}
+
+func (s *AdminService) GetUserUsage(ctx context.Context, userID string) (*domain.UserUsage, error) {
+	usage, err := s.userRepo.GetUsage(ctx, userID)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to get usage of user %s", userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrUserNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+	return usage, nil
+}
+
+func (s *AdminService) ListDefaultStopwords(ctx context.Context, lang, query string) ([]domain.DefaultStopword, error) {
+	stopwords, err := s.stopwordRepo.ListDefault(ctx, lang, query)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list default stopwords internal error", err)
+	}
+	return stopwords, nil
+}
+
+func (s *AdminService) CreateDefaultStopword(ctx context.Context, lang, word string) (*domain.DefaultStopword, error) {
+	if err := s.checkPackExists(ctx, lang); err != nil {
+		return nil, err
+	}
+
+	stopword := &domain.DefaultStopword{
+		Lang: lang,
+		Word: strings.ToLower(strings.TrimSpace(word)),
+	}
+
+	err := s.stopwordRepo.CreateDefault(ctx, stopword)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAlreadyExists) {
+			return nil, NewServiceError(ErrDefaultStopwordExists, "already exists", err)
+		}
+		return nil, NewServiceError(ErrStopwordNotCreated, "create default stopword internal error", err)
+	}
+
+	return stopword, nil
+}
+
+func (s *AdminService) DeleteDefaultStopword(ctx context.Context, lang, word string) error {
+	deleted, err := s.stopwordRepo.DeleteDefault(ctx, lang, word)
+	if err != nil {
+		return NewServiceError(ErrInternal, "delete default stopword internal error", err)
+	}
+
+	if !deleted {
+		return NewServiceError(ErrStopwordNotFound, "not found", nil)
+	}
+
+	return nil
+}
+
+func (s *AdminService) checkPackExists(ctx context.Context, lang string) error {
+	exists, err := s.stopwordRepo.PackExists(ctx, lang)
+	if err != nil {
+		return NewServiceError(ErrInternal, "check stopword pack internal error", err)
+	}
+
+	if !exists {
+		return NewServiceError(ErrStopwordPackNotFound, "not found", nil)
+	}
+
+	return nil
+}
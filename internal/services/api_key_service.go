@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/repositories"
+	"slices"
+	"strings"
+	"time"
+)
+
+type ApiKeyRepo interface {
+	CreateNew(context.Context, *domain.ApiKey) error
+	IsPrefixUnique(context.Context, string) (bool, error)
+	List(ctx context.Context, userID string) ([]domain.ApiKey, error)
+	GetByID(context.Context, string) (*domain.ApiKey, error)
+	Use(ctx context.Context, prefix, keyHash string) (*domain.ApiKey, error)
+	UpdateKey(ctx context.Context, keyID, prefix, keyHash string) (*domain.ApiKey, error)
+	Revoke(ctx context.Context, keyID string) error
+}
+
+type ApiKeyUserRepo interface {
+	GetByID(context.Context, string) (*domain.User, error)
+}
+
+type apiKeyUniquenessChecker interface {
+	IsPrefixUnique(context.Context, string) (bool, error)
+}
+
+// Issued keys look like kv_<prefix>_<secret>
+const (
+	apiKeyTag          = "kv"
+	apiKeyPrefixBytes  = 6
+	apiKeySecretBytes  = 32
+	legacyApiKeyPrefix = 12
+)
+
+type issuedApiKey struct {
+	Key     string
+	Prefix  string
+	KeyHash string
+}
+
+type ApiKeyService struct {
+	apiKeyRepo ApiKeyRepo
+	userRepo   ApiKeyUserRepo
+	audit      AuditRecorder
+}
+
+type CreateApiKeyInput struct {
+	UserID    string
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+	// Scopes of the key making the request, a new key can't get more than these
+	GrantorScopes []string
+}
+
+func NewApiKeyService(apiKeyRepo ApiKeyRepo, userRepo ApiKeyUserRepo, audit AuditRecorder) *ApiKeyService {
+	return &ApiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		audit:      audit,
+	}
+}
+
+// Resolves key owner and checks that the key has the required scope.
+// Empty scope means that any valid key is accepted
+func (s *ApiKeyService) Authenticate(
+	ctx context.Context,
+	key string,
+	scope domain.ApiKeyScope,
+) (*domain.ApiKey, *domain.User, error) {
+	if key == "" {
+		return nil, nil, NewServiceError(ErrUnauthenticated, "api key is missing", nil)
+	}
+
+	prefix := apiKeyPrefix(key)
+	if prefix == "" {
+		return nil, nil, NewServiceError(ErrUnauthenticated, "api key is malformed", nil)
+	}
+
+	apiKey, err := s.apiKeyRepo.Use(ctx, prefix, hashApiKey(key))
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, nil, NewServiceError(ErrUnauthenticated, "api key is unknown, revoked or expired", err)
+		}
+		return nil, nil, NewServiceError(ErrInternal, "failed to look up api key", err)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, nil, NewServiceError(ErrInternal, "failed to get api key owner", err)
+	}
+
+	if user.DisabledAt.Valid {
+		errMsg := fmt.Sprintf("user %s is disabled", user.ID)
+		return nil, nil, NewServiceError(ErrUserDisabled, errMsg, nil)
+	}
+
+	if scope != "" && !slices.Contains(apiKey.Scopes, string(scope)) {
+		errMsg := fmt.Sprintf("api key %s has no %s scope", apiKey.ID, scope)
+		return nil, nil, NewServiceError(ErrApiKeyScopeMissing, errMsg, nil)
+	}
+
+	return apiKey, user, nil
+}
+
+func (s *ApiKeyService) CreateNew(ctx context.Context, input CreateApiKeyInput) (*domain.ApiKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, NewServiceError(ErrApiKeyInvalid, "api key name is empty", nil)
+	}
+
+	// login keys are told apart by name, they're revoked in bulk
+	if strings.HasPrefix(name, domain.LoginApiKeyNamePrefix) {
+		return nil, NewServiceError(ErrApiKeyInvalid, "api key name is reserved for login keys", nil)
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, NewServiceError(ErrApiKeyInvalid, "api key expiry is in the past", nil)
+	}
+
+	scopes := slices.Clone(input.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	if scope, ok := exceedingScope(scopes, input.GrantorScopes); ok {
+		errMsg := fmt.Sprintf("can't grant %s scope", scope)
+		return nil, NewServiceError(ErrApiKeyScopeExceeded, errMsg, nil)
+	}
+
+	issued, err := generateApiKey(ctx, s.apiKeyRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := &domain.ApiKey{
+		UserID:  input.UserID,
+		Name:    name,
+		Prefix:  issued.Prefix,
+		KeyHash: issued.KeyHash,
+		Scopes:  scopes,
+	}
+	if input.ExpiresAt != nil {
+		apiKey.ExpiresAt = sql.NullTime{Time: *input.ExpiresAt, Valid: true}
+	}
+
+	err = s.apiKeyRepo.CreateNew(ctx, apiKey)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAlreadyExists) {
+			return nil, NewServiceError(ErrApiKeyAlreadyExists, "api key name is taken", err)
+		}
+		return nil, NewServiceError(ErrInternal, "create api key internal error", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionApiKeyCreated,
+		ActorID:    input.UserID,
+		TargetType: domain.AuditTargetApiKey,
+		TargetID:   apiKey.ID,
+		Details:    map[string]any{"name": apiKey.Name, "scopes": apiKey.Scopes},
+	})
+
+	apiKey.Key = issued.Key
+	return apiKey, nil
+}
+
+func (s *ApiKeyService) List(ctx context.Context, userID string) ([]domain.ApiKey, error) {
+	apiKeys, err := s.apiKeyRepo.List(ctx, userID)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list api keys internal error", err)
+	}
+	return apiKeys, nil
+}
+
+// callerScopes are scopes of the key making the request, it has to hold
+// every scope of the revoked key
+func (s *ApiKeyService) Revoke(ctx context.Context, keyID, userID string, callerScopes []string) error {
+	if _, err := s.getManagedKey(ctx, keyID, userID, callerScopes); err != nil {
+		return err
+	}
+
+	err := s.apiKeyRepo.Revoke(ctx, keyID)
+	if err != nil {
+		return NewServiceError(ErrInternal, "revoke api key internal error", err)
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionApiKeyRevoked,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetApiKey,
+		TargetID:   keyID,
+	})
+
+	return nil
+}
+
+// Same scope rule as Revoke, otherwise a narrow key could take over the
+// secret of a broader one
+func (s *ApiKeyService) Rotate(ctx context.Context, keyID, userID string, callerScopes []string) (*domain.ApiKey, error) {
+	if _, err := s.getManagedKey(ctx, keyID, userID, callerScopes); err != nil {
+		return nil, err
+	}
+
+	apiKey, err := rotateApiKey(ctx, s.apiKeyRepo, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordKeyRotation(ctx, s.audit, apiKey, userID)
+	return apiKey, nil
+}
+
+func (s *ApiKeyService) getOwnedKey(ctx context.Context, keyID, userID string) (*domain.ApiKey, error) {
+	apiKey, err := s.apiKeyRepo.GetByID(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrApiKeyNotFound, "not found", err)
+		}
+		return nil, NewServiceError(ErrInternal, "get api key internal error", err)
+	}
+
+	if apiKey.UserID != userID {
+		return nil, NewServiceError(ErrApiKeyNotFound, "forbidden", nil)
+	}
+
+	return apiKey, nil
+}
+
+// Owned key whose scopes are all held by the caller, the rule CreateNew
+// applies to new keys
+func (s *ApiKeyService) getManagedKey(
+	ctx context.Context,
+	keyID, userID string,
+	callerScopes []string,
+) (*domain.ApiKey, error) {
+	apiKey, err := s.getOwnedKey(ctx, keyID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if scope, ok := exceedingScope(apiKey.Scopes, callerScopes); ok {
+		errMsg := fmt.Sprintf("api key %s has %s scope the caller lacks", keyID, scope)
+		return nil, NewServiceError(ErrApiKeyScopeInsufficient, errMsg, nil)
+	}
+
+	return apiKey, nil
+}
+
+// First of scopes that isn't among granted
+func exceedingScope(scopes, granted []string) (string, bool) {
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return scope, true
+		}
+	}
+	return "", false
+}
+
+func generateApiKey(ctx context.Context, checker apiKeyUniquenessChecker) (*issuedApiKey, error) {
+	var prefix string
+	for {
+		prefixBytes := make([]byte, apiKeyPrefixBytes)
+		if _, err := rand.Read(prefixBytes); err != nil {
+			return nil, NewServiceError(ErrInternal, "couldn't generate api key prefix", err)
+		}
+		prefix = hex.EncodeToString(prefixBytes)
+
+		isPrefixUnique, err := checker.IsPrefixUnique(ctx, prefix)
+		if err != nil {
+			return nil, NewServiceError(ErrInternal, "couldn't verify if api key prefix is unique", err)
+		}
+
+		if isPrefixUnique {
+			break
+		}
+	}
+
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, NewServiceError(ErrInternal, "couldn't generate api key secret", err)
+	}
+
+	key := apiKeyTag + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	return &issuedApiKey{
+		Key:     key,
+		Prefix:  prefix,
+		KeyHash: hashApiKey(key),
+	}, nil
+}
+
+// Returns the lookup prefix of the key or an empty string if it's malformed.
+// Keys issued before hashing are UUIDs, migration used their first hex characters
+func apiKeyPrefix(key string) string {
+	if rest, ok := strings.CutPrefix(key, apiKeyTag+"_"); ok {
+		prefix, _, found := strings.Cut(rest, "_")
+		if !found || len(prefix) != hex.EncodedLen(apiKeyPrefixBytes) {
+			return ""
+		}
+		return prefix
+	}
+
+	legacy := strings.ReplaceAll(key, "-", "")
+	if len(legacy) < legacyApiKeyPrefix {
+		return ""
+	}
+	return legacy[:legacyApiKeyPrefix]
+}
+
+func hashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+type apiKeyRotator interface {
+	apiKeyUniquenessChecker
+	UpdateKey(ctx context.Context, keyID, prefix, keyHash string) (*domain.ApiKey, error)
+}
+
+func rotateApiKey(ctx context.Context, repo apiKeyRotator, keyID string) (*domain.ApiKey, error) {
+	issued, err := generateApiKey(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := repo.UpdateKey(ctx, keyID, issued.Prefix, issued.KeyHash)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to update api key %s", keyID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrApiKeyNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+
+	apiKey.Key = issued.Key
+	return apiKey, nil
+}
+
+func recordKeyRotation(ctx context.Context, audit AuditRecorder, apiKey *domain.ApiKey, actorID string) {
+	audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionApiKeyRotated,
+		ActorID:    actorID,
+		UserID:     apiKey.UserID,
+		TargetType: domain.AuditTargetApiKey,
+		TargetID:   apiKey.ID,
+	})
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+
+	"go.uber.org/zap"
+)
+
+type AuditRepo interface {
+	CreateNew(context.Context, *domain.AuditEvent) error
+	List(context.Context, domain.ListAuditEventFilter) ([]domain.AuditEvent, int, error)
+}
+
+type AuditRecorder interface {
+	Record(context.Context, AuditEntry)
+}
+
+type AuditEntry struct {
+	Action  domain.AuditAction
+	ActorID string
+	// Owner of the affected account, ActorID is used when empty
+	UserID     string
+	TargetType domain.AuditTarget
+	TargetID   string
+	Details    map[string]any
+}
+
+type AuditService struct {
+	auditRepo AuditRepo
+}
+
+func NewAuditService(auditRepo AuditRepo) *AuditService {
+	return &AuditService{auditRepo: auditRepo}
+}
+
+// Stores the event with client details of the current request. The action
+// has already happened at this point, so failures are only logged
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	meta := domain.RequestMetaFrom(ctx)
+
+	userID := entry.UserID
+	if userID == "" {
+		userID = entry.ActorID
+	}
+
+	event := &domain.AuditEvent{
+		ActorID:    NewNullString(entry.ActorID),
+		UserID:     NewNullString(userID),
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   NewNullString(entry.TargetID),
+		RequestID:  meta.RequestID,
+		IP:         meta.ClientIP,
+		UserAgent:  meta.UserAgent,
+	}
+
+	if len(entry.Details) > 0 {
+		details, err := json.Marshal(entry.Details)
+		if err != nil {
+			logger.FromContext(ctx).Error("Failed to encode audit details", zap.Error(err), zap.String("action", string(entry.Action)))
+		}
+		event.Details = details
+	}
+
+	// the request may be cancelled right after the action, the event shouldn't be lost
+	if err := s.auditRepo.CreateNew(context.WithoutCancel(ctx), event); err != nil {
+		logger.FromContext(ctx).Error(
+			"Failed to record audit event",
+			zap.Error(err),
+			zap.String("action", string(entry.Action)),
+			zap.String("actor_id", entry.ActorID),
+			zap.String("target_id", entry.TargetID),
+		)
+	}
+}
+
+// Users only get events of their own account, admins may read anyone's
+func (s *AuditService) List(
+	ctx context.Context,
+	params domain.ListAuditEventFilter,
+	viewerID string,
+	isAdmin bool,
+) ([]domain.AuditEvent, int, error) {
+	if !isAdmin {
+		if params.UserID != "" && params.UserID != viewerID {
+			return nil, 0, NewServiceError(ErrForbidden, "only admins can read events of other users", nil)
+		}
+		params.UserID = viewerID
+	}
+
+	events, count, err := s.auditRepo.List(ctx, params)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "list audit events internal error", err)
+	}
+
+	return events, count, nil
+}
@@ -2,87 +2,235 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"qvarkk/kvault/internal/challenge"
 	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/repositories"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthUserRepo interface {
-	CreateNew(context.Context, *domain.User) error
+	CreateNewTx(context.Context, *sqlx.Tx, *domain.User) error
 	GetByID(context.Context, string) (*domain.User, error)
 	GetByEmail(context.Context, string) (*domain.User, error)
-	UpdateApiKey(ctx context.Context, userID string, apiKey string) (*domain.User, error)
-	IsApiKeyUnique(context.Context, string) (bool, error)
 }
 
+type AuthApiKeyRepo interface {
+	CreateNewTx(context.Context, *sqlx.Tx, *domain.ApiKey) error
+	RevokeOldLoginKeysTx(ctx context.Context, tx *sqlx.Tx, userID string, keep int) error
+	IsPrefixUnique(context.Context, string) (bool, error)
+	UpdateKey(ctx context.Context, keyID, prefix, keyHash string) (*domain.ApiKey, error)
+}
+
+type AuthGuard interface {
+	Check(ctx context.Context, email, clientIP string) error
+	RecordFailure(ctx context.Context, email, clientIP string)
+	RecordSuccess(ctx context.Context, email, clientIP string)
+}
+
+type ChallengeVerifier interface {
+	Verify(ctx context.Context, subject, solution, clientIP string) error
+}
+
+// Login keys live long enough for a session of a client that logs in
+// again, revoked ones stop working right away
+const loginApiKeyTTL = 30 * 24 * time.Hour
+
+// Older login keys of a user are revoked on login past this many
+const maxLoginApiKeys = 5
+
 type AuthService struct {
-	userRepo AuthUserRepo
+	userRepo   AuthUserRepo
+	apiKeyRepo AuthApiKeyRepo
+	transactor Transactor
+	guard      AuthGuard
+	audit      AuditRecorder
+	// Optional, registration needs no challenge when nil
+	challenge ChallengeVerifier
 }
 
-func NewAuthService(userRepo AuthUserRepo) *AuthService {
-	return &AuthService{userRepo: userRepo}
+type RegisterUserInput struct {
+	Email     string
+	Password  string
+	ClientIP  string
+	Challenge string
 }
 
-func (a *AuthService) GenerateApiKey(ctx context.Context) (string, error) {
-	var apiKey string
-	for {
-		apiKey = GenerateUuidV4()
+type LoginInput struct {
+	Email    string
+	Password string
+	ClientIP string
+}
 
-		isKeyUnique, err := a.userRepo.IsApiKeyUnique(ctx, apiKey)
-		if err != nil {
-			errMsg := fmt.Sprintf("couldn't verify if api key %s is unique", apiKey)
-			return "", NewServiceError(ErrInternal, errMsg, err)
-		}
+func NewAuthService(
+	userRepo AuthUserRepo,
+	apiKeyRepo AuthApiKeyRepo,
+	transactor Transactor,
+	guard AuthGuard,
+	audit AuditRecorder,
+	challenge ChallengeVerifier,
+) *AuthService {
+	return &AuthService{
+		userRepo:   userRepo,
+		apiKeyRepo: apiKeyRepo,
+		transactor: transactor,
+		guard:      guard,
+		audit:      audit,
+		challenge:  challenge,
+	}
+}
+
+// Creates the user together with a default API key. Rejected challenges
+// and taken emails count as failures of the client IP
+func (a *AuthService) RegisterNewUser(
+	ctx context.Context,
+	input RegisterUserInput,
+) (*domain.User, *domain.ApiKey, error) {
+	if err := a.guard.Check(ctx, "", input.ClientIP); err != nil {
+		return nil, nil, err
+	}
+
+	if err := a.verifyChallenge(ctx, input); err != nil {
+		a.guard.RecordFailure(ctx, "", input.ClientIP)
+		return nil, nil, err
+	}
 
-		if isKeyUnique {
-			break
+	user, apiKey, err := a.registerNewUser(ctx, input.Email, input.Password)
+	if errors.Is(err, ErrUserAlreadyExists) {
+		a.guard.RecordFailure(ctx, "", input.ClientIP)
+	}
+
+	return user, apiKey, err
+}
+
+func (a *AuthService) verifyChallenge(ctx context.Context, input RegisterUserInput) error {
+	if a.challenge == nil {
+		return nil
+	}
+
+	err := a.challenge.Verify(ctx, input.Email, input.Challenge, input.ClientIP)
+	if err != nil {
+		if errors.Is(err, challenge.ErrChallengeFailed) {
+			return NewServiceError(ErrChallengeFailed, "registration challenge rejected", err)
 		}
+		return NewServiceError(ErrInternal, "failed to verify registration challenge", err)
 	}
 
-	return apiKey, nil
+	return nil
 }
 
-func (a *AuthService) RegisterNewUser(
+func (a *AuthService) registerNewUser(
 	ctx context.Context,
 	email string,
 	password string,
-) (*domain.User, error) {
-	apiKey, err := a.GenerateApiKey(ctx)
+) (*domain.User, *domain.ApiKey, error) {
+	issued, err := generateApiKey(ctx, a.apiKeyRepo)
 	if err != nil {
-		return nil, NewServiceError(ErrInternal, "failed to generate api key", err)
+		return nil, nil, NewServiceError(ErrInternal, "failed to generate api key", err)
 	}
 
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		return nil, NewServiceError(ErrInternal, "failed to hash password", err)
+		return nil, nil, NewServiceError(ErrInternal, "failed to hash password", err)
 	}
 
 	user := &domain.User{
 		Email:    email,
 		Password: string(passwordHash),
-		APIKey:   apiKey,
 	}
 
-	err = a.userRepo.CreateNew(ctx, user)
-	if err != nil {
-		if errors.Is(err, repositories.ErrAlreadyExists) {
-			return nil, NewServiceError(ErrUserAlreadyExists, "user already exists", err)
+	var apiKey *domain.ApiKey
+
+	err = a.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		err := a.userRepo.CreateNewTx(ctx, tx, user)
+		if err != nil {
+			if errors.Is(err, repositories.ErrAlreadyExists) {
+				return NewServiceError(ErrUserAlreadyExists, "user already exists", err)
+			}
+			return NewServiceError(ErrUserNotCreated, "database error", err)
 		}
-		return nil, NewServiceError(ErrUserNotCreated, "database error", err)
+
+		apiKey = newDefaultApiKey(user, issued)
+		err = a.apiKeyRepo.CreateNewTx(ctx, tx, apiKey)
+		if err != nil {
+			return NewServiceError(ErrUserNotCreated, "failed to create api key", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return user, nil
+	apiKey.Key = issued.Key
+	return user, apiKey, nil
 }
 
+// Checks credentials and issues a new login key with the scopes of the
+// default one. Other keys are left alone so integrations using them keep
+// working, only login keys past the newest maxLoginApiKeys are revoked.
+// Failed attempts are throttled by the guard
 func (a *AuthService) VerifyCredentials(
 	ctx context.Context,
-	email string,
-	password string,
-) (*domain.User, error) {
+	input LoginInput,
+) (*domain.User, *domain.ApiKey, error) {
+	if err := a.guard.Check(ctx, input.Email, input.ClientIP); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := a.checkPassword(ctx, input.Email, input.Password)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCredentials) {
+			a.guard.RecordFailure(ctx, input.Email, input.ClientIP)
+			a.recordLoginFailure(ctx, user, input.Email)
+		}
+		return nil, nil, err
+	}
+
+	if user.DisabledAt.Valid {
+		errMsg := fmt.Sprintf("user %s is disabled", user.ID)
+		return nil, nil, NewServiceError(ErrUserDisabled, errMsg, nil)
+	}
+
+	issued, err := generateApiKey(ctx, a.apiKeyRepo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	apiKey := newLoginApiKey(user, issued)
+	err = a.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := a.apiKeyRepo.CreateNewTx(ctx, tx, apiKey); err != nil {
+			return NewServiceError(ErrInternal, "failed to create login api key", err)
+		}
+		if err := a.apiKeyRepo.RevokeOldLoginKeysTx(ctx, tx, user.ID, maxLoginApiKeys); err != nil {
+			return NewServiceError(ErrInternal, "failed to revoke old login api keys", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a.guard.RecordSuccess(ctx, input.Email, input.ClientIP)
+	a.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionLoginSucceeded,
+		ActorID:    user.ID,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   user.ID,
+		Details:    map[string]any{"api_key_id": apiKey.ID},
+	})
+
+	apiKey.Key = issued.Key
+	return user, apiKey, nil
+}
+
+// User is returned along with ErrInvalidCredentials when only the password is wrong
+func (a *AuthService) checkPassword(ctx context.Context, email, password string) (*domain.User, error) {
 	user, err := a.userRepo.GetByEmail(ctx, email)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to find user %s", email)
@@ -94,30 +242,71 @@ func (a *AuthService) VerifyCredentials(
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, NewServiceError(ErrInvalidCredentials, "failed to compare password hashes", err)
+		return user, NewServiceError(ErrInvalidCredentials, "failed to compare password hashes", err)
 	}
 
 	return user, nil
 }
 
+// Failures for unknown emails belong to no account
+func (a *AuthService) recordLoginFailure(ctx context.Context, user *domain.User, email string) {
+	entry := AuditEntry{
+		Action:     domain.AuditActionLoginFailed,
+		TargetType: domain.AuditTargetUser,
+		Details:    map[string]any{"email": email},
+	}
+	if user != nil {
+		entry.UserID = user.ID
+		entry.TargetID = user.ID
+	}
+
+	a.audit.Record(ctx, entry)
+}
+
+// Replaces the value of the key used for the request
 func (a *AuthService) RotateApiKey(
 	ctx context.Context,
-	userID string,
-) (*domain.User, error) {
-	apiKey, err := a.GenerateApiKey(ctx)
+	keyID string,
+) (*domain.ApiKey, error) {
+	apiKey, err := rotateApiKey(ctx, a.apiKeyRepo, keyID)
 	if err != nil {
-		return nil, NewServiceError(ErrInternal, "failed to generate api key", err)
+		return nil, err
 	}
 
-	user, err := a.userRepo.UpdateApiKey(ctx, userID, apiKey)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to update api key. ID: %s; key: %s", userID, apiKey)
-		return nil, NewServiceError(ErrInternal, errMsg, err)
+	recordKeyRotation(ctx, a.audit, apiKey, apiKey.UserID)
+	return apiKey, nil
+}
+
+// Admins also get the admin scope on their default key
+func newDefaultApiKey(user *domain.User, issued *issuedApiKey) *domain.ApiKey {
+	return &domain.ApiKey{
+		UserID:  user.ID,
+		Name:    domain.DefaultApiKeyName,
+		Prefix:  issued.Prefix,
+		KeyHash: issued.KeyHash,
+		Scopes:  defaultApiKeyScopes(user),
 	}
+}
 
-	return user, nil
+// Named after its prefix so every login gets its own key
+func newLoginApiKey(user *domain.User, issued *issuedApiKey) *domain.ApiKey {
+	return &domain.ApiKey{
+		UserID:    user.ID,
+		Name:      domain.LoginApiKeyNamePrefix + issued.Prefix,
+		Prefix:    issued.Prefix,
+		KeyHash:   issued.KeyHash,
+		Scopes:    defaultApiKeyScopes(user),
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(loginApiKeyTTL), Valid: true},
+	}
 }
 
-func GenerateUuidV4() string {
-	return uuid.New().String()
+func defaultApiKeyScopes(user *domain.User) []string {
+	scopes := make([]string, len(domain.DefaultApiKeyScopes))
+	for i, scope := range domain.DefaultApiKeyScopes {
+		scopes[i] = string(scope)
+	}
+	if user.Role == domain.UserRoleAdmin {
+		scopes = append(scopes, string(domain.ApiKeyScopeAdmin))
+	}
+	return scopes
 }
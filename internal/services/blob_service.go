@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"qvarkk/kvault/internal/blob"
+)
+
+type SignedBlobStore interface {
+	Get(ctx context.Context, key string) (io.ReadCloser, *blob.ObjectInfo, error)
+	VerifyPresigned(key string, query url.Values) (string, error)
+}
+
+type SignedObject struct {
+	Body     io.ReadCloser
+	Info     *blob.ObjectInfo
+	Filename string
+}
+
+// Serves downloads of the local blob store, S3 links never reach the API
+type BlobService struct {
+	store SignedBlobStore
+}
+
+func NewBlobService(store SignedBlobStore) *BlobService {
+	return &BlobService{store: store}
+}
+
+// Caller has to close the body
+func (s *BlobService) OpenSigned(ctx context.Context, key string, query url.Values) (*SignedObject, error) {
+	filename, err := s.store.VerifyPresigned(key, query)
+	if err != nil {
+		return nil, NewServiceError(ErrForbidden, "invalid download link", err)
+	}
+
+	body, info, err := s.store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+			return nil, NewServiceError(ErrFileNotFound, "not found", err)
+		}
+		return nil, NewServiceError(ErrInternal, "failed to open stored file", err)
+	}
+
+	return &SignedObject{
+		Body:     body,
+		Info:     info,
+		Filename: filename,
+	}, nil
+}
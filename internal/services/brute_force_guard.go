@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type AttemptTracker interface {
+	AddFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Block(ctx context.Context, key string, d time.Duration) error
+	BlockedFor(ctx context.Context, keys ...string) (time.Duration, error)
+	Reset(ctx context.Context, keys ...string) error
+}
+
+type BruteForceConfig struct {
+	// Failures are forgotten this long after the first one
+	FailureWindow time.Duration
+	// Failures allowed before delays kick in
+	FreeAttempts int
+	// Delay doubles with every failure past free attempts up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Failures that lock the email or IP out for LockoutDuration
+	MaxEmailFailures int
+	MaxIpFailures    int
+	LockoutDuration  time.Duration
+}
+
+// Slows down and then locks out repeated failed logins per email and per client IP.
+// Redis failures are logged and let requests through
+type BruteForceGuard struct {
+	tracker AttemptTracker
+	audit   AuditRecorder
+	config  BruteForceConfig
+}
+
+func NewBruteForceGuard(tracker AttemptTracker, audit AuditRecorder, config BruteForceConfig) *BruteForceGuard {
+	return &BruteForceGuard{
+		tracker: tracker,
+		audit:   audit,
+		config:  config,
+	}
+}
+
+type guardSubject struct {
+	kind        string
+	value       string
+	maxFailures int
+}
+
+// Fails with ErrTooManyAttempts while the email or the IP has to wait.
+// Empty email checks only the IP
+func (g *BruteForceGuard) Check(ctx context.Context, email, clientIP string) error {
+	subjects := g.subjects(email, clientIP)
+	keys := make([]string, len(subjects))
+	for i, subject := range subjects {
+		keys[i] = blockKey(subject)
+	}
+
+	wait, err := g.tracker.BlockedFor(ctx, keys...)
+	if err != nil {
+		logger.FromContext(ctx).Warn("Brute-force guard check failed", zap.Error(err))
+		return nil
+	}
+
+	if wait > 0 {
+		errMsg := fmt.Sprintf("attempts from %s for %q are blocked for %s", clientIP, email, wait.Round(time.Second))
+		return NewServiceError(ErrTooManyAttempts, errMsg, nil)
+	}
+
+	return nil
+}
+
+func (g *BruteForceGuard) RecordFailure(ctx context.Context, email, clientIP string) {
+	for _, subject := range g.subjects(email, clientIP) {
+		if err := g.recordFailure(ctx, subject); err != nil {
+			logger.FromContext(ctx).Warn(
+				"Brute-force guard failed to record failure",
+				zap.Error(err),
+				zap.String(subject.kind, subject.value),
+			)
+		}
+	}
+}
+
+// Clears the email counter. The IP one stays, otherwise one valid account
+// would be enough to keep guessing others from the same address
+func (g *BruteForceGuard) RecordSuccess(ctx context.Context, email, clientIP string) {
+	subject := guardSubject{kind: "email", value: normalizeEmail(email)}
+	if err := g.tracker.Reset(ctx, failureKey(subject), blockKey(subject)); err != nil {
+		logger.FromContext(ctx).Warn("Brute-force guard failed to reset failures", zap.Error(err))
+	}
+}
+
+func (g *BruteForceGuard) recordFailure(ctx context.Context, subject guardSubject) error {
+	failures, err := g.tracker.AddFailure(ctx, failureKey(subject), g.config.FailureWindow)
+	if err != nil {
+		return err
+	}
+
+	if failures >= subject.maxFailures {
+		if failures == subject.maxFailures {
+			g.audit.Record(ctx, AuditEntry{
+				Action:     domain.AuditActionLockout,
+				TargetType: domain.AuditTargetUser,
+				Details: map[string]any{
+					subject.kind: subject.value,
+					"failures":   failures,
+					"duration":   g.config.LockoutDuration.String(),
+				},
+			})
+		}
+		return g.tracker.Block(ctx, blockKey(subject), g.config.LockoutDuration)
+	}
+
+	if failures <= g.config.FreeAttempts {
+		return nil
+	}
+
+	delay := g.config.BaseDelay << min(failures-g.config.FreeAttempts-1, 20)
+	return g.tracker.Block(ctx, blockKey(subject), min(delay, g.config.MaxDelay))
+}
+
+func (g *BruteForceGuard) subjects(email, clientIP string) []guardSubject {
+	subjects := []guardSubject{{kind: "ip", value: clientIP, maxFailures: g.config.MaxIpFailures}}
+	if email != "" {
+		subjects = append(subjects, guardSubject{
+			kind:        "email",
+			value:       normalizeEmail(email),
+			maxFailures: g.config.MaxEmailFailures,
+		})
+	}
+	return subjects
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failureKey(subject guardSubject) string {
+	return "bruteforce:failures:" + subject.kind + ":" + subject.value
+}
+
+func blockKey(subject guardSubject) string {
+	return "bruteforce:block:" + subject.kind + ":" + subject.value
+}
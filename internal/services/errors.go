@@ -9,10 +9,29 @@ var (
 	ErrForbidden          = errors.New("services: access forbidden")
 	ErrUnauthenticated    = errors.New("services: unauthenticated")
 	ErrInvalidCredentials = errors.New("services: invalid credentials")
+	ErrRateLimited        = errors.New("services: rate limit exceeded")
+	ErrTooManyAttempts    = errors.New("services: too many failed attempts")
+	ErrChallengeFailed    = errors.New("services: challenge failed")
 
 	ErrUserNotCreated    = errors.New("services: failed to create user")
 	ErrUserAlreadyExists = errors.New("services: user already exists")
 	ErrUserNotFound      = errors.New("services: user was not found")
+	ErrUserDisabled      = errors.New("services: user is disabled")
+	ErrAdminSelfAction   = errors.New("services: admin can't do this to their own account")
+
+	ErrPasswordMismatch     = errors.New("services: current password doesn't match")
+	ErrPasswordResetInvalid = errors.New("services: password reset token is invalid or expired")
+
+	ErrFileTooLarge         = errors.New("services: file exceeds max file size")
+	ErrStorageQuotaExceeded = errors.New("services: storage quota exceeded")
+	ErrItemQuotaExceeded    = errors.New("services: item quota exceeded")
+
+	ErrApiKeyNotFound          = errors.New("services: api key was not found")
+	ErrApiKeyAlreadyExists     = errors.New("services: api key already exists")
+	ErrApiKeyInvalid           = errors.New("services: api key definition is invalid")
+	ErrApiKeyScopeMissing      = errors.New("services: api key lacks required scope")
+	ErrApiKeyScopeExceeded     = errors.New("services: api key can't grant more scopes than it has")
+	ErrApiKeyScopeInsufficient = errors.New("services: api key can't manage a key with more scopes than it has")
 
 	ErrItemNotCreated = errors.New("service: failed to create item")
 	ErrItemNotUpdated = errors.New("service: failed to update item")
@@ -21,16 +40,36 @@ var (
 
 	ErrFileNotCreated = errors.New("service: failed to create file")
 	ErrFileNotFound   = errors.New("service: file was not found")
+	ErrFileBusy       = errors.New("service: file is still being processed")
+	ErrFileUnreadable = errors.New("service: file content can't be read")
+
+	ErrFilePreviewNotFound = errors.New("service: file preview was not found")
 
 	ErrStopwordNotCreated    = errors.New("service: failed to create stopword")
 	ErrStopwordAlreadyExists = errors.New("service: stopword already exists")
 	ErrStopwordNotFound      = errors.New("service: stopword was not found")
+	ErrStopwordImportInvalid = errors.New("service: stopword import is invalid")
+	ErrStopwordPackNotFound  = errors.New("service: stopword pack was not found")
+	ErrDefaultStopwordExists = errors.New("service: default stopword already exists")
 
 	ErrTagNotCreated    = errors.New("service: failed to create tag")
 	ErrTagNotFound      = errors.New("service: tag was not found")
 	ErrTagAlreadyExists = errors.New("service: tag already exists")
+	ErrTagMergeInvalid  = errors.New("service: tag can't be merged into itself")
+
+	ErrTagRuleNotFound = errors.New("service: tag rule was not found")
+	ErrTagRuleInvalid  = errors.New("service: tag rule definition is invalid")
+
+	ErrTagAliasAlreadyExists = errors.New("service: tag alias already exists")
+	ErrTagAliasNotFound      = errors.New("service: tag alias was not found")
+
+	ErrTaskNotFound = errors.New("service: archived task was not found")
+
+	ErrLogComponentNotFound = errors.New("service: log component was not found")
+	ErrLogComponentUnused   = errors.New("service: log component isn't used by the process")
+	ErrInvalidLogLevel      = errors.New("service: log level is not valid")
 
-	ErrPdfFileFormat = errors.New("services: provided file has to be a PDF file")
+	ErrUnsupportedFileFormat = errors.New("services: provided file has to be a PDF or an image")
 )
 
 type ServiceError struct {
@@ -1,23 +1,31 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
-	"net/url"
+	"path"
 	"path/filepath"
-	"qvarkk/kvault/internal/aws"
+	"qvarkk/kvault/internal/blob"
 	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/envelope"
 	"qvarkk/kvault/internal/redis"
+	"qvarkk/kvault/internal/repositories"
 	"qvarkk/kvault/internal/tasks"
+	"qvarkk/kvault/logger"
+	"slices"
+	"strings"
 	"time"
 
-	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 type FileRepo interface {
@@ -28,30 +36,96 @@ type FileRepo interface {
 	GetDeletedByIDForUpdate(context.Context, *sqlx.Tx, string) (*domain.File, error)
 	SoftDeleteByIDTx(context.Context, *sqlx.Tx, string) error
 	RestoreByIDTx(context.Context, *sqlx.Tx, string) error
+	ResetContentTx(context.Context, *sqlx.Tx, string) error
+	ListForReprocess(ctx context.Context, params domain.ReprocessFileFilter, afterID string, limit uint64) ([]domain.File, error)
 }
 
+type FilePreviewRepo interface {
+	GetByFilePage(ctx context.Context, fileID string, page int) (*domain.FilePreview, error)
+	FindThumbnailed(ctx context.Context, fileIDs []string) (map[string]bool, error)
+	ReplaceForFileTx(ctx context.Context, tx *sqlx.Tx, fileID string, previews []domain.FilePreview) ([]string, error)
+}
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, *blob.ObjectInfo, error)
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	Head(ctx context.Context, key string) (*blob.ObjectInfo, error)
+	Delete(ctx context.Context, keys ...string) error
+	PresignGet(ctx context.Context, key, filename string) (*blob.PresignedURL, error)
+}
+
+type FileCipher interface {
+	Seal(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *envelope.Envelope, error)
+	SealAlways(ctx context.Context, userID string, plaintext io.Reader) (io.Reader, *envelope.Envelope, error)
+	Open(ctx context.Context, userID string, env envelope.Envelope, sealed io.ReadCloser) (io.ReadCloser, error)
+	OpenSeekable(ctx context.Context, userID string, env envelope.Envelope, sealed io.ReadSeekCloser) (io.ReadSeekCloser, error)
+	SealedSize(size int64) int64
+}
+
+const uploadsPrefix = "uploads"
+
+// Files matched by a bulk reprocess are queued in batches of this size
+const reprocessBatchSize = 500
+
 type FileService struct {
-	fileRepo   FileRepo
-	transactor Transactor
-	redis      *redis.Redis
-	aws        *aws.Aws
+	fileRepo    FileRepo
+	tagRepo     TagRepo
+	previewRepo FilePreviewRepo
+	transactor  Transactor
+	redis       *redis.Redis
+	blobStore   BlobStore
+	// Optional, files are stored as is when nil
+	cipher FileCipher
+	audit  AuditRecorder
 }
 
 type CreateFileInput struct {
 	UserID       string
 	OriginalName string
 	S3Key        string
+	KeyID        string
+	WrappedKey   []byte
 	Size         int64
 	MimeType     string
 	Status       string
 }
 
-func NewFileService(fileRepo FileRepo, transactor Transactor, redis *redis.Redis, aws *aws.Aws) *FileService {
+// Where an upload was stored, key fields are empty for unencrypted files
+type StoredUpload struct {
+	Key        string
+	KeyID      string
+	WrappedKey []byte
+}
+
+// Body is plaintext that can be seeked for range requests, the caller has
+// to close it. ETag and LastModified are of the stored object
+type FileContent struct {
+	File         *domain.File
+	Body         io.ReadSeekCloser
+	ETag         string
+	LastModified time.Time
+}
+
+func NewFileService(
+	fileRepo FileRepo,
+	tagRepo TagRepo,
+	previewRepo FilePreviewRepo,
+	transactor Transactor,
+	redis *redis.Redis,
+	blobStore BlobStore,
+	cipher FileCipher,
+	audit AuditRecorder,
+) *FileService {
 	return &FileService{
-		fileRepo:   fileRepo,
-		transactor: transactor,
-		redis:      redis,
-		aws:        aws,
+		fileRepo:    fileRepo,
+		tagRepo:     tagRepo,
+		previewRepo: previewRepo,
+		transactor:  transactor,
+		redis:       redis,
+		blobStore:   blobStore,
+		cipher:      cipher,
+		audit:       audit,
 	}
 }
 
@@ -60,11 +134,16 @@ func (s *FileService) CreateNew(ctx context.Context, input CreateFileInput) (*do
 		UserID:       input.UserID,
 		OriginalName: input.OriginalName,
 		S3Key:        input.S3Key,
+		WrappedKey:   input.WrappedKey,
 		Size:         input.Size,
 		MimeType:     input.MimeType,
 		Status:       domain.FileStatus(input.Status),
 	}
 
+	if input.KeyID != "" {
+		file.KeyID = sql.NullString{String: input.KeyID, Valid: true}
+	}
+
 	err := s.fileRepo.CreateNew(ctx, file)
 	if err != nil {
 		return nil, NewServiceError(ErrFileNotCreated, "database error", err)
@@ -78,10 +157,185 @@ func (s *FileService) List(ctx context.Context, params domain.ListFileFilter) ([
 	if err != nil {
 		return nil, 0, NewServiceError(ErrInternal, "list files internal error", err)
 	}
-	return files, count, err
+
+	ids := make([]string, len(files))
+	for i, file := range files {
+		ids[i] = file.ID
+	}
+
+	tagsByFile, err := s.tagRepo.FindByFileIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "get file tags internal error", err)
+	}
+
+	thumbnailed, err := s.previewRepo.FindThumbnailed(ctx, ids)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "get file thumbnails internal error", err)
+	}
+
+	for i := range files {
+		files[i].Tags = tagsByFile[files[i].ID]
+		files[i].HasThumbnail = thumbnailed[files[i].ID]
+	}
+
+	return files, count, nil
 }
 
+// URL is empty for encrypted files, the store only has their ciphertext
+// and they can be downloaded through OpenContent only
 func (s *FileService) GetFilePresignedUrl(ctx context.Context, fileID, userID string) (*domain.PresignedURL, error) {
+	file, err := s.getOwnedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.KeyID.Valid {
+		return &domain.PresignedURL{
+			Filename: file.OriginalName,
+			MimeType: file.MimeType,
+			Size:     file.Size,
+		}, nil
+	}
+
+	presigned, err := s.blobStore.PresignGet(ctx, file.S3Key, file.OriginalName)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "failed to presign file url", err)
+	}
+
+	return &domain.PresignedURL{
+		URL:       presigned.URL,
+		Filename:  file.OriginalName,
+		MimeType:  file.MimeType,
+		Size:      file.Size,
+		ExpiresAt: presigned.ExpiresAt,
+	}, nil
+}
+
+func (s *FileService) OpenContent(ctx context.Context, fileID, userID string) (*FileContent, error) {
+	file, err := s.getOwnedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var env *envelope.Envelope
+	if file.KeyID.Valid {
+		env = &envelope.Envelope{WrappedKey: file.WrappedKey, KeyID: file.KeyID.String}
+	}
+
+	return s.openObject(ctx, file, file.S3Key, env)
+}
+
+// Thumbnail is page 0. Previews are PNG images, made by the worker once
+// the file is processed
+func (s *FileService) OpenPreview(ctx context.Context, fileID, userID string, page int) (*FileContent, error) {
+	file, err := s.getOwnedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	preview, err := s.previewRepo.GetByFilePage(ctx, file.ID, page)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotFound) {
+			errMsg := fmt.Sprintf("file %s has no preview of page %d", file.ID, page)
+			return nil, NewServiceError(ErrFilePreviewNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, "get file preview internal error", err)
+	}
+
+	var env *envelope.Envelope
+	if preview.KeyID.Valid {
+		env = &envelope.Envelope{WrappedKey: preview.WrappedKey, KeyID: preview.KeyID.String}
+	}
+
+	return s.openObject(ctx, file, preview.S3Key, env)
+}
+
+// Opens a stored object of the file for random access, decrypting it when
+// an envelope is given
+func (s *FileService) openObject(
+	ctx context.Context,
+	file *domain.File,
+	key string,
+	env *envelope.Envelope,
+) (*FileContent, error) {
+	if env != nil && s.cipher == nil {
+		return nil, NewServiceError(ErrInternal, "file is encrypted but no key provider is configured", nil)
+	}
+
+	info, err := s.blobStore.Head(ctx, key)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "failed to get stored file info", err)
+	}
+
+	var body io.ReadSeekCloser = blob.NewObjectReader(ctx, s.blobStore, key, info.Size)
+
+	if env != nil {
+		body, err = s.cipher.OpenSeekable(ctx, file.UserID, *env, body)
+		if err != nil {
+			return nil, NewServiceError(ErrInternal, "failed to decrypt stored file", err)
+		}
+	}
+
+	return &FileContent{
+		File:         file,
+		Body:         body,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}, nil
+}
+
+// Keywords from the file metadata that aren't bound to it as tags yet
+func (s *FileService) ListTagCandidates(ctx context.Context, fileID, userID string) ([]domain.TagCandidate, error) {
+	file, err := s.getOwnedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Metadata == nil || len(file.Metadata.Keywords) == 0 {
+		return []domain.TagCandidate{}, nil
+	}
+
+	tagsByFile, err := s.tagRepo.FindByFileIDs(ctx, []string{file.ID})
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "get file tags internal error", err)
+	}
+
+	bound := make(map[string]bool)
+	for _, tag := range tagsByFile[file.ID] {
+		bound[tag.ID] = true
+		bound[normalizeTagAlias(tag.Name)] = true
+	}
+
+	words := make([]string, len(file.Metadata.Keywords))
+	for i, keyword := range file.Metadata.Keywords {
+		words[i] = normalizeTagAlias(keyword)
+	}
+
+	tags, err := s.tagRepo.ResolveNames(ctx, userID, words)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "resolve tag names internal error", err)
+	}
+
+	candidates := make([]domain.TagCandidate, 0, len(words))
+	for _, word := range words {
+		if bound[word] {
+			continue
+		}
+
+		candidate := domain.TagCandidate{Word: word}
+		if tag, ok := tags[word]; ok {
+			if bound[tag.ID] {
+				continue
+			}
+			candidate.Tag = &tag
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+func (s *FileService) getOwnedFile(ctx context.Context, fileID, userID string) (*domain.File, error) {
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
 		return nil, NewServiceError(ErrFileNotFound, "not found", err)
@@ -91,43 +345,73 @@ func (s *FileService) GetFilePresignedUrl(ctx context.Context, fileID, userID st
 		return nil, NewServiceError(ErrFileNotFound, "forbidden", nil)
 	}
 
-	presignClient := s3.NewPresignClient(s.aws.S3Client)
-	contentDispositionParam := fmt.Sprintf(
-		"attachment; filename*=UTF-8''%s",
-		url.PathEscape(file.OriginalName),
-	)
+	return file, nil
+}
+
+// Reads plaintext of a stored file, decrypting it when it was encrypted
+func openStoredFile(ctx context.Context, store BlobStore, cipher FileCipher, file *domain.File) (io.ReadCloser, error) {
+	if file.KeyID.Valid && cipher == nil {
+		return nil, errors.New("file is encrypted but no key provider is configured")
+	}
 
-	presignedResult, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
-		Bucket:                     awsSdk.String(s.aws.BucketName),
-		Key:                        awsSdk.String(file.S3Key),
-		ResponseContentDisposition: awsSdk.String(contentDispositionParam),
-	}, s3.WithPresignExpires(time.Second*time.Duration(s.aws.UrlExpirationTimeSeconds)))
+	body, _, err := store.Get(ctx, file.S3Key)
+	if err != nil {
+		return nil, err
+	}
 
-	expiresAt := time.Now().UTC().Add(time.Second * time.Duration(s.aws.UrlExpirationTimeSeconds))
+	if !file.KeyID.Valid {
+		return body, nil
+	}
 
-	return &domain.PresignedURL{
-		URL:       presignedResult.URL,
-		Filename:  file.OriginalName,
-		MimeType:  file.MimeType,
-		Size:      file.Size,
-		ExpiresAt: expiresAt,
-	}, nil
+	env := envelope.Envelope{
+		WrappedKey: file.WrappedKey,
+		KeyID:      file.KeyID.String,
+	}
+
+	plaintext, err := cipher.Open(ctx, file.UserID, env, body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return plaintext, nil
 }
 
 func (s *FileService) DeleteByID(ctx context.Context, fileID, userID string) error {
-	return s.authorizeAndMutateTx(
+	err := s.authorizeAndMutateTx(
 		ctx, fileID, userID,
 		s.fileRepo.GetActiveByIDForUpdate,
 		s.fileRepo.SoftDeleteByIDTx,
 	)
+	if err != nil {
+		return err
+	}
+
+	s.recordFileEvent(ctx, domain.AuditActionFileDeleted, fileID, userID)
+	return nil
 }
 
 func (s *FileService) RestoreByID(ctx context.Context, fileID, userID string) error {
-	return s.authorizeAndMutateTx(
+	err := s.authorizeAndMutateTx(
 		ctx, fileID, userID,
 		s.fileRepo.GetDeletedByIDForUpdate,
 		s.fileRepo.RestoreByIDTx,
 	)
+	if err != nil {
+		return err
+	}
+
+	s.recordFileEvent(ctx, domain.AuditActionFileRestored, fileID, userID)
+	return nil
+}
+
+func (s *FileService) recordFileEvent(ctx context.Context, action domain.AuditAction, fileID, userID string) {
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetFile,
+		TargetID:   fileID,
+	})
 }
 
 func (s *FileService) authorizeAndMutateTx(
@@ -156,59 +440,233 @@ func (s *FileService) authorizeAndMutateTx(
 	return err
 }
 
-func (s *FileService) ValidatePdfFile(ctx context.Context, fileHeader *multipart.FileHeader) error {
-	ext := filepath.Ext(fileHeader.Filename)
-	if ext != ".pdf" {
-		return NewServiceError(ErrPdfFileFormat, "invalid file extension", nil)
-	}
+// Extensions accepted for each detected content type
+var uploadTypes = map[string][]string{
+	"application/pdf": {".pdf"},
+	"image/png":       {".png"},
+	"image/jpeg":      {".jpg", ".jpeg"},
+	"image/tiff":      {".tif", ".tiff"},
+}
+
+func isImageType(mimeType string) bool {
+	return strings.HasPrefix(mimeType, "image/")
+}
 
+// Returns the content type detected from the first bytes, the extension
+// has to agree with it
+func (s *FileService) ValidateUploadFile(ctx context.Context, fileHeader *multipart.FileHeader) (string, error) {
 	file, err := fileHeader.Open()
 	if err != nil {
-		return NewServiceError(ErrInternal, "failed to open uploaded file", err)
+		return "", NewServiceError(ErrInternal, "failed to open uploaded file", err)
 	}
 	defer file.Close()
 
 	buffer := make([]byte, 512)
 	n, err := file.Read(buffer)
 	if err != nil {
-		return NewServiceError(ErrInternal, "failed to read uploaded file", err)
+		return "", NewServiceError(ErrInternal, "failed to read uploaded file", err)
 	}
 
-	contentType := http.DetectContentType(buffer[:n])
-	if contentType != "application/pdf" {
-		return NewServiceError(ErrPdfFileFormat, "invalid file content type", nil)
+	contentType := detectContentType(buffer[:n])
+	extensions, ok := uploadTypes[contentType]
+	if !ok {
+		return "", NewServiceError(ErrUnsupportedFileFormat, "invalid file content type", nil)
 	}
 
-	return nil
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if !slices.Contains(extensions, ext) {
+		return "", NewServiceError(ErrUnsupportedFileFormat, "invalid file extension", nil)
+	}
+
+	return contentType, nil
 }
 
-func (s *FileService) UploadPdfFileToS3(ctx context.Context, fileHeader *multipart.FileHeader) (string, error) {
+// http.DetectContentType doesn't know TIFF
+func detectContentType(head []byte) string {
+	if bytes.HasPrefix(head, []byte("II*\x00")) || bytes.HasPrefix(head, []byte("MM\x00*")) {
+		return "image/tiff"
+	}
+	return http.DetectContentType(head)
+}
+
+// Encrypts the file on the way to the store when the cipher seals uploads
+func (s *FileService) UploadFile(
+	ctx context.Context,
+	userID string,
+	fileHeader *multipart.FileHeader,
+	mimeType string,
+) (*StoredUpload, error) {
 	file, err := fileHeader.Open()
 	if err != nil {
-		return "", err
+		return nil, NewServiceError(ErrInternal, "failed to open uploaded file", err)
 	}
 	defer file.Close()
 
-	filename := uuid.New().String() + ".pdf"
-	_, err = s.aws.S3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: awsSdk.String(s.aws.BucketName),
-		Key:    awsSdk.String(s.aws.GetKey(filename)),
-		Body:   file,
-	})
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	upload := &StoredUpload{
+		Key: path.Join(uploadsPrefix, uuid.New().String()+ext),
+	}
+
+	var body io.Reader = file
+	size := fileHeader.Size
+	contentType := mimeType
 
-	return s.aws.GetKey(filename), err
+	if s.cipher != nil {
+		sealed, env, err := s.cipher.Seal(ctx, userID, file)
+		if err != nil {
+			return nil, NewServiceError(ErrInternal, "failed to encrypt uploaded file", err)
+		}
+
+		if env != nil {
+			body = sealed
+			size = s.cipher.SealedSize(size)
+			contentType = "application/octet-stream"
+			upload.KeyID = env.KeyID
+			upload.WrappedKey = env.WrappedKey
+		}
+	}
+
+	err = s.blobStore.Put(ctx, upload.Key, body, size, contentType)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "failed to store uploaded file", err)
+	}
+
+	return upload, nil
 }
 
-func (s *FileService) EnqueuePdfProcessTask(ctx context.Context, payload tasks.PdfProcessPayload) (*asynq.TaskInfo, error) {
-	task, err := tasks.NewPdfProcessTask(payload)
+func (s *FileService) EnqueuePdfProcessTask(
+	ctx context.Context,
+	payload tasks.PdfProcessPayload,
+	opts ...asynq.Option,
+) (*asynq.TaskInfo, error) {
+	task, err := tasks.NewPdfProcessTask(ctx, payload)
 	if err != nil {
 		return nil, NewServiceError(ErrInternal, "failed to create PDF processing task", err)
 	}
 
-	info, err := s.redis.AsynqClient.EnqueueContext(ctx, task)
+	info, err := s.redis.AsynqClient.EnqueueContext(ctx, task, opts...)
 	if err != nil {
 		return nil, NewServiceError(ErrInternal, "failed to enqueue PDF processing task", err)
 	}
 
 	return info, nil
 }
+
+// Clears extracted text, metadata and previews of the file and queues it
+// for processing again
+func (s *FileService) Reprocess(ctx context.Context, fileID, userID string) error {
+	if err := s.reprocessFile(ctx, fileID, userID); err != nil {
+		return err
+	}
+
+	s.recordFileEvent(ctx, domain.AuditActionFileReprocessed, fileID, userID)
+	return nil
+}
+
+// Reprocesses every file matching the filter and returns how many were
+// queued. Files deleted or picked up by the worker meanwhile are skipped
+func (s *FileService) ReprocessMany(ctx context.Context, params domain.ReprocessFileFilter, actorID string) (int, error) {
+	queued := 0
+	afterID := ""
+
+	for {
+		files, err := s.fileRepo.ListForReprocess(ctx, params, afterID, reprocessBatchSize)
+		if err != nil {
+			return queued, NewServiceError(ErrInternal, "list files to reprocess internal error", err)
+		}
+
+		for _, file := range files {
+			err := s.reprocessFile(ctx, file.ID, file.UserID)
+			if errors.Is(err, ErrFileNotFound) || errors.Is(err, ErrFileBusy) {
+				continue
+			}
+			if err != nil {
+				return queued, err
+			}
+			queued++
+		}
+
+		if len(files) < reprocessBatchSize {
+			break
+		}
+		afterID = files[len(files)-1].ID
+	}
+
+	details := map[string]any{"queued": queued}
+	if params.Status != "" {
+		details["status"] = params.Status
+	}
+	if params.MimeType != "" {
+		details["mime_type"] = params.MimeType
+	}
+	if params.CreatedFrom != nil {
+		details["created_from"] = params.CreatedFrom
+	}
+	if params.CreatedTo != nil {
+		details["created_to"] = params.CreatedTo
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionFilesReprocessed,
+		ActorID:    actorID,
+		UserID:     params.UserID,
+		TargetType: domain.AuditTargetFile,
+		Details:    details,
+	})
+
+	return queued, nil
+}
+
+// The task is enqueued before commit, the worker locks the row first so
+// it only sees the reset file. A fresh task ID keeps asynq from treating
+// it as a duplicate of the previous run
+func (s *FileService) reprocessFile(ctx context.Context, fileID, userID string) error {
+	var staleKeys []string
+
+	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		file, err := s.fileRepo.GetActiveByIDForUpdate(ctx, tx, fileID)
+		if err != nil {
+			return NewServiceError(ErrFileNotFound, "not found", err)
+		}
+
+		if file.UserID != userID {
+			return NewServiceError(ErrFileNotFound, "forbidden", nil)
+		}
+
+		if file.Status == domain.FileStatusUploading || file.Status == domain.FileStatusProcessing {
+			return NewServiceError(ErrFileBusy, fmt.Sprintf("file is %s", file.Status), nil)
+		}
+
+		if err := s.fileRepo.ResetContentTx(ctx, tx, fileID); err != nil {
+			return NewServiceError(ErrInternal, "reset file internal error", err)
+		}
+
+		staleKeys, err = s.previewRepo.ReplaceForFileTx(ctx, tx, fileID, nil)
+		if err != nil {
+			return NewServiceError(ErrInternal, "delete file previews internal error", err)
+		}
+
+		payload := tasks.PdfProcessPayload{
+			UserID: userID,
+			FileID: fileID,
+		}
+		taskID := fmt.Sprintf("%s:%s:%s", tasks.TypePdfProcess, fileID, uuid.New())
+
+		_, err = s.EnqueuePdfProcessTask(ctx, payload, asynq.TaskID(taskID))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// rows are gone already, leftover objects only take space. The task may
+	// have run by now, but it writes under a generation of its own, so none
+	// of its previews are among these keys
+	if len(staleKeys) > 0 {
+		if err := s.blobStore.Delete(ctx, staleKeys...); err != nil {
+			logger.FromContext(ctx).Warn("Failed to delete previews of reprocessed file", zap.Error(err), zap.String("file_id", fileID))
+		}
+	}
+
+	return nil
+}
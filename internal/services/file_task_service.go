@@ -3,16 +3,26 @@ package services
 import (
 	"bytes"
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image/png"
 	"io"
 	"os"
-	"qvarkk/kvault/internal/aws"
+	"path"
+	"qvarkk/kvault/internal/docmeta"
 	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/metrics"
+	"qvarkk/kvault/internal/ocr"
+	"qvarkk/kvault/internal/preview"
+	"qvarkk/kvault/internal/repositories"
+	"qvarkk/kvault/logger"
 	"strings"
 
-	awsSdk "github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/ledongthuc/pdf"
+	"go.uber.org/zap"
 )
 
 type UpdateFileInput struct {
@@ -20,6 +30,20 @@ type UpdateFileInput struct {
 	UserID      string
 	Status      *domain.FileStatus
 	TextContent *string
+	Metadata    *domain.FileMetadata
+	// Set together, an empty source is stored as NULL
+	TextSource    *domain.TextSource
+	OcrConfidence *float64
+	// Kept only with the error status, any other status clears it
+	ErrorMessage *string
+}
+
+// Metadata is nil for images, OcrConfidence is nil unless OCR found text
+type ExtractedContent struct {
+	Text          string
+	Metadata      *domain.FileMetadata
+	TextSource    domain.TextSource
+	OcrConfidence *float64
 }
 
 type FileTaskRepo interface {
@@ -27,72 +51,283 @@ type FileTaskRepo interface {
 	UpdateTx(context.Context, *sqlx.Tx, *domain.File) error
 }
 
+type FileTagRuleRepo interface {
+	ApplyToFileTx(context.Context, *sqlx.Tx, string) error
+}
+
+type FilePreviewTaskRepo interface {
+	ReplaceForFileTx(ctx context.Context, tx *sqlx.Tx, fileID string, previews []domain.FilePreview) ([]string, error)
+}
+
+type PageRenderer interface {
+	RenderPage(ctx context.Context, pdfPath string, page, width int) ([]byte, error)
+}
+
+type OcrEngine interface {
+	Recognize(ctx context.Context, imagePath string) (*ocr.Result, error)
+}
+
+type OcrConfig struct {
+	RenderWidth int
+	// Scanned pages past MaxPages are left without text
+	MaxPages int
+}
+
+type PreviewConfig struct {
+	ThumbnailWidth int
+	PageWidth      int
+	// Pages past MaxPages get no preview, zero leaves only the thumbnail
+	MaxPages int
+}
+
+const previewsPrefix = "previews"
+
 type FileTaskService struct {
-	fileRepo   FileTaskRepo
-	transactor Transactor
-	aws        *aws.Aws
+	fileRepo    FileTaskRepo
+	tagRuleRepo FileTagRuleRepo
+	previewRepo FilePreviewTaskRepo
+	transactor  Transactor
+	blobStore   BlobStore
+	cipher      FileCipher
+	// Optional, no previews are made and scanned PDFs aren't OCR'd when nil
+	renderer      PageRenderer
+	previewConfig PreviewConfig
+	// Optional, text is only read from text layers when nil
+	ocr       OcrEngine
+	ocrConfig OcrConfig
 }
 
-func NewFileTaskService(fileRepo FileTaskRepo, transactor Transactor, aws *aws.Aws) *FileTaskService {
+func NewFileTaskService(
+	fileRepo FileTaskRepo,
+	tagRuleRepo FileTagRuleRepo,
+	previewRepo FilePreviewTaskRepo,
+	transactor Transactor,
+	blobStore BlobStore,
+	cipher FileCipher,
+	renderer PageRenderer,
+	previewConfig PreviewConfig,
+	ocr OcrEngine,
+	ocrConfig OcrConfig,
+) *FileTaskService {
 	return &FileTaskService{
-		fileRepo:   fileRepo,
-		transactor: transactor,
-		aws:        aws,
+		fileRepo:      fileRepo,
+		tagRuleRepo:   tagRuleRepo,
+		previewRepo:   previewRepo,
+		transactor:    transactor,
+		blobStore:     blobStore,
+		cipher:        cipher,
+		renderer:      renderer,
+		previewConfig: previewConfig,
+		ocr:           ocr,
+		ocrConfig:     ocrConfig,
 	}
 }
 
-func (s *FileTaskService) ExtractTextFromFile(ctx context.Context, file *domain.File) (string, error) {
-	resp, err := s.aws.S3Client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: awsSdk.String(s.aws.BucketName),
-		Key:    awsSdk.String(file.S3Key),
-	})
+// Copies plaintext of the stored file to a temporary file, which the
+// caller has to close and remove
+func (s *FileTaskService) downloadToTemp(ctx context.Context, file *domain.File) (*os.File, error) {
+	body, err := openStoredFile(ctx, s.blobStore, s.cipher, file)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
+	defer body.Close()
 
-	tmpFile, err := os.CreateTemp("", "*.pdf")
+	tmpFile, err := os.CreateTemp("", "*"+path.Ext(file.S3Key))
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	_, err = io.Copy(tmpFile, body)
+	if err == nil {
+		_, err = tmpFile.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	return tmpFile, nil
+}
+
+// Text is normalized to single spaces. PDF pages without a text layer and
+// images go through OCR when an engine is configured
+func (s *FileTaskService) ExtractContentFromFile(ctx context.Context, file *domain.File) (*ExtractedContent, error) {
+	tmpFile, err := s.downloadToTemp(ctx, file)
+	if err != nil {
+		return nil, err
 	}
 	defer tmpFile.Close()
 	defer os.Remove(tmpFile.Name())
 
-	_, err = io.Copy(tmpFile, resp.Body)
+	var content *ExtractedContent
+	if isImageType(file.MimeType) {
+		content, err = s.extractImage(ctx, tmpFile.Name())
+	} else {
+		var fileInfo os.FileInfo
+		fileInfo, err = tmpFile.Stat()
+		if err != nil {
+			return nil, err
+		}
+		content, err = s.extractPdf(ctx, tmpFile, fileInfo.Size())
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	_, err = tmpFile.Seek(0, io.SeekStart)
+	metrics.ExtractedBytes.WithLabelValues(file.MimeType).Add(float64(file.Size))
+	return content, nil
+}
+
+// The PDF library panics on some malformed files, which is reported as
+// ErrFileUnreadable like any parse error
+func (s *FileTaskService) extractPdf(ctx context.Context, tmpFile *os.File, size int64) (content *ExtractedContent, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			content = nil
+			err = NewServiceError(ErrFileUnreadable, "PDF parser panicked", fmt.Errorf("%v", recovered))
+		}
+	}()
+
+	r, err := pdf.NewReader(tmpFile, size)
 	if err != nil {
-		return "", err
+		return nil, NewServiceError(ErrFileUnreadable, "failed to open PDF", err)
 	}
 
-	fileInfo, err := tmpFile.Stat()
+	var (
+		pages      []string
+		layerPages int
+		ocrPages   int
+		confidence float64
+	)
+
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				font := page.Font(name)
+				fonts[name] = &font
+			}
+		}
+
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, NewServiceError(ErrFileUnreadable, fmt.Sprintf("failed to read page %d", i), err)
+		}
+
+		words := strings.Fields(text)
+		if len(words) > 0 {
+			layerPages++
+		} else if s.ocr != nil && s.renderer != nil && ocrPages < s.ocrConfig.MaxPages {
+			result, err := s.recognizePage(ctx, tmpFile.Name(), i)
+			if err != nil {
+				return nil, err
+			}
+
+			words = strings.Fields(result.Text)
+			if len(words) > 0 {
+				ocrPages++
+				confidence += result.Confidence
+			}
+		}
+
+		if len(words) > 0 {
+			pages = append(pages, strings.Join(words, " "))
+		}
+	}
+
+	metrics.ExtractedPages.WithLabelValues(string(domain.TextSourceLayer)).Add(float64(layerPages))
+	metrics.ExtractedPages.WithLabelValues(string(domain.TextSourceOcr)).Add(float64(ocrPages))
+	metrics.ExtractedPages.WithLabelValues("empty").Add(float64(r.NumPage() - layerPages - ocrPages))
+
+	metadata := docmeta.FromPdf(r)
+	content = &ExtractedContent{
+		Text:     strings.Join(pages, " "),
+		Metadata: &metadata,
+	}
+
+	switch {
+	case ocrPages > 0 && layerPages > 0:
+		content.TextSource = domain.TextSourceMixed
+	case ocrPages > 0:
+		content.TextSource = domain.TextSourceOcr
+	default:
+		content.TextSource = domain.TextSourceLayer
+	}
+
+	if ocrPages > 0 {
+		mean := confidence / float64(ocrPages)
+		content.OcrConfidence = &mean
+	}
+
+	return content, nil
+}
+
+// Pages are counted from 1
+func (s *FileTaskService) recognizePage(ctx context.Context, pdfPath string, page int) (*ocr.Result, error) {
+	image, err := s.renderer.RenderPage(ctx, pdfPath, page, s.ocrConfig.RenderWidth)
 	if err != nil {
-		return "", err
+		if errors.Is(err, preview.ErrUnreadablePdf) {
+			return nil, NewServiceError(ErrFileUnreadable, fmt.Sprintf("failed to render page %d", page), err)
+		}
+		return nil, err
 	}
 
-	r, err := pdf.NewReader(tmpFile, fileInfo.Size())
+	imageFile, err := os.CreateTemp("", "*.png")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer os.Remove(imageFile.Name())
 
-	var buf bytes.Buffer
-	b, err := r.GetPlainText()
+	_, err = imageFile.Write(image)
+	if closeErr := imageFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	_, err = buf.ReadFrom(b)
+	return s.recognize(ctx, imageFile.Name())
+}
+
+// Images the engine gives up on are reported as ErrFileUnreadable
+func (s *FileTaskService) recognize(ctx context.Context, imagePath string) (*ocr.Result, error) {
+	result, err := s.ocr.Recognize(ctx, imagePath)
 	if err != nil {
-		return "", err
+		if errors.Is(err, ocr.ErrUnreadableImage) {
+			return nil, NewServiceError(ErrFileUnreadable, "OCR engine can't read image", err)
+		}
+		return nil, err
 	}
+	return result, nil
+}
 
-	rawText := buf.String()
-	words := strings.Fields(rawText)
-	normalizedText := strings.Join(words, " ")
-	return normalizedText, nil
+// Images have no text layer, so they are left without text when there's
+// no OCR engine
+func (s *FileTaskService) extractImage(ctx context.Context, imagePath string) (*ExtractedContent, error) {
+	if s.ocr == nil {
+		metrics.ExtractedPages.WithLabelValues("empty").Inc()
+		return &ExtractedContent{}, nil
+	}
+
+	result, err := s.recognize(ctx, imagePath)
+	if err != nil {
+		return nil, err
+	}
+
+	content := &ExtractedContent{
+		Text:       strings.Join(strings.Fields(result.Text), " "),
+		TextSource: domain.TextSourceOcr,
+	}
+	if content.Text != "" {
+		content.OcrConfidence = &result.Confidence
+		metrics.ExtractedPages.WithLabelValues(string(domain.TextSourceOcr)).Inc()
+	} else {
+		metrics.ExtractedPages.WithLabelValues("empty").Inc()
+	}
+
+	return content, nil
 }
 
 func (s *FileTaskService) UpdateFile(
@@ -104,7 +339,11 @@ func (s *FileTaskService) UpdateFile(
 	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
 		file, err := s.fileRepo.GetActiveByIDForUpdate(ctx, tx, input.FileID)
 		if err != nil {
-			return NewServiceError(ErrFileNotFound, "not found", err)
+			// only a missing file is final, anything else is worth a retry
+			if errors.Is(err, repositories.ErrNotFound) {
+				return NewServiceError(ErrFileNotFound, "not found", err)
+			}
+			return NewServiceError(ErrInternal, "get file internal error", err)
 		}
 
 		if file.UserID != input.UserID {
@@ -113,18 +352,184 @@ func (s *FileTaskService) UpdateFile(
 
 		if input.Status != nil {
 			file.Status = *input.Status
+			if file.Status != domain.FileStatusError {
+				file.ErrorMessage = sql.NullString{}
+			}
+		}
+		if input.ErrorMessage != nil {
+			file.ErrorMessage = NewNullString(*input.ErrorMessage)
 		}
 		if input.TextContent != nil {
 			file.TextContent = NewNullString(*input.TextContent)
 		}
+		if input.Metadata != nil {
+			file.Metadata = input.Metadata
+		}
+		if input.TextSource != nil {
+			file.TextSource = sql.NullString{String: string(*input.TextSource), Valid: *input.TextSource != ""}
+			file.OcrConfidence = sql.NullFloat64{}
+			if input.OcrConfidence != nil {
+				file.OcrConfidence = sql.NullFloat64{Float64: *input.OcrConfidence, Valid: true}
+			}
+		}
 
 		if err := s.fileRepo.UpdateTx(ctx, tx, file); err != nil {
 			return NewServiceError(ErrInternal, "update file internal error", err)
 		}
 
+		if file.Status == domain.FileStatusReady {
+			if err := s.tagRuleRepo.ApplyToFileTx(ctx, tx, file.ID); err != nil {
+				return NewServiceError(ErrInternal, "apply tag rules internal error", err)
+			}
+		}
+
 		updated = file
 		return nil
 	})
 
 	return updated, err
 }
+
+// Renders the thumbnail and, when enabled, previews of the first pages.
+// Previews of encrypted files are encrypted too. Does nothing without a
+// renderer
+func (s *FileTaskService) GeneratePreviews(ctx context.Context, file *domain.File) error {
+	if s.renderer == nil || isImageType(file.MimeType) {
+		return nil
+	}
+
+	tmpFile, err := s.downloadToTemp(ctx, file)
+	if err != nil {
+		return err
+	}
+	defer tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	fileInfo, err := tmpFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	r, err := pdf.NewReader(tmpFile, fileInfo.Size())
+	if err != nil {
+		return err
+	}
+
+	if r.NumPage() == 0 {
+		return nil
+	}
+	pageCount := min(r.NumPage(), s.previewConfig.MaxPages)
+
+	// objects of a run go under its own generation, old rows keep pointing
+	// at objects sealed with their keys until the new ones replace them
+	generation := uuid.NewString()
+	previews := make([]domain.FilePreview, 0, pageCount+1)
+
+	thumbnail, err := s.storePreview(ctx, file, tmpFile.Name(), generation, domain.FileThumbnailPage, s.previewConfig.ThumbnailWidth)
+	if err != nil {
+		return err
+	}
+	previews = append(previews, *thumbnail)
+
+	for page := 1; page <= pageCount; page++ {
+		preview, err := s.storePreview(ctx, file, tmpFile.Name(), generation, page, s.previewConfig.PageWidth)
+		if err != nil {
+			s.discardPreviews(ctx, file.ID, previews)
+			return err
+		}
+		previews = append(previews, *preview)
+	}
+
+	var oldKeys []string
+	err = s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		oldKeys, err = s.previewRepo.ReplaceForFileTx(ctx, tx, file.ID, previews)
+		return err
+	})
+	if err != nil {
+		s.discardPreviews(ctx, file.ID, previews)
+		return NewServiceError(ErrInternal, "save file previews internal error", err)
+	}
+
+	if len(oldKeys) > 0 {
+		if err := s.blobStore.Delete(ctx, oldKeys...); err != nil {
+			return NewServiceError(ErrInternal, "failed to delete stale file previews", err)
+		}
+	}
+
+	return nil
+}
+
+// Removes objects of a run no row points at, failures only leave garbage
+func (s *FileTaskService) discardPreviews(ctx context.Context, fileID string, previews []domain.FilePreview) {
+	if len(previews) == 0 {
+		return
+	}
+
+	keys := make([]string, len(previews))
+	for i, preview := range previews {
+		keys[i] = preview.S3Key
+	}
+
+	if err := s.blobStore.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		logger.FromContext(ctx).Warn("Failed to delete previews of a failed run", zap.Error(err), zap.String("file_id", fileID))
+	}
+}
+
+// Thumbnail is rendered from the first page
+func (s *FileTaskService) storePreview(
+	ctx context.Context,
+	file *domain.File,
+	pdfPath, generation string,
+	page, width int,
+) (*domain.FilePreview, error) {
+	image, err := s.renderer.RenderPage(ctx, pdfPath, max(page, 1), width)
+	if err != nil {
+		return nil, err
+	}
+
+	imageConfig, err := png.DecodeConfig(bytes.NewReader(image))
+	if err != nil {
+		return nil, fmt.Errorf("renderer returned invalid PNG of page %d: %w", page, err)
+	}
+
+	name := fmt.Sprintf("page-%d.png", page)
+	if page == domain.FileThumbnailPage {
+		name = "thumbnail.png"
+	}
+
+	preview := &domain.FilePreview{
+		FileID: file.ID,
+		Page:   page,
+		S3Key:  path.Join(previewsPrefix, file.ID, generation, name),
+		Width:  imageConfig.Width,
+		Height: imageConfig.Height,
+		Size:   int64(len(image)),
+	}
+
+	var body io.Reader = bytes.NewReader(image)
+	size := preview.Size
+	contentType := "image/png"
+
+	if file.KeyID.Valid {
+		if s.cipher == nil {
+			return nil, errors.New("file is encrypted but no key provider is configured")
+		}
+
+		sealed, env, err := s.cipher.SealAlways(ctx, file.UserID, body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = sealed
+		size = s.cipher.SealedSize(size)
+		contentType = "application/octet-stream"
+		preview.KeyID = NewNullString(env.KeyID)
+		preview.WrappedKey = env.WrappedKey
+	}
+
+	if err := s.blobStore.Put(ctx, preview.S3Key, body, size, contentType); err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
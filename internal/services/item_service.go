@@ -9,6 +9,7 @@ import (
 
 type ItemRepo interface {
 	CreateNew(context.Context, *domain.Item) error
+	CreateNewTx(context.Context, *sqlx.Tx, *domain.Item) error
 	List(context.Context, domain.ListItemFilter) ([]domain.Item, int, error)
 	GetByID(context.Context, string) (*domain.Item, error)
 	GetActiveByIDForUpdate(context.Context, *sqlx.Tx, string) (*domain.Item, error)
@@ -20,10 +21,16 @@ type ItemRepo interface {
 	UnbindTagByItemIDTx(ctx context.Context, tx *sqlx.Tx, itemID, tagID string) error
 }
 
+type ItemTagRuleRepo interface {
+	ApplyToItemTx(context.Context, *sqlx.Tx, string) error
+}
+
 type ItemService struct {
-	itemRepo   ItemRepo
-	tagRepo    TagRepo
-	transactor Transactor
+	itemRepo    ItemRepo
+	tagRepo     TagRepo
+	tagRuleRepo ItemTagRuleRepo
+	transactor  Transactor
+	audit       AuditRecorder
 }
 
 type CreateItemInput struct {
@@ -40,11 +47,19 @@ type UpdateItemInput struct {
 	Content *string
 }
 
-func NewItemService(itemRepo ItemRepo, tagRepo TagRepo, transactor Transactor) *ItemService {
+func NewItemService(
+	itemRepo ItemRepo,
+	tagRepo TagRepo,
+	tagRuleRepo ItemTagRuleRepo,
+	transactor Transactor,
+	audit AuditRecorder,
+) *ItemService {
 	return &ItemService{
-		itemRepo:   itemRepo,
-		tagRepo:    tagRepo,
-		transactor: transactor,
+		itemRepo:    itemRepo,
+		tagRepo:     tagRepo,
+		tagRuleRepo: tagRuleRepo,
+		transactor:  transactor,
+		audit:       audit,
 	}
 }
 
@@ -56,9 +71,19 @@ func (s *ItemService) CreateNew(ctx context.Context, input CreateItemInput) (*do
 		Content: NewNullString(input.Content),
 	}
 
-	err := s.itemRepo.CreateNew(ctx, item)
+	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		if err := s.itemRepo.CreateNewTx(ctx, tx, item); err != nil {
+			return NewServiceError(ErrItemNotCreated, "database error", err)
+		}
+
+		if err := s.tagRuleRepo.ApplyToItemTx(ctx, tx, item.ID); err != nil {
+			return NewServiceError(ErrItemTagBind, "apply tag rules", err)
+		}
+
+		return nil
+	})
 	if err != nil {
-		return nil, NewServiceError(ErrItemNotCreated, "database error", err)
+		return nil, err
 	}
 
 	return item, nil
@@ -130,6 +155,10 @@ func (s *ItemService) Update(ctx context.Context, input UpdateItemInput) (*domai
 			return NewServiceError(ErrInternal, "update item internal error", err)
 		}
 
+		if err := s.tagRuleRepo.ApplyToItemTx(ctx, tx, item.ID); err != nil {
+			return NewServiceError(ErrItemTagBind, "apply tag rules", err)
+		}
+
 		updated = item
 		return nil
 	})
@@ -138,19 +167,40 @@ func (s *ItemService) Update(ctx context.Context, input UpdateItemInput) (*domai
 }
 
 func (s *ItemService) DeleteByID(ctx context.Context, itemID, userID string) error {
-	return s.authorizeAndMutateTx(
+	err := s.authorizeAndMutateTx(
 		ctx, itemID, userID,
 		s.itemRepo.GetActiveByIDForUpdate,
 		s.itemRepo.SoftDeleteByIDTx,
 	)
+	if err != nil {
+		return err
+	}
+
+	s.recordItemEvent(ctx, domain.AuditActionItemDeleted, itemID, userID)
+	return nil
 }
 
 func (s *ItemService) RestoreByID(ctx context.Context, itemID, userID string) error {
-	return s.authorizeAndMutateTx(
+	err := s.authorizeAndMutateTx(
 		ctx, itemID, userID,
 		s.itemRepo.GetDeletedByIDForUpdate,
 		s.itemRepo.RestoreByIDTx,
 	)
+	if err != nil {
+		return err
+	}
+
+	s.recordItemEvent(ctx, domain.AuditActionItemRestored, itemID, userID)
+	return nil
+}
+
+func (s *ItemService) recordItemEvent(ctx context.Context, action domain.AuditAction, itemID, userID string) {
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetItem,
+		TargetID:   itemID,
+	})
 }
 
 func (s *ItemService) authorizeAndMutateTx(
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/logger"
+	"slices"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Changes log levels of the running process only, other processes keep
+// the levels they were configured with
+type LogService struct {
+	audit AuditRecorder
+	// Components the process logs under, the rest can't be changed
+	components []string
+}
+
+func NewLogService(audit AuditRecorder, components []string) *LogService {
+	return &LogService{audit: audit, components: components}
+}
+
+func (s *LogService) ListLevels(ctx context.Context) []domain.LogLevel {
+	var result []domain.LogLevel
+	for _, level := range logger.Levels() {
+		if slices.Contains(s.components, level.Component) {
+			result = append(result, toLogLevel(level))
+		}
+	}
+	return result
+}
+
+func (s *LogService) SetLevel(ctx context.Context, component, level, adminID string) (*domain.LogLevel, error) {
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return nil, NewServiceError(ErrInvalidLogLevel, "", err)
+	}
+
+	if err := s.checkComponent(component); err != nil {
+		return nil, err
+	}
+
+	if err := logger.SetLevel(component, parsed); err != nil {
+		return nil, toLogLevelError(err)
+	}
+
+	return s.recordLevelEvent(ctx, domain.AuditActionLogLevelUpdated, component, adminID)
+}
+
+// Root goes back to the configured level, other components follow root again
+func (s *LogService) ResetLevel(ctx context.Context, component, adminID string) (*domain.LogLevel, error) {
+	if err := s.checkComponent(component); err != nil {
+		return nil, err
+	}
+
+	if err := logger.ResetLevel(component); err != nil {
+		return nil, toLogLevelError(err)
+	}
+
+	return s.recordLevelEvent(ctx, domain.AuditActionLogLevelReset, component, adminID)
+}
+
+// Components known to the logger but not used by the process are rejected
+// apart from unknown ones, changing them would have no effect
+func (s *LogService) checkComponent(component string) error {
+	if slices.Contains(s.components, component) {
+		return nil
+	}
+
+	known := slices.ContainsFunc(logger.Levels(), func(level logger.ComponentLevel) bool {
+		return level.Component == component
+	})
+	if !known {
+		return NewServiceError(ErrLogComponentNotFound, "", nil)
+	}
+
+	errMsg := fmt.Sprintf("log component %s isn't used by this process", component)
+	return NewServiceError(ErrLogComponentUnused, errMsg, nil)
+}
+
+// Records the level the component ended up with. Logged at warn so the
+// change shows up whatever the new level is
+func (s *LogService) recordLevelEvent(
+	ctx context.Context,
+	action domain.AuditAction,
+	component, adminID string,
+) (*domain.LogLevel, error) {
+	levels := logger.Levels()
+	idx := slices.IndexFunc(levels, func(level logger.ComponentLevel) bool {
+		return level.Component == component
+	})
+	if idx < 0 {
+		return nil, NewServiceError(ErrLogComponentNotFound, "", nil)
+	}
+	level := toLogLevel(levels[idx])
+
+	logger.FromContext(ctx).Warn(
+		"Log level changed",
+		zap.String("component", component),
+		zap.String("level", level.Level),
+		zap.String("admin_id", adminID),
+	)
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    adminID,
+		TargetType: domain.AuditTargetLogComponent,
+		TargetID:   component,
+		Details:    map[string]any{"level": level.Level},
+	})
+
+	return &level, nil
+}
+
+func toLogLevelError(err error) error {
+	if errors.Is(err, logger.ErrUnknownComponent) {
+		return NewServiceError(ErrLogComponentNotFound, "", err)
+	}
+	return NewServiceError(ErrInternal, "set log level internal error", err)
+}
+
+func toLogLevel(level logger.ComponentLevel) domain.LogLevel {
+	return domain.LogLevel{
+		Component:  level.Component,
+		Level:      level.Level.String(),
+		Overridden: level.Overridden,
+	}
+}
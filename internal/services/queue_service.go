@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+
+	"github.com/hibiken/asynq"
+)
+
+type TaskInspector interface {
+	Queues() ([]string, error)
+	GetQueueInfo(queue string) (*asynq.QueueInfo, error)
+	GetTaskInfo(queue, id string) (*asynq.TaskInfo, error)
+	ListArchivedTasks(queue string, opts ...asynq.ListOption) ([]*asynq.TaskInfo, error)
+	RunTask(queue, id string) error
+	DeleteTask(queue, id string) error
+}
+
+// Gives admins a look at tasks the worker gave up on
+type QueueService struct {
+	inspector TaskInspector
+	audit     AuditRecorder
+}
+
+func NewQueueService(inspector TaskInspector, audit AuditRecorder) *QueueService {
+	return &QueueService{
+		inspector: inspector,
+		audit:     audit,
+	}
+}
+
+// Newest failures come first. A queue nothing was enqueued to yet is empty
+func (s *QueueService) ListArchived(ctx context.Context, params domain.ListArchivedTaskFilter) ([]domain.ArchivedTask, int, error) {
+	queueInfo, err := s.inspector.GetQueueInfo(params.Queue)
+	if errors.Is(err, asynq.ErrQueueNotFound) {
+		return []domain.ArchivedTask{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "get queue info internal error", err)
+	}
+
+	infos, err := s.inspector.ListArchivedTasks(
+		params.Queue,
+		asynq.Page(params.Page),
+		asynq.PageSize(params.PageSize),
+	)
+	if err != nil && !errors.Is(err, asynq.ErrQueueNotFound) {
+		return nil, 0, NewServiceError(ErrInternal, "list archived tasks internal error", err)
+	}
+
+	tasks := make([]domain.ArchivedTask, len(infos))
+	for i, info := range infos {
+		tasks[i] = toArchivedTask(info)
+	}
+
+	return tasks, queueInfo.Archived, nil
+}
+
+func (s *QueueService) ListQueueStats(ctx context.Context) ([]domain.QueueStats, error) {
+	queues, err := s.inspector.Queues()
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list queues internal error", err)
+	}
+
+	stats := make([]domain.QueueStats, 0, len(queues))
+	for _, queue := range queues {
+		info, err := s.inspector.GetQueueInfo(queue)
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, NewServiceError(ErrInternal, "get queue info internal error", err)
+		}
+
+		stats = append(stats, domain.QueueStats{
+			Queue:     info.Queue,
+			Size:      info.Size,
+			Pending:   info.Pending,
+			Active:    info.Active,
+			Scheduled: info.Scheduled,
+			Retry:     info.Retry,
+			Archived:  info.Archived,
+			Completed: info.Completed,
+			Processed: info.Processed,
+			Failed:    info.Failed,
+			Paused:    info.Paused,
+			Latency:   info.Latency,
+		})
+	}
+
+	return stats, nil
+}
+
+// Moves the task back to pending, it starts over with no retries used
+func (s *QueueService) RetryArchived(ctx context.Context, queue, taskID, adminID string) error {
+	if err := s.getArchived(queue, taskID); err != nil {
+		return err
+	}
+
+	if err := s.inspector.RunTask(queue, taskID); err != nil {
+		return s.taskError(err, queue, taskID)
+	}
+
+	s.recordTaskEvent(ctx, domain.AuditActionTaskRetried, queue, taskID, adminID)
+	return nil
+}
+
+func (s *QueueService) DeleteArchived(ctx context.Context, queue, taskID, adminID string) error {
+	if err := s.getArchived(queue, taskID); err != nil {
+		return err
+	}
+
+	if err := s.inspector.DeleteTask(queue, taskID); err != nil {
+		return s.taskError(err, queue, taskID)
+	}
+
+	s.recordTaskEvent(ctx, domain.AuditActionTaskDeleted, queue, taskID, adminID)
+	return nil
+}
+
+// Tasks in other states belong to the worker and aren't touched
+func (s *QueueService) getArchived(queue, taskID string) error {
+	info, err := s.inspector.GetTaskInfo(queue, taskID)
+	if err != nil {
+		return s.taskError(err, queue, taskID)
+	}
+
+	if info.State != asynq.TaskStateArchived {
+		errMsg := fmt.Sprintf("task %s is %s", taskID, info.State)
+		return NewServiceError(ErrTaskNotFound, errMsg, nil)
+	}
+
+	return nil
+}
+
+func (s *QueueService) taskError(err error, queue, taskID string) error {
+	errMsg := fmt.Sprintf("failed to find task %s in queue %s", taskID, queue)
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return NewServiceError(ErrTaskNotFound, errMsg, err)
+	}
+	return NewServiceError(ErrInternal, errMsg, err)
+}
+
+func (s *QueueService) recordTaskEvent(ctx context.Context, action domain.AuditAction, queue, taskID, adminID string) {
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    adminID,
+		TargetType: domain.AuditTargetTask,
+		TargetID:   taskID,
+		Details:    map[string]any{"queue": queue},
+	})
+}
+
+func toArchivedTask(info *asynq.TaskInfo) domain.ArchivedTask {
+	return domain.ArchivedTask{
+		ID:           info.ID,
+		Queue:        info.Queue,
+		Type:         info.Type,
+		Payload:      info.Payload,
+		LastError:    info.LastErr,
+		LastFailedAt: info.LastFailedAt,
+		Retried:      info.Retried,
+		MaxRetry:     info.MaxRetry,
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/repositories"
+)
+
+type QuotaUserRepo interface {
+	GetByID(context.Context, string) (*domain.User, error)
+	GetUsage(ctx context.Context, userID string) (*domain.UserUsage, error)
+	UpdateQuota(ctx context.Context, userID string, override domain.UserQuotaOverride) (*domain.User, error)
+}
+
+type QuotaService struct {
+	userRepo QuotaUserRepo
+	audit    AuditRecorder
+	defaults domain.UserQuota
+}
+
+func NewQuotaService(userRepo QuotaUserRepo, audit AuditRecorder, defaults domain.UserQuota) *QuotaService {
+	return &QuotaService{
+		userRepo: userRepo,
+		audit:    audit,
+		defaults: defaults,
+	}
+}
+
+// Returns current usage together with limits in effect for the user
+func (s *QuotaService) GetUsage(ctx context.Context, userID string) (*domain.UserUsage, *domain.UserQuota, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	usage, err := s.userRepo.GetUsage(ctx, userID)
+	if err != nil {
+		return nil, nil, NewServiceError(ErrInternal, "get usage internal error", err)
+	}
+
+	return usage, s.resolve(user), nil
+}
+
+// Checked before the file is stored. Concurrent uploads may overshoot
+// the storage quota a little since nothing is reserved
+func (s *QuotaService) CheckFileUpload(ctx context.Context, userID string, size int64) error {
+	usage, quota, err := s.GetUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxFileBytes > 0 && size > quota.MaxFileBytes {
+		errMsg := fmt.Sprintf("file of %d bytes exceeds limit of %d", size, quota.MaxFileBytes)
+		return NewServiceError(ErrFileTooLarge, errMsg, nil)
+	}
+
+	if quota.MaxStorageBytes > 0 && usage.StorageBytes+size > quota.MaxStorageBytes {
+		errMsg := fmt.Sprintf("%d of %d bytes used", usage.StorageBytes, quota.MaxStorageBytes)
+		return NewServiceError(ErrStorageQuotaExceeded, errMsg, nil)
+	}
+
+	return nil
+}
+
+func (s *QuotaService) CheckItemCreate(ctx context.Context, userID string) error {
+	usage, quota, err := s.GetUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if quota.MaxItems > 0 && int64(usage.ItemCount) >= quota.MaxItems {
+		errMsg := fmt.Sprintf("%d of %d items used", usage.ItemCount, quota.MaxItems)
+		return NewServiceError(ErrItemQuotaExceeded, errMsg, nil)
+	}
+
+	return nil
+}
+
+func (s *QuotaService) SetUserQuota(
+	ctx context.Context,
+	userID, adminID string,
+	override domain.UserQuotaOverride,
+) (*domain.UserQuota, error) {
+	user, err := s.userRepo.UpdateQuota(ctx, userID, override)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to update quota of user %s", userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrUserNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+
+	quota := s.resolve(user)
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionUserQuotaUpdated,
+		ActorID:    adminID,
+		UserID:     userID,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   userID,
+		Details: map[string]any{
+			"max_storage_bytes": quota.MaxStorageBytes,
+			"max_file_bytes":    quota.MaxFileBytes,
+			"max_items":         quota.MaxItems,
+		},
+	})
+
+	return quota, nil
+}
+
+func (s *QuotaService) getUser(ctx context.Context, userID string) (*domain.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to find user %s", userID)
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil, NewServiceError(ErrUserNotFound, errMsg, err)
+		}
+		return nil, NewServiceError(ErrInternal, errMsg, err)
+	}
+	return user, nil
+}
+
+func (s *QuotaService) resolve(user *domain.User) *domain.UserQuota {
+	quota := s.defaults
+	if user.MaxStorageBytes.Valid {
+		quota.MaxStorageBytes = user.MaxStorageBytes.Int64
+	}
+	if user.MaxFileBytes.Valid {
+		quota.MaxFileBytes = user.MaxFileBytes.Int64
+	}
+	if user.MaxItems.Valid {
+		quota.MaxItems = user.MaxItems.Int64
+	}
+	return &quota
+}
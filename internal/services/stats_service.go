@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/metrics"
+	"qvarkk/kvault/logger"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type StatsRepo interface {
+	GetTotals(context.Context) (*domain.Totals, error)
+}
+
+// Keeps domain gauges up to date. Counting runs on a timer rather than on
+// scrape, so frequent scrapes don't load the database
+type StatsService struct {
+	statsRepo StatsRepo
+}
+
+func NewStatsService(statsRepo StatsRepo) *StatsService {
+	return &StatsService{statsRepo: statsRepo}
+}
+
+var fileStatuses = []domain.FileStatus{
+	domain.FileStatusUploading,
+	domain.FileStatusProcessing,
+	domain.FileStatusReady,
+	domain.FileStatusError,
+}
+
+func (s *StatsService) Refresh(ctx context.Context) error {
+	totals, err := s.statsRepo.GetTotals(ctx)
+	if err != nil {
+		return NewServiceError(ErrInternal, "get totals internal error", err)
+	}
+
+	metrics.Items.Set(float64(totals.ItemCount))
+	metrics.Tags.Set(float64(totals.TagCount))
+	for _, status := range fileStatuses {
+		metrics.Files.WithLabelValues(string(status)).Set(float64(totals.FilesByStatus[status]))
+	}
+
+	return nil
+}
+
+// Refreshes right away and then every interval until ctx is done
+func (s *StatsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			logger.Logger.Warn("Failed to refresh domain metrics", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
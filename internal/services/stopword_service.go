@@ -5,6 +5,8 @@ import (
 	"errors"
 	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/repositories"
+	"slices"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -19,12 +21,20 @@ type StopwordRepo interface {
 	DisableTx(ctx context.Context, tx *sqlx.Tx, word, userID string) error
 	Delete(ctx context.Context, word, userID string) error
 	DeleteTx(ctx context.Context, tx *sqlx.Tx, word, userID string) error
-	IsDefaultTx(context.Context, *sqlx.Tx, string) (bool, error)
+	IsDefaultTx(ctx context.Context, tx *sqlx.Tx, word, userID string) (bool, error)
+	DeleteDefaultOverridesTx(ctx context.Context, tx *sqlx.Tx, userID string, words []string) error
+	UpsertManyEnabledTx(ctx context.Context, tx *sqlx.Tx, userID string, words []string) (int64, error)
+	ListPacks(ctx context.Context, userID string) ([]domain.StopwordPack, error)
+	PackExists(ctx context.Context, lang string) (bool, error)
+	SetPackEnabled(ctx context.Context, userID, lang string, isEnabled bool) error
 }
 
+const MaxStopwordsImport = 10000
+
 type StopwordService struct {
 	stopwordRepo StopwordRepo
 	transactor   Transactor
+	audit        AuditRecorder
 }
 
 type CreateStopwordInput struct {
@@ -32,10 +42,11 @@ type CreateStopwordInput struct {
 	Word   string
 }
 
-func NewStopwordService(stopwordRepo StopwordRepo, transactor Transactor) *StopwordService {
+func NewStopwordService(stopwordRepo StopwordRepo, transactor Transactor, audit AuditRecorder) *StopwordService {
 	return &StopwordService{
 		stopwordRepo: stopwordRepo,
 		transactor:   transactor,
+		audit:        audit,
 	}
 }
 
@@ -68,7 +79,7 @@ func (s *StopwordService) List(ctx context.Context, params domain.ListStopwordFi
 
 func (s *StopwordService) Enable(ctx context.Context, word string, userID string) error {
 	return s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
-		isDefault, err := s.stopwordRepo.IsDefaultTx(ctx, tx, word)
+		isDefault, err := s.stopwordRepo.IsDefaultTx(ctx, tx, word, userID)
 		if err != nil {
 			return NewServiceError(ErrInternal, "check default", err)
 		}
@@ -97,7 +108,7 @@ func (s *StopwordService) Enable(ctx context.Context, word string, userID string
 
 func (s *StopwordService) Disable(ctx context.Context, word string, userID string) error {
 	return s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
-		isDefault, err := s.stopwordRepo.IsDefaultTx(ctx, tx, word)
+		isDefault, err := s.stopwordRepo.IsDefaultTx(ctx, tx, word, userID)
 		if err != nil {
 			return NewServiceError(ErrInternal, "check default", err)
 		}
@@ -147,3 +158,129 @@ func (s *StopwordService) Delete(
 
 	return nil
 }
+
+// Turns every given word into an enabled stopword: words from enabled packs
+// lose their overrides, the rest become user stopwords. Returns the number
+// of distinct words after normalization.
+func (s *StopwordService) Import(
+	ctx context.Context,
+	userID string,
+	words []string,
+) (int, error) {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		normalized = append(normalized, word)
+	}
+
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+
+	if len(normalized) == 0 {
+		return 0, NewServiceError(ErrStopwordImportInvalid, "no words to import", nil)
+	}
+	if len(normalized) > MaxStopwordsImport {
+		return 0, NewServiceError(ErrStopwordImportInvalid, "too many words", nil)
+	}
+
+	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		err := s.stopwordRepo.DeleteDefaultOverridesTx(ctx, tx, userID, normalized)
+		if err != nil {
+			return NewServiceError(ErrInternal, "delete default overrides", err)
+		}
+
+		_, err = s.stopwordRepo.UpsertManyEnabledTx(ctx, tx, userID, normalized)
+		if err != nil {
+			return NewServiceError(ErrInternal, "upsert stopwords", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionStopwordsImported,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetStopword,
+		Details:    map[string]any{"count": len(normalized)},
+	})
+
+	return len(normalized), nil
+}
+
+// Returns enabled user stopwords sorted alphabetically, pack words are not included
+func (s *StopwordService) Export(ctx context.Context, userID string) ([]string, error) {
+	params := domain.ListStopwordFilter{
+		UserID: userID,
+		Source: string(domain.StopwordSourceUser),
+		SortFilter: domain.SortFilter{
+			Column:    "word",
+			Direction: "ASC",
+		},
+	}
+
+	stopwords, err := s.stopwordRepo.GetActiveStopwords(ctx, params)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "export stopwords internal error", err)
+	}
+
+	words := make([]string, 0, len(stopwords))
+	for _, stopword := range stopwords {
+		if stopword.IsEnabled {
+			words = append(words, stopword.Word)
+		}
+	}
+
+	return words, nil
+}
+
+func (s *StopwordService) ListPacks(ctx context.Context, userID string) ([]domain.StopwordPack, error) {
+	packs, err := s.stopwordRepo.ListPacks(ctx, userID)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list stopword packs internal error", err)
+	}
+	return packs, nil
+}
+
+func (s *StopwordService) EnablePack(ctx context.Context, lang, userID string) error {
+	return s.setPackEnabled(ctx, lang, userID, true)
+}
+
+func (s *StopwordService) DisablePack(ctx context.Context, lang, userID string) error {
+	return s.setPackEnabled(ctx, lang, userID, false)
+}
+
+func (s *StopwordService) setPackEnabled(ctx context.Context, lang, userID string, isEnabled bool) error {
+	exists, err := s.stopwordRepo.PackExists(ctx, lang)
+	if err != nil {
+		return NewServiceError(ErrInternal, "check stopword pack", err)
+	}
+
+	if !exists {
+		return NewServiceError(ErrStopwordPackNotFound, "not found", nil)
+	}
+
+	err = s.stopwordRepo.SetPackEnabled(ctx, userID, lang, isEnabled)
+	if err != nil {
+		return NewServiceError(ErrInternal, "mutate stopword pack internal error", err)
+	}
+
+	action := domain.AuditActionPackDisabled
+	if isEnabled {
+		action = domain.AuditActionPackEnabled
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     action,
+		ActorID:    userID,
+		TargetType: domain.AuditTargetStopwordPack,
+		TargetID:   lang,
+	})
+
+	return nil
+}
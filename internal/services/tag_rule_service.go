@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/repositories"
+	"slices"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type TagRuleRepo interface {
+	CreateNew(context.Context, *domain.TagRule) error
+	List(context.Context, domain.ListTagRuleFilter) ([]domain.TagRule, error)
+	GetByID(context.Context, string) (*domain.TagRule, error)
+	GetByIDForUpdate(context.Context, *sqlx.Tx, string) (*domain.TagRule, error)
+	UpdateTx(context.Context, *sqlx.Tx, *domain.TagRule) error
+	DeleteByID(context.Context, string) error
+	ValidatePattern(context.Context, string) error
+	DryRun(context.Context, domain.TagRuleDryRunFilter) ([]domain.Item, int, error)
+}
+
+type TagRuleService struct {
+	tagRuleRepo TagRuleRepo
+	tagRepo     TagRepo
+	transactor  Transactor
+}
+
+type CreateTagRuleInput struct {
+	UserID    string
+	TagID     string
+	Name      string
+	Kind      string
+	Field     string
+	Pattern   string
+	Keywords  []string
+	IsEnabled bool
+}
+
+type UpdateTagRuleInput struct {
+	RuleID    string
+	UserID    string
+	TagID     *string
+	Name      *string
+	Kind      *string
+	Field     *string
+	Pattern   *string
+	Keywords  *[]string
+	IsEnabled *bool
+}
+
+func NewTagRuleService(tagRuleRepo TagRuleRepo, tagRepo TagRepo, transactor Transactor) *TagRuleService {
+	return &TagRuleService{
+		tagRuleRepo: tagRuleRepo,
+		tagRepo:     tagRepo,
+		transactor:  transactor,
+	}
+}
+
+func (s *TagRuleService) CreateNew(ctx context.Context, input CreateTagRuleInput) (*domain.TagRule, error) {
+	if err := s.checkTagOwner(ctx, input.TagID, input.UserID); err != nil {
+		return nil, err
+	}
+
+	rule := &domain.TagRule{
+		UserID:    input.UserID,
+		TagID:     input.TagID,
+		Name:      input.Name,
+		Kind:      domain.TagRuleKind(input.Kind),
+		Field:     domain.TagRuleField(input.Field),
+		Pattern:   NewNullString(input.Pattern),
+		Keywords:  input.Keywords,
+		IsEnabled: input.IsEnabled,
+	}
+
+	if err := s.normalizeAndValidate(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	err := s.tagRuleRepo.CreateNew(ctx, rule)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "create tag rule internal error", err)
+	}
+
+	return rule, nil
+}
+
+func (s *TagRuleService) List(ctx context.Context, params domain.ListTagRuleFilter) ([]domain.TagRule, error) {
+	rules, err := s.tagRuleRepo.List(ctx, params)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list tag rules internal error", err)
+	}
+	return rules, nil
+}
+
+func (s *TagRuleService) GetByID(ctx context.Context, ruleID, userID string) (*domain.TagRule, error) {
+	rule, err := s.tagRuleRepo.GetByID(ctx, ruleID)
+	if err != nil {
+		return nil, NewServiceError(ErrTagRuleNotFound, "not found", err)
+	}
+
+	if rule.UserID != userID {
+		return nil, NewServiceError(ErrTagRuleNotFound, "forbidden", nil)
+	}
+
+	return rule, nil
+}
+
+func (s *TagRuleService) Update(ctx context.Context, input UpdateTagRuleInput) (*domain.TagRule, error) {
+	if input.TagID != nil {
+		if err := s.checkTagOwner(ctx, *input.TagID, input.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	var updated *domain.TagRule
+
+	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		rule, err := s.tagRuleRepo.GetByIDForUpdate(ctx, tx, input.RuleID)
+		if err != nil {
+			return NewServiceError(ErrTagRuleNotFound, "not found", err)
+		}
+
+		if rule.UserID != input.UserID {
+			return NewServiceError(ErrTagRuleNotFound, "forbidden", nil)
+		}
+
+		if input.TagID != nil {
+			rule.TagID = *input.TagID
+		}
+		if input.Name != nil {
+			rule.Name = *input.Name
+		}
+		if input.Kind != nil {
+			rule.Kind = domain.TagRuleKind(*input.Kind)
+		}
+		if input.Field != nil {
+			rule.Field = domain.TagRuleField(*input.Field)
+		}
+		if input.Pattern != nil {
+			rule.Pattern = NewNullString(*input.Pattern)
+		}
+		if input.Keywords != nil {
+			rule.Keywords = *input.Keywords
+		}
+		if input.IsEnabled != nil {
+			rule.IsEnabled = *input.IsEnabled
+		}
+
+		if err := s.normalizeAndValidate(ctx, rule); err != nil {
+			return err
+		}
+
+		if err := s.tagRuleRepo.UpdateTx(ctx, tx, rule); err != nil {
+			return NewServiceError(ErrInternal, "update tag rule internal error", err)
+		}
+
+		updated = rule
+		return nil
+	})
+
+	return updated, err
+}
+
+func (s *TagRuleService) DeleteByID(ctx context.Context, ruleID, userID string) error {
+	if _, err := s.GetByID(ctx, ruleID, userID); err != nil {
+		return err
+	}
+
+	err := s.tagRuleRepo.DeleteByID(ctx, ruleID)
+	if err != nil {
+		return NewServiceError(ErrInternal, "delete tag rule internal error", err)
+	}
+
+	return nil
+}
+
+// Returns existing items a rule with given definition would bind its tag to,
+// without saving the rule or touching any bindings
+func (s *TagRuleService) DryRun(
+	ctx context.Context,
+	params domain.TagRuleDryRunFilter,
+) ([]domain.Item, int, error) {
+	rule := &domain.TagRule{
+		Kind:     params.Kind,
+		Field:    params.Field,
+		Pattern:  NewNullString(params.Pattern),
+		Keywords: params.Keywords,
+	}
+
+	if err := s.normalizeAndValidate(ctx, rule); err != nil {
+		return nil, 0, err
+	}
+
+	params.Pattern = rule.Pattern.String
+	params.Keywords = rule.Keywords
+
+	items, count, err := s.tagRuleRepo.DryRun(ctx, params)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "tag rule dry run internal error", err)
+	}
+
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	tagsByItem, err := s.tagRepo.FindByItemIDs(ctx, ids)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "get item tags internal error", err)
+	}
+
+	for i := range items {
+		items[i].Tags = tagsByItem[items[i].ID]
+	}
+
+	return items, count, nil
+}
+
+func (s *TagRuleService) checkTagOwner(ctx context.Context, tagID, userID string) error {
+	tag, err := s.tagRepo.GetByID(ctx, tagID)
+	if err != nil {
+		return NewServiceError(ErrTagNotFound, "not found", err)
+	}
+
+	if tag.UserID != userID {
+		return NewServiceError(ErrTagNotFound, "forbidden", nil)
+	}
+
+	return nil
+}
+
+// Drops the part of the definition that doesn't belong to the rule kind,
+// so regex rules carry no keywords and keyword rules carry no pattern
+func (s *TagRuleService) normalizeAndValidate(ctx context.Context, rule *domain.TagRule) error {
+	if rule.Field == "" {
+		rule.Field = domain.TagRuleFieldAny
+	}
+
+	switch rule.Kind {
+	case domain.TagRuleKindRegex:
+		rule.Keywords = pq.StringArray{}
+		if !rule.Pattern.Valid {
+			return NewServiceError(ErrTagRuleInvalid, "empty pattern", nil)
+		}
+
+		err := s.tagRuleRepo.ValidatePattern(ctx, rule.Pattern.String)
+		if err != nil {
+			if errors.Is(err, repositories.ErrInvalidInput) {
+				return NewServiceError(ErrTagRuleInvalid, "invalid pattern", err)
+			}
+			return NewServiceError(ErrInternal, "validate pattern internal error", err)
+		}
+	case domain.TagRuleKindKeywords:
+		rule.Pattern = NewNullString("")
+
+		keywords := pq.StringArray{}
+		for _, keyword := range rule.Keywords {
+			keyword = strings.TrimSpace(keyword)
+			if keyword != "" && !slices.Contains(keywords, keyword) {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) == 0 {
+			return NewServiceError(ErrTagRuleInvalid, "no keywords", nil)
+		}
+		rule.Keywords = keywords
+	default:
+		return NewServiceError(ErrTagRuleInvalid, "unknown kind", nil)
+	}
+
+	return nil
+}
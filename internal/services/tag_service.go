@@ -5,6 +5,8 @@ import (
 	"errors"
 	"qvarkk/kvault/internal/domain"
 	"qvarkk/kvault/internal/repositories"
+	"slices"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -18,19 +20,39 @@ type TagRepo interface {
 	DeleteByID(context.Context, string) error
 	FindByItemID(context.Context, string) ([]domain.Tag, error)
 	FindByItemIDs(context.Context, []string) (repositories.ItemTagsByID, error)
+	FindByFileIDs(context.Context, []string) (repositories.FileTagsByID, error)
+	ResolveNames(ctx context.Context, userID string, words []string) (map[string]domain.Tag, error)
+	RebindItemsTx(ctx context.Context, tx *sqlx.Tx, targetID string, sourceIDs []string) error
+	RebindFilesTx(ctx context.Context, tx *sqlx.Tx, targetID string, sourceIDs []string) error
+	RepointRulesTx(ctx context.Context, tx *sqlx.Tx, targetID string, sourceIDs []string) error
+	DeleteByIDsTx(context.Context, *sqlx.Tx, []string) error
+	CreateAlias(context.Context, *domain.TagAlias) error
+	UpsertAliasTx(context.Context, *sqlx.Tx, *domain.TagAlias) error
+	RepointAliasesTx(ctx context.Context, tx *sqlx.Tx, targetID string, sourceIDs []string) error
+	ListAliases(context.Context, string) ([]domain.TagAlias, error)
+	DeleteAlias(ctx context.Context, tagID, alias string) (bool, error)
+	Stats(context.Context, domain.TagStatsFilter) ([]domain.TagStats, int, error)
+	FindCoOccurrences(ctx context.Context, tagIDs []string, limit int) (repositories.TagCoOccurrencesByID, error)
 }
 
 type TagService struct {
 	tagRepo      TagRepo
 	stopwordRepo StopwordRepo
 	transactor   Transactor
+	audit        AuditRecorder
 }
 
-func NewTagService(tagRepo TagRepo, stopwordRepo StopwordRepo, transactor Transactor) *TagService {
+func NewTagService(
+	tagRepo TagRepo,
+	stopwordRepo StopwordRepo,
+	transactor Transactor,
+	audit AuditRecorder,
+) *TagService {
 	return &TagService{
 		tagRepo:      tagRepo,
 		stopwordRepo: stopwordRepo,
 		transactor:   transactor,
+		audit:        audit,
 	}
 }
 
@@ -45,6 +67,18 @@ type UpdateTagInput struct {
 	Name   string
 }
 
+type MergeTagsInput struct {
+	UserID    string
+	TargetID  string
+	SourceIDs []string
+}
+
+type CreateTagAliasInput struct {
+	UserID string
+	TagID  string
+	Alias  string
+}
+
 func (s *TagService) CreateNew(
 	ctx context.Context,
 	input CreateTagInput,
@@ -76,6 +110,32 @@ func (s *TagService) List(
 	return tags, count, nil
 }
 
+func (s *TagService) Stats(
+	ctx context.Context,
+	filter domain.TagStatsFilter,
+) ([]domain.TagStats, int, error) {
+	stats, count, err := s.tagRepo.Stats(ctx, filter)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "tag stats", err)
+	}
+
+	ids := make([]string, len(stats))
+	for i, stat := range stats {
+		ids[i] = stat.ID
+	}
+
+	coByTag, err := s.tagRepo.FindCoOccurrences(ctx, ids, filter.CoOccurrenceMax)
+	if err != nil {
+		return nil, 0, NewServiceError(ErrInternal, "tag co-occurrences", err)
+	}
+
+	for i := range stats {
+		stats[i].CoOccurrences = coByTag[stats[i].ID]
+	}
+
+	return stats, count, nil
+}
+
 func (s *TagService) Update(
 	ctx context.Context,
 	input UpdateTagInput,
@@ -142,3 +202,174 @@ func (s *TagService) DeleteByID(
 
 	return nil
 }
+
+// Merges source tags into the target one. Items and files keep their
+// binding, aliases and rules follow the target. Names of
+// merged tags become aliases of the target so auto-tagging doesn't
+// recreate them
+func (s *TagService) Merge(
+	ctx context.Context,
+	input MergeTagsInput,
+) (*domain.Tag, error) {
+	if slices.Contains(input.SourceIDs, input.TargetID) {
+		return nil, NewServiceError(ErrTagMergeInvalid, "target is among sources", nil)
+	}
+
+	var merged *domain.Tag
+
+	err := s.transactor.WithTx(ctx, func(tx *sqlx.Tx) error {
+		target, err := s.tagRepo.GetByIDForUpdate(ctx, tx, input.TargetID)
+		if err != nil {
+			return NewServiceError(ErrTagNotFound, "target not found", err)
+		}
+
+		if target.UserID != input.UserID {
+			return NewServiceError(ErrTagNotFound, "forbidden", nil)
+		}
+
+		sources := make([]*domain.Tag, 0, len(input.SourceIDs))
+		for _, sourceID := range input.SourceIDs {
+			source, err := s.tagRepo.GetByIDForUpdate(ctx, tx, sourceID)
+			if err != nil {
+				return NewServiceError(ErrTagNotFound, "source not found", err)
+			}
+
+			if source.UserID != input.UserID {
+				return NewServiceError(ErrTagNotFound, "forbidden", nil)
+			}
+
+			sources = append(sources, source)
+		}
+
+		if err := s.tagRepo.RebindItemsTx(ctx, tx, target.ID, input.SourceIDs); err != nil {
+			return NewServiceError(ErrInternal, "rebind items internal error", err)
+		}
+
+		if err := s.tagRepo.RebindFilesTx(ctx, tx, target.ID, input.SourceIDs); err != nil {
+			return NewServiceError(ErrInternal, "rebind files internal error", err)
+		}
+
+		if err := s.tagRepo.RepointAliasesTx(ctx, tx, target.ID, input.SourceIDs); err != nil {
+			return NewServiceError(ErrInternal, "repoint aliases internal error", err)
+		}
+
+		if err := s.tagRepo.RepointRulesTx(ctx, tx, target.ID, input.SourceIDs); err != nil {
+			return NewServiceError(ErrInternal, "repoint rules internal error", err)
+		}
+
+		if err := s.tagRepo.DeleteByIDsTx(ctx, tx, input.SourceIDs); err != nil {
+			return NewServiceError(ErrInternal, "delete merged tags internal error", err)
+		}
+
+		for _, source := range sources {
+			alias := &domain.TagAlias{
+				Alias:  normalizeTagAlias(source.Name),
+				TagID:  target.ID,
+				UserID: input.UserID,
+			}
+
+			if err := s.tagRepo.UpsertAliasTx(ctx, tx, alias); err != nil {
+				return NewServiceError(ErrInternal, "upsert alias internal error", err)
+			}
+		}
+
+		if err := s.tagRepo.UpdateTx(ctx, tx, target); err != nil {
+			return NewServiceError(ErrInternal, "update tag internal error", err)
+		}
+
+		merged = target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, AuditEntry{
+		Action:     domain.AuditActionTagMerged,
+		ActorID:    input.UserID,
+		TargetType: domain.AuditTargetTag,
+		TargetID:   merged.ID,
+		Details:    map[string]any{"source_ids": input.SourceIDs},
+	})
+
+	return merged, nil
+}
+
+func (s *TagService) CreateAlias(
+	ctx context.Context,
+	input CreateTagAliasInput,
+) (*domain.TagAlias, error) {
+	if _, err := s.getOwnedTag(ctx, input.TagID, input.UserID); err != nil {
+		return nil, err
+	}
+
+	alias := &domain.TagAlias{
+		Alias:  normalizeTagAlias(input.Alias),
+		TagID:  input.TagID,
+		UserID: input.UserID,
+	}
+
+	err := s.tagRepo.CreateAlias(ctx, alias)
+	if err != nil {
+		if errors.Is(err, repositories.ErrAlreadyExists) {
+			return nil, NewServiceError(ErrTagAliasAlreadyExists, "alias already exists", err)
+		}
+		return nil, NewServiceError(ErrInternal, "create alias internal error", err)
+	}
+
+	return alias, nil
+}
+
+func (s *TagService) ListAliases(
+	ctx context.Context,
+	tagID, userID string,
+) ([]domain.TagAlias, error) {
+	if _, err := s.getOwnedTag(ctx, tagID, userID); err != nil {
+		return nil, err
+	}
+
+	aliases, err := s.tagRepo.ListAliases(ctx, tagID)
+	if err != nil {
+		return nil, NewServiceError(ErrInternal, "list aliases internal error", err)
+	}
+
+	return aliases, nil
+}
+
+func (s *TagService) DeleteAlias(
+	ctx context.Context,
+	tagID, alias, userID string,
+) error {
+	if _, err := s.getOwnedTag(ctx, tagID, userID); err != nil {
+		return err
+	}
+
+	deleted, err := s.tagRepo.DeleteAlias(ctx, tagID, normalizeTagAlias(alias))
+	if err != nil {
+		return NewServiceError(ErrInternal, "delete alias internal error", err)
+	}
+
+	if !deleted {
+		return NewServiceError(ErrTagAliasNotFound, "not found", nil)
+	}
+
+	return nil
+}
+
+func (s *TagService) getOwnedTag(ctx context.Context, tagID, userID string) (*domain.Tag, error) {
+	tag, err := s.tagRepo.GetByID(ctx, tagID)
+	if err != nil {
+		return nil, NewServiceError(ErrTagNotFound, "not found", err)
+	}
+
+	if tag.UserID != userID {
+		return nil, NewServiceError(ErrTagNotFound, "forbidden", nil)
+	}
+
+	return tag, nil
+}
+
+// auto-tagging works on words from 'simple' tsvectors, which are lowercased
+func normalizeTagAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
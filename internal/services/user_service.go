@@ -11,7 +11,6 @@ import (
 type UserRepo interface {
 	GetByID(context.Context, string) (*domain.User, error)
 	GetByEmail(context.Context, string) (*domain.User, error)
-	GetByApiKey(context.Context, string) (*domain.User, error)
 }
 
 type UserService struct {
@@ -22,19 +21,6 @@ func NewUserService(userRepo UserRepo) *UserService {
 	return &UserService{userRepo: userRepo}
 }
 
-func (u *UserService) Authenticate(ctx context.Context, apiKey string) (*domain.User, error) {
-	user, err := u.GetByApiKey(ctx, apiKey)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to authenticate user with API key = %s", apiKey)
-		if errors.Is(err, ErrUserNotFound) {
-			return nil, NewServiceError(ErrUnauthenticated, errMsg, err)
-		}
-		return nil, err
-	}
-
-	return user, nil
-}
-
 func (u *UserService) GetByID(ctx context.Context, userID string) (*domain.User, error) {
 	return u.getByField(ctx, repositories.UserFieldID, userID)
 }
@@ -43,10 +29,6 @@ func (u *UserService) GetByEmail(ctx context.Context, email string) (*domain.Use
 	return u.getByField(ctx, repositories.UserFieldEmail, email)
 }
 
-func (u *UserService) GetByApiKey(ctx context.Context, apiKey string) (*domain.User, error) {
-	return u.getByField(ctx, repositories.UserFieldApiKey, apiKey)
-}
-
 func (u *UserService) getByField(ctx context.Context, field string, value string) (*domain.User, error) {
 	var user *domain.User
 	var err error
@@ -55,8 +37,6 @@ func (u *UserService) getByField(ctx context.Context, field string, value string
 		user, err = u.userRepo.GetByID(ctx, value)
 	case repositories.UserFieldEmail:
 		user, err = u.userRepo.GetByEmail(ctx, value)
-	case repositories.UserFieldApiKey:
-		user, err = u.userRepo.GetByApiKey(ctx, value)
 	}
 
 	if err != nil {
@@ -1,6 +1,25 @@
 package tasks
 
+// Request that enqueued the task, embedded in every payload so the worker
+// continues its trace and logs with its ID
+type Origin struct {
+	TraceContext map[string]string `json:",omitempty"`
+	RequestID    string            `json:",omitempty"`
+}
+
 type PdfProcessPayload struct {
 	UserID string
 	FileID string
+	Origin
+}
+
+type UserDeletePayload struct {
+	UserID string
+	Origin
+}
+
+// Only the email, whether it belongs to a user is found out by the worker
+type PasswordResetMailPayload struct {
+	Email string
+	Origin
 }
@@ -1,19 +1,56 @@
 package tasks
 
 import (
+	"context"
 	"encoding/json"
+	"qvarkk/kvault/internal/domain"
+	"qvarkk/kvault/internal/tracing"
 
 	"github.com/hibiken/asynq"
 )
 
+// pdf:process handles image uploads too, the name stays for tasks
+// already in the queue
 const (
 	TypePdfProcess = "pdf:process"
+	TypeUserDelete = "user:delete"
+
+	TypePasswordResetMail = "password-reset:mail"
 )
 
-func NewPdfProcessTask(payload PdfProcessPayload) (*asynq.Task, error) {
+// Queue tasks go to when no other one is given
+const QueueDefault = "default"
+
+func newOrigin(ctx context.Context) Origin {
+	return Origin{
+		TraceContext: tracing.Inject(ctx),
+		RequestID:    domain.RequestMetaFrom(ctx).RequestID,
+	}
+}
+
+func NewPdfProcessTask(ctx context.Context, payload PdfProcessPayload) (*asynq.Task, error) {
+	payload.Origin = newOrigin(ctx)
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
 	return asynq.NewTask(TypePdfProcess, jsonPayload), nil
 }
+
+func NewUserDeleteTask(ctx context.Context, payload UserDeletePayload) (*asynq.Task, error) {
+	payload.Origin = newOrigin(ctx)
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeUserDelete, jsonPayload), nil
+}
+
+func NewPasswordResetMailTask(ctx context.Context, payload PasswordResetMailPayload) (*asynq.Task, error) {
+	payload.Origin = newOrigin(ctx)
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypePasswordResetMail, jsonPayload), nil
+}
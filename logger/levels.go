@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Components are top-level logger names, everything logged through an
+// unnamed logger belongs to root
+const (
+	ComponentRoot   = "root"
+	ComponentAccess = "access"
+	ComponentHttp   = "http"
+	ComponentWorker = "worker"
+)
+
+var components = []string{ComponentRoot, ComponentAccess, ComponentHttp, ComponentWorker}
+
+var ErrUnknownComponent = errors.New("logger: unknown component")
+
+type ComponentLevel struct {
+	Component string
+	Level     zapcore.Level
+	// Whether the level is set for the component itself or taken from root
+	Overridden bool
+}
+
+// Root level plus overrides, changed at runtime by admins
+type levelSet struct {
+	mu        sync.RWMutex
+	base      zapcore.Level
+	root      zapcore.Level
+	overrides map[string]zapcore.Level
+}
+
+var levels = &levelSet{overrides: map[string]zapcore.Level{}}
+
+func (s *levelSet) configure(root zapcore.Level, overrides map[string]string) error {
+	parsed := make(map[string]zapcore.Level, len(overrides))
+	for component, value := range overrides {
+		if !isComponent(component) || component == ComponentRoot {
+			return fmt.Errorf("%w %q", ErrUnknownComponent, component)
+		}
+		level, err := zapcore.ParseLevel(value)
+		if err != nil {
+			return fmt.Errorf("logger: level of %s: %w", component, err)
+		}
+		parsed[component] = level
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.base, s.root, s.overrides = root, root, parsed
+	return nil
+}
+
+func (s *levelSet) enabled(loggerName string, level zapcore.Level) bool {
+	component, _, _ := strings.Cut(loggerName, ".")
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if override, ok := s.overrides[component]; ok {
+		return override.Enabled(level)
+	}
+	return s.root.Enabled(level)
+}
+
+// Lowest level any component logs at, entries below it are dropped
+// before their fields are built
+func (s *levelSet) minLevel() zapcore.Level {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lowest := s.root
+	for _, level := range s.overrides {
+		lowest = min(lowest, level)
+	}
+	return lowest
+}
+
+// In the order of components, root first
+func Levels() []ComponentLevel {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+
+	result := make([]ComponentLevel, 0, len(components))
+	for _, component := range components {
+		entry := ComponentLevel{Component: component, Level: levels.root}
+		if override, ok := levels.overrides[component]; ok {
+			entry.Level, entry.Overridden = override, true
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// Setting root changes every component without an override
+func SetLevel(component string, level zapcore.Level) error {
+	if !isComponent(component) {
+		return fmt.Errorf("%w %q", ErrUnknownComponent, component)
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if component == ComponentRoot {
+		levels.root = level
+	} else {
+		levels.overrides[component] = level
+	}
+	return nil
+}
+
+// Drops the override of a component, root goes back to its configured level
+func ResetLevel(component string) error {
+	if !isComponent(component) {
+		return fmt.Errorf("%w %q", ErrUnknownComponent, component)
+	}
+
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if component == ComponentRoot {
+		levels.root = levels.base
+	} else {
+		delete(levels.overrides, component)
+	}
+	return nil
+}
+
+func isComponent(name string) bool {
+	return slices.Contains(components, name)
+}
+
+// Filters entries by the level of the component they're logged under,
+// wrapped cores accept every level
+type levelCore struct {
+	zapcore.Core
+	levels *levelSet
+}
+
+func (c levelCore) Enabled(level zapcore.Level) bool {
+	return c.levels.minLevel().Enabled(level)
+}
+
+func (c levelCore) Level() zapcore.Level {
+	return c.levels.minLevel()
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabled(entry.LoggerName, entry.Level) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
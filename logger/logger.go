@@ -1,17 +1,40 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"qvarkk/kvault/config"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	OutputStdout = "stdout"
+	OutputFile   = "file"
+	OutputBoth   = "both"
+
+	FormatConsole = "console"
+	FormatJson    = "json"
 )
 
 var Logger *zap.Logger
 
-func Init(filename string, debug bool) error {
-	logFile, err := os.OpenFile(filename+".log", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+// One JSON line per HTTP request on every output, kept apart from the
+// human-readable console log
+var Access *zap.Logger
+
+func Init(name string, config config.LogConfig, debug bool) error {
+	level, err := zapcore.ParseLevel(config.Level)
 	if err != nil {
+		return fmt.Errorf("logger: %w", err)
+	}
+	if debug {
+		level = zapcore.DebugLevel
+	}
+	if err := levels.configure(level, config.Levels); err != nil {
 		return err
 	}
 
@@ -21,23 +44,65 @@ func Init(filename string, debug bool) error {
 	encoderConfig.ConsoleSeparator = " | "
 	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
-	fileEncoder := zapcore.NewJSONEncoder(encoderConfig)
-	stdoutEncoder := zapcore.NewConsoleEncoder(encoderConfig)
+	jsonEncoder := zapcore.NewJSONEncoder(encoderConfig)
 
-	level := zap.InfoLevel
-	if debug {
-		level = zap.DebugLevel
+	var stdoutEncoder zapcore.Encoder
+	switch config.Format {
+	case FormatConsole:
+		stdoutEncoder = zapcore.NewConsoleEncoder(encoderConfig)
+	case FormatJson:
+		stdoutEncoder = jsonEncoder
+	default:
+		return fmt.Errorf("logger: unknown format %q", config.Format)
 	}
 
-	core := zapcore.NewTee(
-		zapcore.NewCore(fileEncoder, zapcore.AddSync(logFile), level),
-		zapcore.NewCore(stdoutEncoder, zapcore.AddSync(os.Stdout), level),
-	)
+	var toStdout, toFile bool
+	switch config.Output {
+	case OutputStdout:
+		toStdout = true
+	case OutputFile:
+		toFile = true
+	case OutputBoth:
+		toStdout, toFile = true, true
+	default:
+		return fmt.Errorf("logger: unknown output %q", config.Output)
+	}
 
-	Logger = zap.New(core)
-	return err
+	// levels are checked by levelCore, cores take whatever gets through
+	var cores, accessCores []zapcore.Core
+	if toStdout {
+		stdout := zapcore.Lock(os.Stdout)
+		cores = append(cores, zapcore.NewCore(stdoutEncoder, stdout, zapcore.DebugLevel))
+		accessCores = append(accessCores, zapcore.NewCore(jsonEncoder, stdout, zapcore.DebugLevel))
+	}
+	if toFile {
+		file := zapcore.AddSync(newRotatingFile(name, config))
+		cores = append(cores, zapcore.NewCore(jsonEncoder, file, zapcore.DebugLevel))
+		accessCores = append(accessCores, zapcore.NewCore(jsonEncoder, file, zapcore.DebugLevel))
+	}
+
+	Logger = zap.New(levelCore{Core: zapcore.NewTee(cores...), levels: levels})
+	Access = zap.New(levelCore{Core: zapcore.NewTee(accessCores...), levels: levels}).Named(ComponentAccess)
+	return nil
+}
+
+// The file is opened on first write and reopened after each rotation
+func newRotatingFile(name string, config config.LogConfig) io.Writer {
+	filename := config.File
+	if filename == "" {
+		filename = name + ".log"
+	}
+
+	return &lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    config.MaxSizeMB,
+		MaxAge:     config.MaxAgeDays,
+		MaxBackups: config.MaxBackups,
+		Compress:   config.Compress,
+	}
 }
 
 func Sync() {
 	Logger.Sync()
+	Access.Sync()
 }
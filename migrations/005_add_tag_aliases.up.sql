@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS tag_aliases (
+  alias TEXT NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id),
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, alias)
+);
+CREATE INDEX IF NOT EXISTS idx_tag_aliases_tag_id ON tag_aliases(tag_id);
+
+
+-- auto-tagging resolves words through aliases before creating a new tag
+CREATE OR REPLACE FUNCTION extract_item_tags(item_id UUID, item_user_id UUID, content TEXT, search_vector tsvector)
+RETURNS VOID AS $$
+DECLARE
+  tag_word TEXT;
+  tag_id   UUID;
+BEGIN
+  IF length(coalesce(content, '')) < 50 THEN
+    RETURN;
+  END IF;
+
+  FOR tag_word IN
+    EXECUTE format(
+      'SELECT word FROM ts_stat(%L) 
+        WHERE length(word) > 3
+          AND word ~ %L
+          AND word NOT IN (SELECT word FROM active_stopwords(%L::uuid))
+        ORDER BY nentry DESC
+        LIMIT 3',
+      'SELECT search_vector FROM items WHERE id = ''' || item_id || '''',
+      '^[a-zA-Zа-яА-ЯёЁ\u00C0-\u024F]+$',
+      item_user_id
+    )
+  LOOP
+    SELECT ta.tag_id INTO tag_id
+    FROM tag_aliases ta
+    WHERE ta.user_id = item_user_id AND ta.alias = tag_word;
+
+    IF tag_id IS NULL THEN
+      INSERT INTO tags (user_id, name)
+      VALUES (item_user_id, tag_word)
+      ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+      RETURNING id INTO tag_id;
+    END IF;
+
+    INSERT INTO item_tags (item_id, tag_id, source)
+    VALUES (item_id, tag_id, 'auto')
+    ON CONFLICT DO NOTHING;
+  END LOOP;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_item_tags_tag_id;
+
+ALTER TABLE item_tags DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE item_tags ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_item_tags_tag_id ON item_tags(tag_id);
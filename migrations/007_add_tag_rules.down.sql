@@ -0,0 +1,23 @@
+DROP FUNCTION IF EXISTS tag_rule_matches(tag_rule_kind, TEXT, TEXT[], TEXT);
+DROP FUNCTION IF EXISTS tag_rule_subject(tag_rule_field, TEXT, TEXT);
+
+DROP INDEX IF EXISTS idx_file_tags_tag_id;
+DROP TABLE IF EXISTS file_tags;
+
+DROP INDEX IF EXISTS idx_tag_rules_user_id;
+DROP TABLE IF EXISTS tag_rules;
+
+DROP TYPE IF EXISTS tag_rule_field;
+DROP TYPE IF EXISTS tag_rule_kind;
+
+-- enum values can't be dropped, so tag_source is recreated without 'rule'
+UPDATE item_tags SET source = 'auto' WHERE source = 'rule';
+
+ALTER TYPE tag_source RENAME TO tag_source_old;
+CREATE TYPE tag_source AS ENUM ('auto', 'manual');
+
+ALTER TABLE item_tags ALTER COLUMN source DROP DEFAULT;
+ALTER TABLE item_tags ALTER COLUMN source TYPE tag_source USING source::text::tag_source;
+ALTER TABLE item_tags ALTER COLUMN source SET DEFAULT 'auto';
+
+DROP TYPE tag_source_old;
@@ -0,0 +1,54 @@
+-- 'rule' sits between 'auto' and 'manual' so MAX/GREATEST keep the strongest binding
+ALTER TYPE tag_source ADD VALUE IF NOT EXISTS 'rule' BEFORE 'manual';
+
+CREATE TYPE tag_rule_kind AS ENUM ('regex', 'keywords');
+CREATE TYPE tag_rule_field AS ENUM ('title', 'content', 'any');
+
+CREATE TABLE IF NOT EXISTS tag_rules (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id),
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  kind tag_rule_kind NOT NULL,
+  field tag_rule_field NOT NULL DEFAULT 'any',
+  pattern TEXT,
+  keywords TEXT[] NOT NULL DEFAULT '{}',
+  is_enabled BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_tag_rules_user_id ON tag_rules(user_id);
+
+CREATE TABLE IF NOT EXISTS file_tags (
+  file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+  tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+  source tag_source NOT NULL DEFAULT 'auto',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (file_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_file_tags_tag_id ON file_tags(tag_id);
+
+
+CREATE OR REPLACE FUNCTION tag_rule_subject(field tag_rule_field, title TEXT, content TEXT)
+RETURNS TEXT AS $$
+  SELECT CASE field
+    WHEN 'title' THEN coalesce(title, '')
+    WHEN 'content' THEN coalesce(content, '')
+    ELSE coalesce(title, '') || ' ' || coalesce(content, '')
+  END;
+$$ LANGUAGE sql IMMUTABLE;
+
+
+-- regex rules use POSIX matching as is (case sensitive unless the pattern says otherwise),
+-- keyword rules match any keyword as a case insensitive substring
+CREATE OR REPLACE FUNCTION tag_rule_matches(kind tag_rule_kind, pattern TEXT, keywords TEXT[], subject TEXT)
+RETURNS BOOLEAN AS $$
+  SELECT CASE kind
+    WHEN 'regex' THEN coalesce(subject, '') ~ pattern
+    WHEN 'keywords' THEN EXISTS (
+      SELECT 1 FROM unnest(keywords) AS k(word)
+      WHERE k.word <> '' AND strpos(lower(coalesce(subject, '')), lower(k.word)) > 0
+    )
+    ELSE false
+  END;
+$$ LANGUAGE sql IMMUTABLE;
@@ -0,0 +1,35 @@
+CREATE OR REPLACE FUNCTION active_stopwords(p_user_id UUID)
+RETURNS TABLE(word TEXT, source stopword_source, is_enabled BOOL, updated_at TIMESTAMPTZ) AS $$
+  SELECT 
+    sd.word,
+    'default'::stopword_source AS source,
+    CASE WHEN s.word IS NOT NULL THEN s.is_enabled ELSE true END AS is_enabled,
+    COALESCE(s.updated_at, '0001-01-01 00:00:00+00'::TIMESTAMPTZ) AS updated_at
+  FROM stopwords_default sd
+  LEFT JOIN stopwords s ON s.word = sd.word AND s.user_id = p_user_id
+
+  UNION ALL
+
+  SELECT
+    s.word,
+    'user'::stopword_source AS source,
+    s.is_enabled,
+    s.updated_at
+  FROM stopwords s
+  WHERE s.user_id = p_user_id
+    AND s.word NOT IN (SELECT word FROM stopwords_default);
+$$ LANGUAGE sql STABLE;
+
+DROP FUNCTION IF EXISTS active_default_stopwords(UUID);
+
+DELETE FROM stopwords_default WHERE lang NOT IN ('en', 'ru');
+DELETE FROM stopwords_default a USING stopwords_default b
+  WHERE a.word = b.word AND a.lang > b.lang;
+
+DROP INDEX IF EXISTS idx_stopwords_default_word;
+ALTER TABLE stopwords_default DROP CONSTRAINT IF EXISTS stopwords_default_pkey;
+ALTER TABLE stopwords_default DROP COLUMN IF EXISTS lang;
+ALTER TABLE stopwords_default ADD PRIMARY KEY (word);
+
+DROP TABLE IF EXISTS user_stopword_packs;
+DROP TABLE IF EXISTS stopword_packs;
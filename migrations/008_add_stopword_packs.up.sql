@@ -0,0 +1,82 @@
+CREATE TABLE IF NOT EXISTS stopword_packs (
+  lang TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  enabled_by_default BOOLEAN NOT NULL DEFAULT false
+);
+
+INSERT INTO stopword_packs (lang, name, enabled_by_default) VALUES
+  ('en', 'English', true),
+  ('ru', 'Русский', true),
+  ('de', 'Deutsch', false)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS user_stopword_packs (
+  user_id UUID NOT NULL REFERENCES users(id),
+  lang TEXT NOT NULL REFERENCES stopword_packs(lang) ON DELETE CASCADE,
+  is_enabled BOOLEAN NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, lang)
+);
+
+-- the mixed list from 004 is split by script, the same word may now live in several packs
+ALTER TABLE stopwords_default ADD COLUMN IF NOT EXISTS lang TEXT REFERENCES stopword_packs(lang) ON DELETE CASCADE;
+UPDATE stopwords_default SET lang = CASE WHEN word ~ '^[a-z]+$' THEN 'en' ELSE 'ru' END;
+ALTER TABLE stopwords_default ALTER COLUMN lang SET NOT NULL;
+ALTER TABLE stopwords_default DROP CONSTRAINT IF EXISTS stopwords_default_pkey;
+ALTER TABLE stopwords_default ADD PRIMARY KEY (lang, word);
+CREATE INDEX IF NOT EXISTS idx_stopwords_default_word ON stopwords_default(word);
+
+INSERT INTO stopwords_default (lang, word) VALUES
+  ('de', 'aber'), ('de', 'alle'), ('de', 'allem'), ('de', 'allen'), ('de', 'aller'), ('de', 'alles'), ('de', 'also'),
+  ('de', 'andere'), ('de', 'anderen'), ('de', 'auch'), ('de', 'auf'), ('de', 'bei'), ('de', 'bereits'), ('de', 'bist'),
+  ('de', 'bitte'), ('de', 'damit'), ('de', 'dann'), ('de', 'dass'), ('de', 'dein'), ('de', 'deine'), ('de', 'denn'),
+  ('de', 'dessen'), ('de', 'diese'), ('de', 'diesem'), ('de', 'diesen'), ('de', 'dieser'), ('de', 'dieses'),
+  ('de', 'doch'), ('de', 'dort'), ('de', 'durch'), ('de', 'eine'), ('de', 'einem'), ('de', 'einen'), ('de', 'einer'),
+  ('de', 'eines'), ('de', 'etwas'), ('de', 'euch'), ('de', 'euer'), ('de', 'gegen'), ('de', 'gewesen'), ('de', 'habe'),
+  ('de', 'haben'), ('de', 'hatte'), ('de', 'hatten'), ('de', 'hier'), ('de', 'hinter'), ('de', 'ihre'), ('de', 'ihrem'),
+  ('de', 'ihren'), ('de', 'ihrer'), ('de', 'immer'), ('de', 'jede'), ('de', 'jedem'), ('de', 'jeden'), ('de', 'jeder'),
+  ('de', 'jedes'), ('de', 'jene'), ('de', 'jetzt'), ('de', 'kann'), ('de', 'kein'), ('de', 'keine'), ('de', 'keinen'),
+  ('de', 'können'), ('de', 'könnte'), ('de', 'machen'), ('de', 'mein'), ('de', 'meine'), ('de', 'meinem'),
+  ('de', 'meinen'), ('de', 'meiner'), ('de', 'mich'), ('de', 'mir'), ('de', 'mit'), ('de', 'muss'), ('de', 'nach'),
+  ('de', 'nicht'), ('de', 'nichts'), ('de', 'noch'), ('de', 'nun'), ('de', 'nur'), ('de', 'oder'), ('de', 'ohne'),
+  ('de', 'sehr'), ('de', 'sein'), ('de', 'seine'), ('de', 'seinem'), ('de', 'seinen'), ('de', 'seiner'), ('de', 'selbst'),
+  ('de', 'sich'), ('de', 'sind'), ('de', 'solche'), ('de', 'soll'), ('de', 'sollte'), ('de', 'sondern'), ('de', 'über'),
+  ('de', 'unter'), ('de', 'unser'), ('de', 'unsere'), ('de', 'viel'), ('de', 'vom'), ('de', 'von'), ('de', 'vor'),
+  ('de', 'wann'), ('de', 'warum'), ('de', 'weil'), ('de', 'welche'), ('de', 'welchem'), ('de', 'welchen'),
+  ('de', 'welcher'), ('de', 'wenn'), ('de', 'werde'), ('de', 'werden'), ('de', 'wieder'), ('de', 'will'), ('de', 'wird'),
+  ('de', 'wollen'), ('de', 'wurde'), ('de', 'wurden'), ('de', 'während'), ('de', 'zwischen')
+ON CONFLICT DO NOTHING;
+
+
+CREATE OR REPLACE FUNCTION active_default_stopwords(p_user_id UUID)
+RETURNS TABLE(word TEXT) AS $$
+  SELECT DISTINCT sd.word
+  FROM stopwords_default sd
+  JOIN stopword_packs sp ON sp.lang = sd.lang
+  LEFT JOIN user_stopword_packs usp ON usp.lang = sp.lang AND usp.user_id = p_user_id
+  WHERE COALESCE(usp.is_enabled, sp.enabled_by_default);
+$$ LANGUAGE sql STABLE;
+
+
+CREATE OR REPLACE FUNCTION active_stopwords(p_user_id UUID)
+RETURNS TABLE(word TEXT, source stopword_source, is_enabled BOOL, updated_at TIMESTAMPTZ) AS $$
+  SELECT 
+    sd.word,
+    'default'::stopword_source AS source,
+    CASE WHEN s.word IS NOT NULL THEN s.is_enabled ELSE true END AS is_enabled,
+    COALESCE(s.updated_at, '0001-01-01 00:00:00+00'::TIMESTAMPTZ) AS updated_at
+  FROM active_default_stopwords(p_user_id) sd
+  LEFT JOIN stopwords s ON s.word = sd.word AND s.user_id = p_user_id
+
+  UNION ALL
+
+  SELECT
+    s.word,
+    'user'::stopword_source AS source,
+    s.is_enabled,
+    s.updated_at
+  FROM stopwords s
+  WHERE s.user_id = p_user_id
+    AND s.source = 'user'
+    AND s.word NOT IN (SELECT word FROM active_default_stopwords(p_user_id));
+$$ LANGUAGE sql STABLE;
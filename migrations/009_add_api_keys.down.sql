@@ -0,0 +1,12 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS api_key TEXT;
+
+UPDATE users u SET api_key = k.key
+FROM api_keys k
+WHERE k.user_id = u.id AND k.name = 'default' AND k.revoked_at IS NULL;
+
+UPDATE users SET api_key = uuid_generate_v4()::text WHERE api_key IS NULL;
+
+ALTER TABLE users ALTER COLUMN api_key SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_api_key_key UNIQUE (api_key);
+
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  key TEXT NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
+-- revoked keys are kept for history, so names only have to be unique among live ones
+CREATE UNIQUE INDEX IF NOT EXISTS idx_api_keys_user_id_name ON api_keys(user_id, name) WHERE revoked_at IS NULL;
+
+-- existing keys keep working with the scopes new users get
+INSERT INTO api_keys (user_id, name, key, scopes)
+SELECT id, 'default', api_key, ARRAY[
+  'items:read', 'items:write',
+  'files:read', 'files:write',
+  'tags:read', 'tags:write',
+  'stopwords:read', 'stopwords:write'
+]
+FROM users;
+
+ALTER TABLE users DROP COLUMN IF EXISTS api_key;
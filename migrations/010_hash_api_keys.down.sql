@@ -0,0 +1,9 @@
+-- hashes can't be reversed, so every key gets a new random value
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS key TEXT;
+UPDATE api_keys SET key = uuid_generate_v4()::text;
+ALTER TABLE api_keys ALTER COLUMN key SET NOT NULL;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_key_key UNIQUE (key);
+
+DROP INDEX IF EXISTS idx_api_keys_prefix;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS key_hash;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS prefix;
@@ -0,0 +1,14 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS prefix TEXT;
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS key_hash TEXT;
+
+-- legacy keys are plain UUIDs, their prefix is the first 12 hex characters
+UPDATE api_keys SET
+  prefix = substr(replace(key, '-', ''), 1, 12),
+  key_hash = encode(sha256(convert_to(key, 'UTF8')), 'hex');
+
+ALTER TABLE api_keys ALTER COLUMN prefix SET NOT NULL;
+ALTER TABLE api_keys ALTER COLUMN key_hash SET NOT NULL;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_key_hash_key UNIQUE (key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(prefix);
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS key;
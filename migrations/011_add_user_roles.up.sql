@@ -0,0 +1,4 @@
+CREATE TYPE user_role AS ENUM ('user', 'admin');
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role user_role NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS deletion_requested_at;
+
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS max_items;
+ALTER TABLE users DROP COLUMN IF EXISTS max_file_bytes;
+ALTER TABLE users DROP COLUMN IF EXISTS max_storage_bytes;
@@ -0,0 +1,4 @@
+-- NULL means the configured default, 0 means no limit
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_storage_bytes BIGINT CHECK (max_storage_bytes >= 0);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_file_bytes BIGINT CHECK (max_file_bytes >= 0);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_items INTEGER CHECK (max_items >= 0);
@@ -0,0 +1,2 @@
+ALTER TABLE files DROP COLUMN IF EXISTS wrapped_key;
+ALTER TABLE files DROP COLUMN IF EXISTS key_id;
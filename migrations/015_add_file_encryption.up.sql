@@ -0,0 +1,3 @@
+-- Set for files stored encrypted, key_id names the key that wrapped the data key
+ALTER TABLE files ADD COLUMN IF NOT EXISTS key_id TEXT;
+ALTER TABLE files ADD COLUMN IF NOT EXISTS wrapped_key BYTEA;
@@ -0,0 +1,13 @@
+-- page 0 holds the thumbnail, previews of encrypted files are encrypted too
+CREATE TABLE IF NOT EXISTS file_previews (
+  file_id UUID NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+  page INT NOT NULL CHECK (page >= 0),
+  s3_key TEXT NOT NULL,
+  width INT NOT NULL,
+  height INT NOT NULL,
+  size BIGINT NOT NULL,
+  key_id TEXT,
+  wrapped_key BYTEA,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (file_id, page)
+);
@@ -0,0 +1,20 @@
+DROP TRIGGER IF EXISTS files_search_vector_trigger ON files;
+CREATE TRIGGER files_search_vector_trigger
+BEFORE INSERT OR UPDATE OF text_content
+ON files
+FOR EACH ROW
+EXECUTE FUNCTION update_search_vector_files();
+
+CREATE OR REPLACE FUNCTION update_search_vector_files()
+RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector :=
+    setweight(to_tsvector('simple', coalesce(NEW.original_name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(NEW.text_content, '')), 'B');
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_files_metadata_page_count;
+ALTER TABLE files DROP COLUMN IF EXISTS metadata;
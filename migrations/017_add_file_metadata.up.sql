@@ -0,0 +1,27 @@
+ALTER TABLE files ADD COLUMN IF NOT EXISTS metadata JSONB;
+CREATE INDEX IF NOT EXISTS idx_files_metadata_page_count ON files(((metadata->>'page_count')::int));
+
+-- declared title, subject, author and keywords rank like the file name
+CREATE OR REPLACE FUNCTION update_search_vector_files()
+RETURNS trigger AS $$
+BEGIN
+  NEW.search_vector :=
+    setweight(to_tsvector('simple', coalesce(NEW.original_name, '')), 'A') ||
+    setweight(to_tsvector('simple', concat_ws(' ',
+      NEW.metadata->>'title',
+      NEW.metadata->>'subject',
+      NEW.metadata->>'author',
+      (SELECT string_agg(k, ' ') FROM jsonb_array_elements_text(NEW.metadata->'keywords') k)
+    )), 'A') ||
+    setweight(to_tsvector('simple', coalesce(NEW.text_content, '')), 'B');
+
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS files_search_vector_trigger ON files;
+CREATE TRIGGER files_search_vector_trigger
+BEFORE INSERT OR UPDATE OF text_content, metadata
+ON files
+FOR EACH ROW
+EXECUTE FUNCTION update_search_vector_files();
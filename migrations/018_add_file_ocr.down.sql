@@ -0,0 +1,2 @@
+ALTER TABLE files DROP COLUMN IF EXISTS ocr_confidence;
+ALTER TABLE files DROP COLUMN IF EXISTS text_source;
@@ -0,0 +1,4 @@
+-- filled by the worker, confidence is the mean over OCR'd words from 0 to 100
+ALTER TABLE files ADD COLUMN IF NOT EXISTS text_source TEXT
+  CHECK (text_source IN ('text_layer', 'ocr', 'mixed'));
+ALTER TABLE files ADD COLUMN IF NOT EXISTS ocr_confidence REAL;
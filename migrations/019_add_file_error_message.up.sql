@@ -0,0 +1,2 @@
+-- why the last processing run failed for good, cleared once the file is processed again
+ALTER TABLE files ADD COLUMN IF NOT EXISTS error_message TEXT;